@@ -2,6 +2,7 @@ package clustercache
 
 import (
 	"sync"
+	"time"
 
 	"github.com/kubecost/cost-model/pkg/env"
 	"k8s.io/klog"
@@ -72,6 +73,23 @@ type ClusterCache interface {
 
 	// SetConfigMapUpdateFunc sets the configmap update function
 	SetConfigMapUpdateFunc(func(interface{}))
+
+	// GetResourceStatuses returns the number of cached objects and the last sync time of each
+	// cached resource.
+	GetResourceStatuses() []ResourceStatus
+}
+
+// ResourceStatus describes the state of a single cached resource
+type ResourceStatus struct {
+	// Resource is the plural name of the resource, ie: "pods"
+	Resource string
+
+	// Count is the number of cached objects of the resource
+	Count int
+
+	// LastSync is the time the resource was last synced. A zero time indicates the resource has
+	// not yet synced.
+	LastSync time.Time
 }
 
 // KubernetesClusterCache is the implementation of ClusterCache
@@ -333,6 +351,40 @@ func (kcc *KubernetesClusterCache) GetAllReplicationControllers() []*v1.Replicat
 	return rcs
 }
 
+func (kcc *KubernetesClusterCache) GetResourceStatuses() []ResourceStatus {
+	watches := []struct {
+		resource string
+		wc       WatchController
+	}{
+		{"namespaces", kcc.namespaceWatch},
+		{"nodes", kcc.nodeWatch},
+		{"pods", kcc.podWatch},
+		{"configmaps", kcc.kubecostConfigMapWatch},
+		{"services", kcc.serviceWatch},
+		{"daemonsets", kcc.daemonsetsWatch},
+		{"deployments", kcc.deploymentsWatch},
+		{"statefulsets", kcc.statefulsetWatch},
+		{"replicasets", kcc.replicasetWatch},
+		{"persistentvolumes", kcc.pvWatch},
+		{"persistentvolumeclaims", kcc.pvcWatch},
+		{"storageclasses", kcc.storageClassWatch},
+		{"jobs", kcc.jobsWatch},
+		{"horizontalpodautoscalers", kcc.hpaWatch},
+		{"poddisruptionbudgets", kcc.pdbWatch},
+		{"replicationcontrollers", kcc.replicationControllerWatch},
+	}
+
+	statuses := make([]ResourceStatus, 0, len(watches))
+	for _, w := range watches {
+		statuses = append(statuses, ResourceStatus{
+			Resource: w.resource,
+			Count:    w.wc.Count(),
+			LastSync: w.wc.LastSync(),
+		})
+	}
+	return statuses
+}
+
 func (kcc *KubernetesClusterCache) SetConfigMapUpdateFunc(f func(interface{})) {
 	kcc.kubecostConfigMapWatch.SetUpdateHandler(f)
 }
@@ -2,6 +2,7 @@ package clustercache
 
 import (
 	"sync"
+	"time"
 
 	"github.com/kubecost/cost-model/pkg/config"
 	"github.com/kubecost/cost-model/pkg/log"
@@ -21,6 +22,7 @@ type ClusterImporter struct {
 	sourceHandlerID config.HandlerID
 	dataLock        *sync.Mutex
 	data            *clusterEncoding
+	lastSync        time.Time
 }
 
 // Creates a new ClusterCache implementation which uses an import process to provide cluster data
@@ -37,6 +39,7 @@ func (ci *ClusterImporter) onImportSourceChanged(changeType config.ChangeType, d
 	if changeType == config.ChangeTypeDeleted {
 		ci.dataLock.Lock()
 		ci.data = new(clusterEncoding)
+		ci.lastSync = time.Now()
 		ci.dataLock.Unlock()
 		return
 	}
@@ -55,6 +58,7 @@ func (ci *ClusterImporter) update(data []byte) {
 
 	ci.dataLock.Lock()
 	ci.data = ce
+	ci.lastSync = time.Now()
 	ci.dataLock.Unlock()
 }
 
@@ -315,6 +319,44 @@ func (ci *ClusterImporter) GetAllReplicationControllers() []*v1.ReplicationContr
 	return cloneList
 }
 
+// GetResourceStatuses returns the number of imported objects of each resource, all of which share
+// the time the cluster data was last imported.
+func (ci *ClusterImporter) GetResourceStatuses() []ResourceStatus {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	counts := []struct {
+		resource string
+		count    int
+	}{
+		{"namespaces", len(ci.data.Namespaces)},
+		{"nodes", len(ci.data.Nodes)},
+		{"pods", len(ci.data.Pods)},
+		{"services", len(ci.data.Services)},
+		{"daemonsets", len(ci.data.DaemonSets)},
+		{"deployments", len(ci.data.Deployments)},
+		{"statefulsets", len(ci.data.StatefulSets)},
+		{"replicasets", len(ci.data.ReplicaSets)},
+		{"persistentvolumes", len(ci.data.PersistentVolumes)},
+		{"persistentvolumeclaims", len(ci.data.PersistentVolumeClaims)},
+		{"storageclasses", len(ci.data.StorageClasses)},
+		{"jobs", len(ci.data.Jobs)},
+		{"horizontalpodautoscalers", len(ci.data.HorizontalPodAutoscalers)},
+		{"poddisruptionbudgets", len(ci.data.PodDisruptionBudgets)},
+		{"replicationcontrollers", len(ci.data.ReplicationControllers)},
+	}
+
+	statuses := make([]ResourceStatus, 0, len(counts))
+	for _, c := range counts {
+		statuses = append(statuses, ResourceStatus{
+			Resource: c.resource,
+			Count:    c.count,
+			LastSync: ci.lastSync,
+		})
+	}
+	return statuses
+}
+
 // SetConfigMapUpdateFunc sets the configmap update function
 func (ci *ClusterImporter) SetConfigMapUpdateFunc(_ func(interface{})) {
 	// TODO: (bolt) This function is still a bit strange to me for the ClusterCache interface.
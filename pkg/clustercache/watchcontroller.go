@@ -3,6 +3,7 @@ package clustercache
 import (
 	"fmt"
 	"reflect"
+	"sync/atomic"
 	"time"
 
 	"k8s.io/klog"
@@ -31,11 +32,18 @@ type WatchController interface {
 	// GetAll returns all of the resources
 	GetAll() []interface{}
 
+	// Count returns the number of cached resources without copying them
+	Count() int
+
 	// SetUpdateHandler sets a specific handler for adding/updating individual resources
 	SetUpdateHandler(WatchHandler) WatchController
 
 	// SetRemovedHandler sets a specific handler for removing individual resources
 	SetRemovedHandler(WatchHandler) WatchController
+
+	// LastSync returns the time the cache last synced or processed a resource change. A zero
+	// time is returned if the cache has not yet synced.
+	LastSync() time.Time
 }
 
 // CachingWatchController composites the watching behavior and a cache to ensure that all
@@ -50,6 +58,9 @@ type CachingWatchController struct {
 
 	updateHandler WatchHandler
 	removeHandler WatchHandler
+
+	// lastSync is the unix nano timestamp of the last sync, accessed atomically
+	lastSync int64
 }
 
 func NewCachingWatcher(restClient rest.Interface, resource string, resourceType rt.Object, namespace string, fieldSelector fields.Selector) WatchController {
@@ -103,6 +114,11 @@ func (c *CachingWatchController) GetAll() []interface{} {
 	return cloneList
 }
 
+// Count returns the number of cached resources without copying them
+func (c *CachingWatchController) Count() int {
+	return len(c.indexer.ListKeys())
+}
+
 func (c *CachingWatchController) SetUpdateHandler(handler WatchHandler) WatchController {
 	c.updateHandler = handler
 	return c
@@ -113,6 +129,22 @@ func (c *CachingWatchController) SetRemovedHandler(handler WatchHandler) WatchCo
 	return c
 }
 
+// LastSync returns the time the cache last synced or processed a resource change. A zero time
+// is returned if the cache has not yet synced.
+func (c *CachingWatchController) LastSync() time.Time {
+	nanos := atomic.LoadInt64(&c.lastSync)
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
+
+// markSynced records the current time as the last sync time
+func (c *CachingWatchController) markSynced() {
+	atomic.StoreInt64(&c.lastSync, time.Now().UnixNano())
+}
+
 func (c *CachingWatchController) processNextItem() bool {
 	// Wait until there is a new item in the working queue
 	key, quit := c.queue.Get()
@@ -148,6 +180,8 @@ func (c *CachingWatchController) handle(key string) error {
 			c.updateHandler(obj)
 		}
 	}
+
+	c.markSynced()
 	return nil
 }
 
@@ -185,6 +219,8 @@ func (c *CachingWatchController) WarmUp(cancelCh chan struct{}) {
 		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 		return
 	}
+
+	c.markSynced()
 }
 
 func (c *CachingWatchController) Run(threadiness int, stopCh chan struct{}) {
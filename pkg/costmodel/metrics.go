@@ -265,6 +265,11 @@ func NewCostModelMetricsEmitter(promClient promclient.Client, clusterCache clust
 		EmitPodAnnotations:            env.IsEmitPodAnnotationsMetric(),
 		EmitKubeStateMetrics:          env.IsEmitKsmV1Metrics(),
 		EmitKubeStateMetricsV1Only:    env.IsEmitKsmV1MetricsOnly(),
+		EmitClusterCacheMetrics:       env.IsEmitClusterCacheMetrics(),
+		PodLabelsNamespaceFilter: metrics.NewNamespaceFilter(
+			env.GetPodLabelsNamespaceInclude(),
+			env.GetPodLabelsNamespaceExclude(),
+		),
 	})
 
 	return &CostModelMetricsEmitter{
@@ -43,6 +43,11 @@ const (
 	EmitKsmV1MetricsEnvVar = "EMIT_KSM_V1_METRICS"
 	EmitKsmV1MetricsOnly   = "EMIT_KSM_V1_METRICS_ONLY"
 
+	EmitClusterCacheMetricsEnvVar = "EMIT_CLUSTER_CACHE_METRICS"
+
+	PodLabelsNamespaceIncludeEnvVar = "POD_LABELS_NAMESPACE_INCLUDE"
+	PodLabelsNamespaceExcludeEnvVar = "POD_LABELS_NAMESPACE_EXCLUDE"
+
 	ThanosEnabledEnvVar      = "THANOS_ENABLED"
 	ThanosQueryUrlEnvVar     = "THANOS_QUERY_URL"
 	ThanosOffsetEnvVar       = "THANOS_QUERY_OFFSET"
@@ -154,6 +159,24 @@ func IsEmitKsmV1MetricsOnly() bool {
 	return GetBool(EmitKsmV1MetricsOnly, false)
 }
 
+// IsEmitClusterCacheMetrics returns true if cost-model is configured to emit the sync time and
+// object count of each resource in the cluster cache
+func IsEmitClusterCacheMetrics() bool {
+	return GetBool(EmitClusterCacheMetricsEnvVar, false)
+}
+
+// GetPodLabelsNamespaceInclude returns the comma separated namespace glob patterns (ie: "team-*")
+// pod label metrics are restricted to. An empty list includes all namespaces.
+func GetPodLabelsNamespaceInclude() []string {
+	return GetList(PodLabelsNamespaceIncludeEnvVar)
+}
+
+// GetPodLabelsNamespaceExclude returns the comma separated namespace glob patterns for which pod
+// label metrics are not emitted. Exclusions take precedence over inclusions.
+func GetPodLabelsNamespaceExclude() []string {
+	return GetList(PodLabelsNamespaceExcludeEnvVar)
+}
+
 // GetAWSAccessKeyID returns the environment variable value for AWSAccessKeyIDEnvVar which represents
 // the AWS access key for authentication
 func GetAWSAccessKeyID() string {
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"github.com/kubecost/cost-model/pkg/clustercache"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+//--------------------------------------------------------------------------
+//  ClusterCacheCollector
+//--------------------------------------------------------------------------
+
+// ClusterCacheCollector is a prometheus collector that generates metrics describing the state of
+// the cluster cache, which can be used to alert when the cache stops syncing.
+type ClusterCacheCollector struct {
+	KubeClusterCache clustercache.ClusterCache
+}
+
+// Describe sends the super-set of all possible descriptors of metrics
+// collected by this Collector.
+func (ccc ClusterCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cachedDesc("kubecost_clustercache_last_sync_seconds", "Unix timestamp of the last cluster cache sync by resource")
+	ch <- cachedDesc("kubecost_clustercache_objects", "Number of objects in the cluster cache by resource")
+}
+
+// Collect is called by the Prometheus registry when collecting metrics.
+func (ccc ClusterCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range ccc.KubeClusterCache.GetResourceStatuses() {
+		lastSync := 0.0
+		if !status.LastSync.IsZero() {
+			lastSync = float64(status.LastSync.UnixNano()) / 1e9
+		}
+
+		ch <- newClusterCacheResourceMetric(
+			"kubecost_clustercache_last_sync_seconds",
+			"kubecost_clustercache_last_sync_seconds Unix timestamp of the last cluster cache sync by resource",
+			status.Resource,
+			lastSync,
+		)
+		ch <- newClusterCacheResourceMetric(
+			"kubecost_clustercache_objects",
+			"kubecost_clustercache_objects Number of objects in the cluster cache by resource",
+			status.Resource,
+			float64(status.Count),
+		)
+	}
+}
+
+//--------------------------------------------------------------------------
+//  ClusterCacheResourceMetric
+//--------------------------------------------------------------------------
+
+// ClusterCacheResourceMetric is a prometheus.Metric used to encode the state of a single resource
+// in the cluster cache
+type ClusterCacheResourceMetric struct {
+	fqName   string
+	help     string
+	resource string
+	value    float64
+}
+
+// Creates a new ClusterCacheResourceMetric, implementation of prometheus.Metric
+func newClusterCacheResourceMetric(fqname, help, resource string, value float64) ClusterCacheResourceMetric {
+	return ClusterCacheResourceMetric{
+		fqName:   fqname,
+		help:     help,
+		resource: resource,
+		value:    value,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (m ClusterCacheResourceMetric) Desc() *prometheus.Desc {
+	return cachedDesc(m.fqName, m.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (m ClusterCacheResourceMetric) Write(dm *dto.Metric) error {
+	dm.Gauge = &dto.Gauge{
+		Value: &m.value,
+	}
+	dm.Label = []*dto.LabelPair{
+		{
+			Name:  toStringPtr("resource"),
+			Value: &m.resource,
+		},
+	}
+	return nil
+}
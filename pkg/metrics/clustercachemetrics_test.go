@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/clustercache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClusterCacheCollectorGather(t *testing.T) {
+	cache := &testClusterCache{
+		statuses: []clustercache.ResourceStatus{
+			{Resource: "pods", Count: 3, LastSync: time.Unix(1600000000, 500000000)},
+			{Resource: "nodes", Count: 1, LastSync: time.Unix(1500000000, 0)},
+			{Resource: "jobs", Count: 0},
+		},
+	}
+
+	// each resource reports its own sync time, such that a stale watch is not hidden by the others
+	expected := `
+# HELP kubecost_clustercache_last_sync_seconds kubecost_clustercache_last_sync_seconds Unix timestamp of the last cluster cache sync by resource
+# TYPE kubecost_clustercache_last_sync_seconds gauge
+kubecost_clustercache_last_sync_seconds{resource="jobs"} 0
+kubecost_clustercache_last_sync_seconds{resource="nodes"} 1.5e+09
+kubecost_clustercache_last_sync_seconds{resource="pods"} 1.6000000005e+09
+# HELP kubecost_clustercache_objects kubecost_clustercache_objects Number of objects in the cluster cache by resource
+# TYPE kubecost_clustercache_objects gauge
+kubecost_clustercache_objects{resource="jobs"} 0
+kubecost_clustercache_objects{resource="nodes"} 1
+kubecost_clustercache_objects{resource="pods"} 3
+`
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(ClusterCacheCollector{KubeClusterCache: cache})
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
@@ -21,7 +21,7 @@ type KubecostDeploymentCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kdc KubecostDeploymentCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("deployment_match_labels", "deployment match labels", []string{}, nil)
+	ch <- cachedDesc("deployment_match_labels", "deployment match labels")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -69,11 +69,7 @@ func newDeploymentMatchLabelsMetric(name, namespace, fqname string, labelNames,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (dmlm DeploymentMatchLabelsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"deployment": dmlm.deploymentName,
-		"namespace":  dmlm.namespace,
-	}
-	return prometheus.NewDesc(dmlm.fqName, dmlm.help, dmlm.labelNames, l)
+	return cachedDesc(dmlm.fqName, dmlm.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -114,8 +110,8 @@ type KubeDeploymentCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kdc KubeDeploymentCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_deployment_spec_replicas", "Number of desired pods for a deployment.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_deployment_status_replicas_available", "The number of available replicas per deployment.", []string{}, nil)
+	ch <- cachedDesc("kube_deployment_spec_replicas", "Number of desired pods for a deployment.")
+	ch <- cachedDesc("kube_deployment_status_replicas_available", "The number of available replicas per deployment.")
 
 }
 
@@ -173,11 +169,7 @@ func newKubeDeploymentReplicasMetric(fqname, deployment, namespace string, repli
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kdr KubeDeploymentReplicasMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"deployment": kdr.deployment,
-		"namespace":  kdr.namespace,
-	}
-	return prometheus.NewDesc(kdr.fqName, kdr.help, []string{}, l)
+	return cachedDesc(kdr.fqName, kdr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -227,11 +219,7 @@ func newKubeDeploymentStatusAvailableReplicasMetric(fqname, deployment, namespac
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kdr KubeDeploymentStatusAvailableReplicasMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"deployment": kdr.deployment,
-		"namespace":  kdr.namespace,
-	}
-	return prometheus.NewDesc(kdr.fqName, kdr.help, []string{}, l)
+	return cachedDesc(kdr.fqName, kdr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
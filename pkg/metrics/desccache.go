@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//--------------------------------------------------------------------------
+//  descCache
+//--------------------------------------------------------------------------
+
+// descKey uniquely identifies a cached descriptor by metric name and help
+type descKey struct {
+	fqName string
+	help   string
+}
+
+// descCache holds the descriptors returned by the collectors and metrics of this package, which
+// are allocated once per metric name rather than on every scrape.
+var descCache = struct {
+	lock  sync.RWMutex
+	descs map[descKey]*prometheus.Desc
+}{
+	descs: make(map[descKey]*prometheus.Desc),
+}
+
+// cachedDesc returns the descriptor for the metric name and help, creating it on first use. The
+// descriptor has no labels, as the labels of each metric are encoded by its Write method, and the
+// registry only uses the name and help of the descriptor of a collected metric.
+func cachedDesc(fqName, help string) *prometheus.Desc {
+	key := descKey{fqName: fqName, help: help}
+
+	descCache.lock.RLock()
+	desc, ok := descCache.descs[key]
+	descCache.lock.RUnlock()
+	if ok {
+		return desc
+	}
+
+	descCache.lock.Lock()
+	defer descCache.lock.Unlock()
+
+	if desc, ok := descCache.descs[key]; ok {
+		return desc
+	}
+
+	desc = prometheus.NewDesc(fqName, help, []string{}, nil)
+	descCache.descs[key] = desc
+	return desc
+}
@@ -23,7 +23,7 @@ type KubeJobCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kjc KubeJobCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_job_status_failed", "The number of pods which reached Phase Failed and the reason for failure.", []string{}, nil)
+	ch <- cachedDesc("kube_job_status_failed", "The number of pods which reached Phase Failed and the reason for failure.")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -84,12 +84,7 @@ func newKubeJobStatusFailedMetric(job, namespace, fqName, reason string, value f
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kjsf KubeJobStatusFailedMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"job_name":  kjsf.job,
-		"namespace": kjsf.namespace,
-		"reason":    kjsf.reason,
-	}
-	return prometheus.NewDesc(kjsf.fqName, kjsf.help, []string{}, l)
+	return cachedDesc(kjsf.fqName, kjsf.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
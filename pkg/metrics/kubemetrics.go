@@ -29,6 +29,8 @@ type KubeMetricsOpts struct {
 	EmitPodAnnotations            bool
 	EmitKubeStateMetrics          bool
 	EmitKubeStateMetricsV1Only    bool
+	EmitClusterCacheMetrics       bool
+	PodLabelsNamespaceFilter      *NamespaceFilter
 }
 
 // DefaultKubeMetricsOpts returns KubeMetricsOpts with default values set
@@ -39,6 +41,8 @@ func DefaultKubeMetricsOpts() *KubeMetricsOpts {
 		EmitPodAnnotations:            false,
 		EmitKubeStateMetrics:          true,
 		EmitKubeStateMetricsV1Only:    false,
+		EmitClusterCacheMetrics:       false,
+		PodLabelsNamespaceFilter:      nil,
 	}
 }
 
@@ -49,64 +53,92 @@ func InitKubeMetrics(clusterCache clustercache.ClusterCache, opts *KubeMetricsOp
 	}
 
 	kubeMetricInit.Do(func() {
-		if opts.EmitKubecostControllerMetrics {
-			prometheus.MustRegister(KubecostServiceCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubecostDeploymentCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubecostStatefulsetCollector{
-				KubeClusterCache: clusterCache,
-			})
-		}
+		registerKubeMetrics(prometheus.DefaultRegisterer, clusterCache, opts)
+	})
+}
 
-		if opts.EmitPodAnnotations {
-			prometheus.MustRegister(KubecostPodCollector{
-				KubeClusterCache: clusterCache,
-			})
-		}
+// registerKubeMetrics registers the collectors enabled by the options with the registerer. The
+// kube-state-metrics collectors are registered for either EmitKubeStateMetrics or
+// EmitKubeStateMetricsV1Only, never both, as they emit metrics with the same names.
+func registerKubeMetrics(registerer prometheus.Registerer, clusterCache clustercache.ClusterCache, opts *KubeMetricsOpts) {
+	if opts.EmitKubecostControllerMetrics {
+		registerer.MustRegister(KubecostServiceCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubecostDeploymentCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubecostStatefulsetCollector{
+			KubeClusterCache: clusterCache,
+		})
+	}
 
-		if opts.EmitNamespaceAnnotations {
-			prometheus.MustRegister(KubecostNamespaceCollector{
-				KubeClusterCache: clusterCache,
-			})
-		}
+	if opts.EmitPodAnnotations {
+		registerer.MustRegister(KubecostPodCollector{
+			KubeClusterCache: clusterCache,
+			NamespaceFilter:  opts.PodLabelsNamespaceFilter,
+		})
+	}
 
-		if opts.EmitKubeStateMetrics {
-			prometheus.MustRegister(KubeNodeCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubeNamespaceCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubeDeploymentCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubePodCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubePVCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubePVCCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubeJobCollector{
-				KubeClusterCache: clusterCache,
-			})
-		} else if opts.EmitKubeStateMetricsV1Only {
-			prometheus.MustRegister(KubeNodeCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubeNamespaceCollector{
-				KubeClusterCache: clusterCache,
-			})
-			prometheus.MustRegister(KubePodLabelsCollector{
-				KubeClusterCache: clusterCache,
-			})
-		}
-	})
+	if opts.EmitNamespaceAnnotations {
+		registerer.MustRegister(KubecostNamespaceCollector{
+			KubeClusterCache: clusterCache,
+		})
+	}
+
+	if opts.EmitKubeStateMetrics {
+		registerer.MustRegister(KubeNodeCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubeNamespaceCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubeDeploymentCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubePodCollector{
+			KubeClusterCache: clusterCache,
+			NamespaceFilter:  opts.PodLabelsNamespaceFilter,
+		})
+		registerer.MustRegister(KubePVCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubePVCCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubeJobCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubeServiceLabelsCollector{
+			KubeClusterCache: clusterCache,
+		})
+	} else if opts.EmitKubeStateMetricsV1Only {
+		registerer.MustRegister(KubeNodeCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubeNamespaceCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubePodLabelsCollector{
+			KubeClusterCache: clusterCache,
+			NamespaceFilter:  opts.PodLabelsNamespaceFilter,
+		})
+		registerer.MustRegister(KubePersistentVolumeClaimLabelsCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubePersistentVolumeLabelsCollector{
+			KubeClusterCache: clusterCache,
+		})
+		registerer.MustRegister(KubeServiceLabelsCollector{
+			KubeClusterCache: clusterCache,
+		})
+	}
+
+	if opts.EmitClusterCacheMetrics {
+		registerer.MustRegister(ClusterCacheCollector{
+			KubeClusterCache: clusterCache,
+		})
+	}
 }
 
 //--------------------------------------------------------------------------
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegisterKubeMetrics(t *testing.T) {
+	testCases := map[string]struct {
+		ksm           bool
+		ksmV1Only     bool
+		serviceLabels bool
+	}{
+		"kube state metrics":         {ksm: true, serviceLabels: true},
+		"kube state metrics v1 only": {ksmV1Only: true, serviceLabels: true},
+		"both":                       {ksm: true, ksmV1Only: true, serviceLabels: true},
+		"neither":                    {},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			opts := &KubeMetricsOpts{
+				EmitKubecostControllerMetrics: true,
+				EmitNamespaceAnnotations:      true,
+				EmitPodAnnotations:            true,
+				EmitKubeStateMetrics:          test.ksm,
+				EmitKubeStateMetricsV1Only:    test.ksmV1Only,
+				EmitClusterCacheMetrics:       true,
+			}
+
+			// registering a collector twice, or two collectors describing the same metric, panics
+			registry := prometheus.NewRegistry()
+			registerKubeMetrics(registry, &testClusterCache{}, opts)
+
+			// the service labels collector is registered exactly once by either branch
+			err := registry.Register(KubeServiceLabelsCollector{KubeClusterCache: &testClusterCache{}})
+			var are prometheus.AlreadyRegisteredError
+			if registered := errors.As(err, &are); registered != test.serviceLabels {
+				t.Fatalf("service labels registered: exp (%t); act (%t): %v", test.serviceLabels, registered, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"path"
+)
+
+//--------------------------------------------------------------------------
+//  NamespaceFilter
+//--------------------------------------------------------------------------
+
+// NamespaceFilter is used by collectors to restrict metric emission to a subset of
+// namespaces. Include and Exclude entries are glob patterns (ie: "team-*") matched
+// against the full namespace name. An empty Include list matches all namespaces,
+// and Exclude always takes precedence over Include.
+type NamespaceFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// NewNamespaceFilter creates a new NamespaceFilter using the provided include and
+// exclude patterns.
+func NewNamespaceFilter(include []string, exclude []string) *NamespaceFilter {
+	return &NamespaceFilter{
+		Include: include,
+		Exclude: exclude,
+	}
+}
+
+// Matches returns true if the namespace should be included by the filter. A nil
+// filter matches all namespaces.
+func (nf *NamespaceFilter) Matches(namespace string) bool {
+	if nf == nil {
+		return true
+	}
+
+	if matchesAnyNamespacePattern(nf.Exclude, namespace) {
+		return false
+	}
+
+	if len(nf.Include) == 0 {
+		return true
+	}
+
+	return matchesAnyNamespacePattern(nf.Include, namespace)
+}
+
+// matchesAnyNamespacePattern returns true if the namespace matches any of the glob patterns
+// provided. Malformed patterns are treated as literal namespace names.
+func matchesAnyNamespacePattern(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if pattern == namespace {
+			return true
+		}
+
+		matched, err := path.Match(pattern, namespace)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import "testing"
+
+func TestNamespaceFilterMatches(t *testing.T) {
+	testCases := map[string]struct {
+		filter    *NamespaceFilter
+		namespace string
+		expected  bool
+	}{
+		"nil filter": {
+			filter:    nil,
+			namespace: "kubecost",
+			expected:  true,
+		},
+		"empty filter": {
+			filter:    NewNamespaceFilter(nil, nil),
+			namespace: "kubecost",
+			expected:  true,
+		},
+		"exact include": {
+			filter:    NewNamespaceFilter([]string{"kubecost"}, nil),
+			namespace: "kubecost",
+			expected:  true,
+		},
+		"exact include mismatch": {
+			filter:    NewNamespaceFilter([]string{"kubecost"}, nil),
+			namespace: "default",
+			expected:  false,
+		},
+		"glob include": {
+			filter:    NewNamespaceFilter([]string{"team-*"}, nil),
+			namespace: "team-payments",
+			expected:  true,
+		},
+		"glob include mismatch": {
+			filter:    NewNamespaceFilter([]string{"team-*"}, nil),
+			namespace: "payments-team",
+			expected:  false,
+		},
+		"prefix include": {
+			filter:    NewNamespaceFilter([]string{"kube-*"}, nil),
+			namespace: "kube-system",
+			expected:  true,
+		},
+		"prefix is not a substring match": {
+			filter:    NewNamespaceFilter([]string{"kube-*"}, nil),
+			namespace: "my-kube-system",
+			expected:  false,
+		},
+		"exclude": {
+			filter:    NewNamespaceFilter(nil, []string{"kube-*"}),
+			namespace: "kube-system",
+			expected:  false,
+		},
+		"exclude wins over include": {
+			filter:    NewNamespaceFilter([]string{"team-*"}, []string{"team-secret"}),
+			namespace: "team-secret",
+			expected:  false,
+		},
+		"exclude glob wins over exact include": {
+			filter:    NewNamespaceFilter([]string{"team-secret"}, []string{"team-*"}),
+			namespace: "team-secret",
+			expected:  false,
+		},
+		"malformed pattern is literal": {
+			filter:    NewNamespaceFilter([]string{"team-["}, nil),
+			namespace: "team-[",
+			expected:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := tc.filter.Matches(tc.namespace); actual != tc.expected {
+				t.Fatalf("Matches(%s): exp (%t); act (%t)", tc.namespace, tc.expected, actual)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ type KubecostNamespaceCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (nsac KubecostNamespaceCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_namespace_annotations", "namespace annotations", []string{}, nil)
+	ch <- cachedDesc("kube_namespace_annotations", "namespace annotations")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -64,10 +64,7 @@ func newNamespaceAnnotationsMetric(fqname, namespace string, labelNames []string
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam NamespaceAnnotationsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": nam.namespace,
-	}
-	return prometheus.NewDesc(nam.fqName, nam.help, []string{}, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -105,7 +102,7 @@ type KubeNamespaceCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (nsac KubeNamespaceCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_namespace_labels", "namespace labels", []string{}, nil)
+	ch <- cachedDesc("kube_namespace_labels", "namespace labels")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -149,10 +146,7 @@ func newKubeNamespaceLabelsMetric(fqname, namespace string, labelNames []string,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam KubeNamespaceLabelsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": nam.namespace,
-	}
-	return prometheus.NewDesc(nam.fqName, nam.help, []string{}, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -27,14 +27,14 @@ type KubeNodeCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (nsac KubeNodeCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_node_status_capacity", "Node resource capacity.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_status_capacity_memory_bytes", "node capacity memory bytes", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_status_capacity_cpu_cores", "node capacity cpu cores", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_status_allocatable", "The allocatable for different resources of a node that are available for scheduling.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_status_allocatable_cpu_cores", "The allocatable cpu cores.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_status_allocatable_memory_bytes", "The allocatable memory in bytes.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_labels", "all labels for each node prefixed with label_", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_node_status_condition", "The condition of a cluster node.", []string{}, nil)
+	ch <- cachedDesc("kube_node_status_capacity", "Node resource capacity.")
+	ch <- cachedDesc("kube_node_status_capacity_memory_bytes", "node capacity memory bytes")
+	ch <- cachedDesc("kube_node_status_capacity_cpu_cores", "node capacity cpu cores")
+	ch <- cachedDesc("kube_node_status_allocatable", "The allocatable for different resources of a node that are available for scheduling.")
+	ch <- cachedDesc("kube_node_status_allocatable_cpu_cores", "The allocatable cpu cores.")
+	ch <- cachedDesc("kube_node_status_allocatable_memory_bytes", "The allocatable memory in bytes.")
+	ch <- cachedDesc("kube_node_labels", "all labels for each node prefixed with label_")
+	ch <- cachedDesc("kube_node_status_condition", "The condition of a cluster node.")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -133,12 +133,7 @@ func newKubeNodeStatusCapacityMetric(fqname, node, resource, unit string, value
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubeNodeStatusCapacityMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"node":     kpcrr.node,
-		"resource": kpcrr.resource,
-		"unit":     kpcrr.unit,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -191,8 +186,7 @@ func newKubeNodeStatusCapacityMemoryBytesMetric(fqname string, node string, byte
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam KubeNodeStatusCapacityMemoryBytesMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{"node": nam.node}
-	return prometheus.NewDesc(nam.fqName, nam.help, []string{}, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -237,8 +231,7 @@ func newKubeNodeStatusCapacityCPUCoresMetric(fqname string, node string, cores f
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam KubeNodeStatusCapacityCPUCoresMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{"node": nam.node}
-	return prometheus.NewDesc(nam.fqName, nam.help, []string{}, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -293,10 +286,7 @@ func newKubeNodeLabelsMetric(node string, fqname string, labelNames []string, la
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam KubeNodeLabelsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"node": nam.node,
-	}
-	return prometheus.NewDesc(nam.fqName, nam.help, nam.labelNames, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -350,12 +340,7 @@ func newKubeNodeStatusConditionMetric(node, fqname, condition, status string, va
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam KubeNodeStatusConditionMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"node":      nam.node,
-		"condition": nam.condition,
-		"status":    nam.status,
-	}
-	return prometheus.NewDesc(nam.fqName, nam.help, []string{}, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -430,12 +415,7 @@ func newKubeNodeStatusAllocatableMetric(fqname, node, resource, unit string, val
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubeNodeStatusAllocatableMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"node":     kpcrr.node,
-		"resource": kpcrr.resource,
-		"unit":     kpcrr.unit,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -488,10 +468,7 @@ func newKubeNodeStatusAllocatableCPUCoresMetric(fqname, node string, value float
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubeNodeStatusAllocatableCPUCoresMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"node": kpcrr.node,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -536,10 +513,7 @@ func newKubeNodeStatusAllocatableMemoryBytesMetric(fqname, node string, value fl
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubeNodeStatusAllocatableMemoryBytesMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"node": kpcrr.node,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
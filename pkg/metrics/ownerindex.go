@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"github.com/kubecost/cost-model/pkg/clustercache"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//--------------------------------------------------------------------------
+//  ownerIndex
+//--------------------------------------------------------------------------
+
+// ownerKey uniquely identifies a namespaced controller object by kind and name
+type ownerKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// ownerIndex is a lookup of the owner references for each controller object in the
+// cluster cache. It's used to walk a pod's owner references to the top-level controller,
+// ie: Pod -> ReplicaSet -> Deployment.
+type ownerIndex struct {
+	owners map[ownerKey][]metav1.OwnerReference
+}
+
+// newOwnerIndex creates a new ownerIndex from the controllers in the provided cluster cache. This
+// copies the owner references of every ReplicaSet, Deployment, StatefulSet, DaemonSet, Job and
+// ReplicationController in the cache, which is O(controllers) allocations on each call, so it should
+// be created once per scrape, and only if a root owner is resolved.
+func newOwnerIndex(cache clustercache.ClusterCache) *ownerIndex {
+	owners := make(map[ownerKey][]metav1.OwnerReference)
+
+	for _, rs := range cache.GetAllReplicaSets() {
+		owners[ownerKey{"ReplicaSet", rs.GetNamespace(), rs.GetName()}] = rs.GetOwnerReferences()
+	}
+	for _, d := range cache.GetAllDeployments() {
+		owners[ownerKey{"Deployment", d.GetNamespace(), d.GetName()}] = d.GetOwnerReferences()
+	}
+	for _, ss := range cache.GetAllStatefulSets() {
+		owners[ownerKey{"StatefulSet", ss.GetNamespace(), ss.GetName()}] = ss.GetOwnerReferences()
+	}
+	for _, ds := range cache.GetAllDaemonSets() {
+		owners[ownerKey{"DaemonSet", ds.GetNamespace(), ds.GetName()}] = ds.GetOwnerReferences()
+	}
+	for _, job := range cache.GetAllJobs() {
+		owners[ownerKey{"Job", job.GetNamespace(), job.GetName()}] = job.GetOwnerReferences()
+	}
+	for _, rc := range cache.GetAllReplicationControllers() {
+		owners[ownerKey{"ReplicationController", rc.GetNamespace(), rc.GetName()}] = rc.GetOwnerReferences()
+	}
+
+	return &ownerIndex{
+		owners: owners,
+	}
+}
+
+// rootOwner walks the owner references starting with the provided references until the
+// top-level owner is located, returning its kind and name. If an intermediate owner does not
+// exist in the index, or a cycle is detected, the last resolved owner is returned. The ok
+// return is false if there are no owner references to walk.
+func (oi *ownerIndex) rootOwner(namespace string, refs []metav1.OwnerReference) (kind string, name string, ok bool) {
+	ref, ok := controllerOwnerRef(refs)
+	if !ok {
+		return "", "", false
+	}
+
+	current := ownerKey{ref.Kind, namespace, ref.Name}
+	visited := map[ownerKey]bool{}
+
+	for {
+		visited[current] = true
+
+		parentRefs, exists := oi.owners[current]
+		if !exists {
+			break
+		}
+
+		parentRef, hasParent := controllerOwnerRef(parentRefs)
+		if !hasParent {
+			break
+		}
+
+		parent := ownerKey{parentRef.Kind, namespace, parentRef.Name}
+		if visited[parent] {
+			break
+		}
+
+		current = parent
+	}
+
+	return current.kind, current.name, true
+}
+
+// controllerOwnerRef returns the managing controller reference if one exists, otherwise the
+// first owner reference.
+func controllerOwnerRef(refs []metav1.OwnerReference) (metav1.OwnerReference, bool) {
+	if len(refs) == 0 {
+		return metav1.OwnerReference{}, false
+	}
+
+	for _, ref := range refs {
+		if ref.Controller != nil && *ref.Controller {
+			return ref, true
+		}
+	}
+
+	return refs[0], true
+}
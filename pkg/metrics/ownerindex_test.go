@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func ownerRef(kind, name string, controller bool) metav1.OwnerReference {
+	return metav1.OwnerReference{Kind: kind, Name: name, Controller: &controller}
+}
+
+func TestOwnerIndexRootOwner(t *testing.T) {
+	testCases := map[string]struct {
+		owners       map[ownerKey][]metav1.OwnerReference
+		refs         []metav1.OwnerReference
+		expectedKind string
+		expectedName string
+		expectedOK   bool
+	}{
+		"pod to replicaset to deployment": {
+			owners: map[ownerKey][]metav1.OwnerReference{
+				{"ReplicaSet", "default", "web-5d4f"}: {ownerRef("Deployment", "web", true)},
+				{"Deployment", "default", "web"}:      nil,
+			},
+			refs:         []metav1.OwnerReference{ownerRef("ReplicaSet", "web-5d4f", true)},
+			expectedKind: "Deployment",
+			expectedName: "web",
+			expectedOK:   true,
+		},
+		"missing replicaset": {
+			owners:       map[ownerKey][]metav1.OwnerReference{},
+			refs:         []metav1.OwnerReference{ownerRef("ReplicaSet", "web-5d4f", true)},
+			expectedKind: "ReplicaSet",
+			expectedName: "web-5d4f",
+			expectedOK:   true,
+		},
+		"replicaset in another namespace": {
+			owners: map[ownerKey][]metav1.OwnerReference{
+				{"ReplicaSet", "other", "web-5d4f"}: {ownerRef("Deployment", "web", true)},
+			},
+			refs:         []metav1.OwnerReference{ownerRef("ReplicaSet", "web-5d4f", true)},
+			expectedKind: "ReplicaSet",
+			expectedName: "web-5d4f",
+			expectedOK:   true,
+		},
+		"self cycle": {
+			owners: map[ownerKey][]metav1.OwnerReference{
+				{"ReplicaSet", "default", "web-5d4f"}: {ownerRef("ReplicaSet", "web-5d4f", true)},
+			},
+			refs:         []metav1.OwnerReference{ownerRef("ReplicaSet", "web-5d4f", true)},
+			expectedKind: "ReplicaSet",
+			expectedName: "web-5d4f",
+			expectedOK:   true,
+		},
+		"two node cycle": {
+			owners: map[ownerKey][]metav1.OwnerReference{
+				{"ReplicaSet", "default", "web-5d4f"}: {ownerRef("Deployment", "web", true)},
+				{"Deployment", "default", "web"}:      {ownerRef("ReplicaSet", "web-5d4f", true)},
+			},
+			refs:         []metav1.OwnerReference{ownerRef("ReplicaSet", "web-5d4f", true)},
+			expectedKind: "Deployment",
+			expectedName: "web",
+			expectedOK:   true,
+		},
+		"controller reference preferred": {
+			owners: map[ownerKey][]metav1.OwnerReference{
+				{"Job", "default", "backup-1"}: {ownerRef("CronJob", "backup", true)},
+			},
+			refs: []metav1.OwnerReference{
+				ownerRef("ConfigMap", "backup-config", false),
+				ownerRef("Job", "backup-1", true),
+			},
+			expectedKind: "CronJob",
+			expectedName: "backup",
+			expectedOK:   true,
+		},
+		"bare pod": {
+			owners:     map[ownerKey][]metav1.OwnerReference{},
+			refs:       nil,
+			expectedOK: false,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			oi := &ownerIndex{owners: test.owners}
+
+			kind, ownerName, ok := oi.rootOwner("default", test.refs)
+			if ok != test.expectedOK {
+				t.Fatalf("ok: exp (%t); act (%t)", test.expectedOK, ok)
+			}
+			if kind != test.expectedKind {
+				t.Fatalf("kind: exp (%s); act (%s)", test.expectedKind, kind)
+			}
+			if ownerName != test.expectedName {
+				t.Fatalf("name: exp (%s); act (%s)", test.expectedName, ownerName)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/kubecost/cost-model/pkg/clustercache"
 	"github.com/kubecost/cost-model/pkg/prom"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 //--------------------------------------------------------------------------
@@ -13,12 +14,13 @@ import (
 // KubecostPodCollector is a prometheus collector that emits pod metrics
 type KubecostPodLabelsCollector struct {
 	KubeClusterCache clustercache.ClusterCache
+	NamespaceFilter  *NamespaceFilter
 }
 
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kpmc KubecostPodLabelsCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_pod_annotations", "All annotations for each pod prefix with annotation_", []string{}, nil)
+	ch <- cachedDesc("kube_pod_annotations", "All annotations for each pod prefix with annotation_")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -27,6 +29,9 @@ func (kpmc KubecostPodLabelsCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, pod := range pods {
 		podName := pod.GetName()
 		podNS := pod.GetNamespace()
+		if !kpmc.NamespaceFilter.Matches(podNS) {
+			continue
+		}
 
 		// Pod Annotations
 		labels, values := prom.KubeAnnotationsToLabels(pod.Annotations)
@@ -43,21 +48,35 @@ func (kpmc KubecostPodLabelsCollector) Collect(ch chan<- prometheus.Metric) {
 // KubePodLabelsCollector is a prometheus collector that emits pod labels only
 type KubePodLabelsCollector struct {
 	KubeClusterCache clustercache.ClusterCache
+	NamespaceFilter  *NamespaceFilter
 }
 
 // Describe sends the super-set of all possible descriptors of pod labels only
 // collected by this Collector.
 func (kpmc KubePodLabelsCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_pod_labels", "All labels for each pod prefixed with label_", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_owner", "Information about the Pod's owner", []string{}, nil)
+	ch <- cachedDesc("kube_pod_labels", "All labels for each pod prefixed with label_")
+	ch <- cachedDesc("kube_pod_owner", "Information about the Pod's owner")
+	ch <- cachedDesc("kube_pod_root_owner", "Information about the Pod's top-level controller")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
 func (kpmc KubePodLabelsCollector) Collect(ch chan<- prometheus.Metric) {
 	pods := kpmc.KubeClusterCache.GetAllPods()
+	if len(pods) == 0 {
+		return
+	}
+
+	// the owner index is built on the first pod with owner references, as it's
+	// not needed if every pod is filtered or unowned
+	var owners *ownerIndex
+
 	for _, pod := range pods {
 		podName := pod.GetName()
 		podNS := pod.GetNamespace()
+		if !kpmc.NamespaceFilter.Matches(podNS) {
+			continue
+		}
+
 		podUID := string(pod.GetUID())
 
 		// Pod Labels
@@ -68,5 +87,77 @@ func (kpmc KubePodLabelsCollector) Collect(ch chan<- prometheus.Metric) {
 		for _, owner := range pod.OwnerReferences {
 			ch <- newKubePodOwnerMetric("kube_pod_owner", podNS, podName, owner.Name, owner.Kind, owner.Controller != nil)
 		}
+
+		// Root Owner
+		if len(pod.OwnerReferences) == 0 {
+			continue
+		}
+		if owners == nil {
+			owners = newOwnerIndex(kpmc.KubeClusterCache)
+		}
+		if kind, name, ok := owners.rootOwner(podNS, pod.OwnerReferences); ok {
+			ch <- newKubePodRootOwnerMetric("kube_pod_root_owner", podNS, podName, name, kind)
+		}
+	}
+}
+
+//--------------------------------------------------------------------------
+//  KubePodRootOwnerMetric
+//--------------------------------------------------------------------------
+
+// KubePodRootOwnerMetric is a prometheus.Metric used to encode the top-level controller of a pod
+type KubePodRootOwnerMetric struct {
+	fqName    string
+	help      string
+	namespace string
+	pod       string
+	ownerName string
+	ownerKind string
+}
+
+// Creates a new KubePodRootOwnerMetric, implementation of prometheus.Metric
+func newKubePodRootOwnerMetric(fqname, namespace, pod, ownerName, ownerKind string) KubePodRootOwnerMetric {
+	return KubePodRootOwnerMetric{
+		fqName:    fqname,
+		help:      "kube_pod_root_owner Information about the Pod's top-level controller",
+		namespace: namespace,
+		pod:       pod,
+		ownerName: ownerName,
+		ownerKind: ownerKind,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (kpro KubePodRootOwnerMetric) Desc() *prometheus.Desc {
+	return cachedDesc(kpro.fqName, kpro.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (kpro KubePodRootOwnerMetric) Write(m *dto.Metric) error {
+	v := float64(1.0)
+	m.Gauge = &dto.Gauge{
+		Value: &v,
+	}
+
+	m.Label = []*dto.LabelPair{
+		{
+			Name:  toStringPtr("namespace"),
+			Value: &kpro.namespace,
+		},
+		{
+			Name:  toStringPtr("pod"),
+			Value: &kpro.pod,
+		},
+		{
+			Name:  toStringPtr("owner_name"),
+			Value: &kpro.ownerName,
+		},
+		{
+			Name:  toStringPtr("owner_kind"),
+			Value: &kpro.ownerKind,
+		},
 	}
+	return nil
 }
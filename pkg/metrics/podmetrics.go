@@ -18,12 +18,16 @@ import (
 // KubecostPodCollector is a prometheus collector that emits pod metrics
 type KubecostPodCollector struct {
 	KubeClusterCache clustercache.ClusterCache
+
+	// NamespaceFilter restricts the emitted pod annotations to pods in matching namespaces. A nil
+	// filter matches all namespaces.
+	NamespaceFilter *NamespaceFilter
 }
 
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kpmc KubecostPodCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_pod_annotations", "All annotations for each pod prefix with annotation_", []string{}, nil)
+	ch <- cachedDesc("kube_pod_annotations", "All annotations for each pod prefix with annotation_")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -32,6 +36,9 @@ func (kpmc KubecostPodCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, pod := range pods {
 		podName := pod.GetName()
 		podNS := pod.GetNamespace()
+		if !kpmc.NamespaceFilter.Matches(podNS) {
+			continue
+		}
 
 		// Pod Annotations
 		labels, values := prom.KubeAnnotationsToLabels(pod.Annotations)
@@ -48,21 +55,27 @@ func (kpmc KubecostPodCollector) Collect(ch chan<- prometheus.Metric) {
 // KubePodMetricCollector is a prometheus collector that emits pod metrics
 type KubePodCollector struct {
 	KubeClusterCache clustercache.ClusterCache
+
+	// NamespaceFilter restricts kube_pod_labels to pods in matching namespaces. All other pod
+	// metrics are emitted for every pod, as they are required for cost allocation. A nil filter
+	// matches all namespaces.
+	NamespaceFilter *NamespaceFilter
 }
 
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kpmc KubePodCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_pod_labels", "All labels for each pod prefixed with label_", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_owner", "Information about the Pod's owner", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_status_running", "Describes whether the container is currently in running state", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_status_terminated_reason", "Describes the reason the container is currently in terminated state.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_status_restarts_total", "The number of container restarts per container.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_resource_requests", "The number of requested resource by a container", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_resource_limits", "The number of requested limit resource by a container.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_resource_limits_cpu_cores", "The number of requested limit cpu core resource by a container.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_container_resource_limits_memory_bytes", "The number of requested limit memory resource by a container.", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_pod_status_phase", "The pods current phase.", []string{}, nil)
+	ch <- cachedDesc("kube_pod_labels", "All labels for each pod prefixed with label_")
+	ch <- cachedDesc("kube_pod_owner", "Information about the Pod's owner")
+	ch <- cachedDesc("kube_pod_container_status_running", "Describes whether the container is currently in running state")
+	ch <- cachedDesc("kube_pod_container_status_terminated_reason", "Describes the reason the container is currently in terminated state.")
+	ch <- cachedDesc("kube_pod_container_status_restarts_total", "The number of container restarts per container.")
+	ch <- cachedDesc("kube_pod_container_resource_requests", "The number of requested resource by a container")
+	ch <- cachedDesc("kube_pod_container_resource_limits", "The number of requested limit resource by a container.")
+	ch <- cachedDesc("kube_pod_container_resource_limits_cpu_cores", "The number of requested limit cpu core resource by a container.")
+	ch <- cachedDesc("kube_pod_container_resource_limits_memory_bytes", "The number of requested limit memory resource by a container.")
+	ch <- cachedDesc("kube_pod_status_phase", "The pods current phase.")
+	ch <- cachedDesc("kube_pod_created", "Unix creation timestamp")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -93,9 +106,16 @@ func (kpmc KubePodCollector) Collect(ch chan<- prometheus.Metric) {
 			}
 		}
 
+		// Pod Creation Timestamp
+		if !pod.CreationTimestamp.IsZero() {
+			ch <- newKubePodCreatedMetric("kube_pod_created", podNS, podName, podUID, float64(pod.CreationTimestamp.Unix()))
+		}
+
 		// Pod Labels
-		labelNames, labelValues := prom.KubePrependQualifierToLabels(pod.GetLabels(), "label_")
-		ch <- newKubePodLabelsMetric("kube_pod_labels", podNS, podName, podUID, labelNames, labelValues)
+		if kpmc.NamespaceFilter.Matches(podNS) {
+			labelNames, labelValues := prom.KubePrependQualifierToLabels(pod.GetLabels(), "label_")
+			ch <- newKubePodLabelsMetric("kube_pod_labels", podNS, podName, podUID, labelNames, labelValues)
+		}
 
 		// Owner References
 		for _, owner := range pod.OwnerReferences {
@@ -219,11 +239,7 @@ func newPodAnnotationMetric(fqname, namespace, pod string, labelNames, labelValu
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (pam PodAnnotationsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": pam.namespace,
-		"pod":       pam.pod,
-	}
-	return prometheus.NewDesc(pam.fqName, pam.help, []string{}, l)
+	return cachedDesc(pam.fqName, pam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -287,12 +303,7 @@ func newKubePodLabelsMetric(fqname, namespace, pod, uid string, labelNames []str
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (nam KubePodLabelsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": nam.namespace,
-		"pod":       nam.pod,
-		"uid":       nam.uid,
-	}
-	return prometheus.NewDesc(nam.fqName, nam.help, nam.labelNames, l)
+	return cachedDesc(nam.fqName, nam.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -360,13 +371,7 @@ func newKubePodContainerStatusRestartsTotalMetric(fqname, namespace, pod, uid, c
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcs KubePodContainerStatusRestartsTotalMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcs.namespace,
-		"pod":       kpcs.pod,
-		"uid":       kpcs.uid,
-		"container": kpcs.container,
-	}
-	return prometheus.NewDesc(kpcs.fqName, kpcs.help, []string{}, l)
+	return cachedDesc(kpcs.fqName, kpcs.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -429,14 +434,7 @@ func newKubePodContainerStatusTerminatedReasonMetric(fqname, namespace, pod, uid
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcs KubePodContainerStatusTerminatedReasonMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcs.namespace,
-		"pod":       kpcs.pod,
-		"uid":       kpcs.uid,
-		"container": kpcs.container,
-		"reason":    kpcs.reason,
-	}
-	return prometheus.NewDesc(kpcs.fqName, kpcs.help, []string{}, l)
+	return cachedDesc(kpcs.fqName, kpcs.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -504,13 +502,7 @@ func newKubePodStatusPhaseMetric(fqname, namespace, pod, uid, phase string, valu
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcs KubePodStatusPhaseMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcs.namespace,
-		"pod":       kpcs.pod,
-		"uid":       kpcs.uid,
-		"phase":     kpcs.phase,
-	}
-	return prometheus.NewDesc(kpcs.fqName, kpcs.help, []string{}, l)
+	return cachedDesc(kpcs.fqName, kpcs.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
@@ -542,6 +534,61 @@ func (kpcs KubePodStatusPhaseMetric) Write(m *dto.Metric) error {
 	return nil
 }
 
+//--------------------------------------------------------------------------
+//  KubePodCreatedMetric
+//--------------------------------------------------------------------------
+
+// KubePodCreatedMetric is a prometheus.Metric emitting the unix creation timestamp of a pod
+type KubePodCreatedMetric struct {
+	fqName    string
+	help      string
+	pod       string
+	namespace string
+	uid       string
+	value     float64
+}
+
+// Creates a new KubePodCreatedMetric, implementation of prometheus.Metric
+func newKubePodCreatedMetric(fqname, namespace, pod, uid string, value float64) KubePodCreatedMetric {
+	return KubePodCreatedMetric{
+		fqName:    fqname,
+		help:      "kube_pod_created Unix creation timestamp",
+		pod:       pod,
+		namespace: namespace,
+		uid:       uid,
+		value:     value,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (kpc KubePodCreatedMetric) Desc() *prometheus.Desc {
+	return cachedDesc(kpc.fqName, kpc.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data transmission object.
+func (kpc KubePodCreatedMetric) Write(m *dto.Metric) error {
+	m.Gauge = &dto.Gauge{
+		Value: &kpc.value,
+	}
+
+	m.Label = []*dto.LabelPair{
+		{
+			Name:  toStringPtr("namespace"),
+			Value: &kpc.namespace,
+		},
+		{
+			Name:  toStringPtr("pod"),
+			Value: &kpc.pod,
+		},
+		{
+			Name:  toStringPtr("uid"),
+			Value: &kpc.uid,
+		},
+	}
+	return nil
+}
+
 //--------------------------------------------------------------------------
 //  KubePodContainerStatusRunningMetric
 //--------------------------------------------------------------------------
@@ -573,13 +620,7 @@ func newKubePodContainerStatusRunningMetric(fqname, namespace, pod, uid, contain
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcs KubePodContainerStatusRunningMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcs.namespace,
-		"pod":       kpcs.pod,
-		"uid":       kpcs.uid,
-		"container": kpcs.container,
-	}
-	return prometheus.NewDesc(kpcs.fqName, kpcs.help, []string{}, l)
+	return cachedDesc(kpcs.fqName, kpcs.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -650,16 +691,7 @@ func newKubePodContainerResourceRequestsMetric(fqname, namespace, pod, uid, cont
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubePodContainerResourceRequestsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcrr.namespace,
-		"pod":       kpcrr.pod,
-		"uid":       kpcrr.uid,
-		"container": kpcrr.container,
-		"node":      kpcrr.node,
-		"resource":  kpcrr.resource,
-		"unit":      kpcrr.unit,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -739,16 +771,7 @@ func newKubePodContainerResourceLimitsMetric(fqname, namespace, pod, uid, contai
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubePodContainerResourceLimitsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcrr.namespace,
-		"pod":       kpcrr.pod,
-		"uid":       kpcrr.uid,
-		"container": kpcrr.container,
-		"node":      kpcrr.node,
-		"resource":  kpcrr.resource,
-		"unit":      kpcrr.unit,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -824,14 +847,7 @@ func newKubePodContainerResourceLimitsCPUCoresMetric(fqname, namespace, pod, uid
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubePodContainerResourceLimitsCPUCoresMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcrr.namespace,
-		"pod":       kpcrr.pod,
-		"uid":       kpcrr.uid,
-		"container": kpcrr.container,
-		"node":      kpcrr.node,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -899,14 +915,7 @@ func newKubePodContainerResourceLimitsMemoryBytesMetric(fqname, namespace, pod,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubePodContainerResourceLimitsMemoryBytesMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace": kpcrr.namespace,
-		"pod":       kpcrr.pod,
-		"uid":       kpcrr.uid,
-		"container": kpcrr.container,
-		"node":      kpcrr.node,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -972,14 +981,7 @@ func newKubePodOwnerMetric(fqname, namespace, pod, ownerName, ownerKind string,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpo KubePodOwnerMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"namespace":           kpo.namespace,
-		"pod":                 kpo.pod,
-		"owner_name":          kpo.ownerName,
-		"owner_kind":          kpo.ownerKind,
-		"owner_is_controller": fmt.Sprintf("%t", kpo.ownerIsController),
-	}
-	return prometheus.NewDesc(kpo.fqName, kpo.help, []string{}, l)
+	return cachedDesc(kpo.fqName, kpo.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestKubePodCollectorNamespaceFilter(t *testing.T) {
+	controller := true
+	cache := &testClusterCache{
+		pods: []*v1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:       "team-a",
+					Name:            "web",
+					UID:             types.UID("uid-web"),
+					Labels:          map[string]string{"app": "web"},
+					Annotations:     map[string]string{"owner": "a"},
+					OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d4f", Controller: &controller}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:       "kube-system",
+					Name:            "dns",
+					UID:             types.UID("uid-dns"),
+					Labels:          map[string]string{"app": "dns"},
+					Annotations:     map[string]string{"owner": "system"},
+					OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "dns-7c9a", Controller: &controller}},
+				},
+			},
+		},
+	}
+	filter := NewNamespaceFilter([]string{"team-*", "kube-*"}, []string{"kube-system"})
+
+	// labels and annotations are only emitted for matching namespaces, while the remaining pod
+	// metrics are emitted for all pods
+	expected := `
+# HELP kube_pod_annotations kube_pod_annotations Pod Annotations
+# TYPE kube_pod_annotations gauge
+kube_pod_annotations{annotation_owner="a",namespace="team-a",pod="web"} 1
+# HELP kube_pod_labels kube_pod_labels all labels for each pod prefixed with label_
+# TYPE kube_pod_labels gauge
+kube_pod_labels{label_app="web",namespace="team-a",pod="web",uid="uid-web"} 1
+# HELP kube_pod_owner kube_pod_owner Information about the Pod's owner
+# TYPE kube_pod_owner gauge
+kube_pod_owner{namespace="kube-system",owner_is_controller="true",owner_kind="ReplicaSet",owner_name="dns-7c9a",pod="dns"} 1
+kube_pod_owner{namespace="team-a",owner_is_controller="true",owner_kind="ReplicaSet",owner_name="web-5d4f",pod="web"} 1
+`
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		KubePodCollector{KubeClusterCache: cache, NamespaceFilter: filter},
+		KubecostPodCollector{KubeClusterCache: cache, NamespaceFilter: filter},
+	)
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "kube_pod_annotations", "kube_pod_labels", "kube_pod_owner")
+	if err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
+
+func TestKubePodCollectorCreated(t *testing.T) {
+	cache := &testClusterCache{
+		pods: []*v1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "default",
+					Name:              "web",
+					UID:               types.UID("uid-web"),
+					CreationTimestamp: metav1.NewTime(time.Unix(1622505600, 0)),
+				},
+			},
+			{
+				// pods without a creation timestamp, ie: not yet persisted, have no kube_pod_created
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "pending",
+					UID:       types.UID("uid-pending"),
+				},
+			},
+		},
+	}
+
+	expected := `
+# HELP kube_pod_created kube_pod_created Unix creation timestamp
+# TYPE kube_pod_created gauge
+kube_pod_created{namespace="default",pod="web",uid="uid-web"} 1.6225056e+09
+`
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(KubePodCollector{KubeClusterCache: cache})
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "kube_pod_created"); err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"github.com/kubecost/cost-model/pkg/clustercache"
+	"github.com/kubecost/cost-model/pkg/prom"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	v1 "k8s.io/api/core/v1"
@@ -18,8 +19,9 @@ type KubePVCCollector struct {
 
 // Describe sends the super-set of all possible descriptors of metrics collected by this Collector.
 func (kpvc KubePVCCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_persistentvolumeclaim_resource_requests_storage_bytes", "The pvc storage resource requests in bytes", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_persistentvolumeclaim_info", "The pvc storage resource requests in bytes", []string{}, nil)
+	ch <- cachedDesc("kube_persistentvolumeclaim_resource_requests_storage_bytes", "The pvc storage resource requests in bytes")
+	ch <- cachedDesc("kube_persistentvolumeclaim_info", "The pvc storage resource requests in bytes")
+	ch <- cachedDesc("kube_persistentvolumeclaim_labels", "All labels for each pvc prefixed with label_")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -31,6 +33,9 @@ func (kpvc KubePVCCollector) Collect(ch chan<- prometheus.Metric) {
 
 		ch <- newKubePVCInfoMetric("kube_persistentvolumeclaim_info", pvc.Name, pvc.Namespace, storageClass, volume)
 
+		labelNames, labelValues := prom.KubePrependQualifierToLabels(pvc.GetLabels(), "label_")
+		ch <- newKubePVCLabelsMetric("kube_persistentvolumeclaim_labels", pvc.Namespace, pvc.Name, labelNames, labelValues)
+
 		if storage, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]; ok {
 			ch <- newKubePVCResourceRequestsStorageBytesMetric("kube_persistentvolumeclaim_resource_requests_storage_bytes", pvc.Name, pvc.Namespace, float64(storage.Value()))
 		}
@@ -64,11 +69,7 @@ func newKubePVCResourceRequestsStorageBytesMetric(fqname, pvc, namespace string,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpvcrr KubePVCResourceRequestsStorageBytesMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"persistentvolumeclaim": kpvcrr.pvc,
-		"namespace":             kpvcrr.namespace,
-	}
-	return prometheus.NewDesc(kpvcrr.fqName, kpvcrr.help, []string{}, l)
+	return cachedDesc(kpvcrr.fqName, kpvcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -120,13 +121,7 @@ func newKubePVCInfoMetric(fqname, pvc, namespace, storageclass, volume string) K
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpvcrr KubePVCInfoMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"persistentvolumeclaim": kpvcrr.pvc,
-		"namespace":             kpvcrr.namespace,
-		"storageclass":          kpvcrr.storageclass,
-		"volumename":            kpvcrr.volume,
-	}
-	return prometheus.NewDesc(kpvcrr.fqName, kpvcrr.help, []string{}, l)
+	return cachedDesc(kpvcrr.fqName, kpvcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -157,3 +152,65 @@ func (kpvci KubePVCInfoMetric) Write(m *dto.Metric) error {
 	}
 	return nil
 }
+
+//--------------------------------------------------------------------------
+//  KubePVCLabelsMetric
+//--------------------------------------------------------------------------
+
+// KubePVCLabelsMetric is a prometheus.Metric used to encode persistent volume claim labels
+type KubePVCLabelsMetric struct {
+	fqName      string
+	help        string
+	labelNames  []string
+	labelValues []string
+	pvc         string
+	namespace   string
+}
+
+// Creates a new KubePVCLabelsMetric, implementation of prometheus.Metric
+func newKubePVCLabelsMetric(fqname, namespace, pvc string, labelNames []string, labelValues []string) KubePVCLabelsMetric {
+	return KubePVCLabelsMetric{
+		fqName:      fqname,
+		help:        "kube_persistentvolumeclaim_labels all labels for each pvc prefixed with label_",
+		pvc:         pvc,
+		namespace:   namespace,
+		labelNames:  labelNames,
+		labelValues: labelValues,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (kpvcl KubePVCLabelsMetric) Desc() *prometheus.Desc {
+	return cachedDesc(kpvcl.fqName, kpvcl.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (kpvcl KubePVCLabelsMetric) Write(m *dto.Metric) error {
+	h := float64(1)
+	m.Gauge = &dto.Gauge{
+		Value: &h,
+	}
+
+	var labels []*dto.LabelPair
+	for i := range kpvcl.labelNames {
+		labels = append(labels, &dto.LabelPair{
+			Name:  &kpvcl.labelNames[i],
+			Value: &kpvcl.labelValues[i],
+		})
+	}
+
+	labels = append(labels,
+		&dto.LabelPair{
+			Name:  toStringPtr("persistentvolumeclaim"),
+			Value: &kpvcl.pvc,
+		},
+		&dto.LabelPair{
+			Name:  toStringPtr("namespace"),
+			Value: &kpvcl.namespace,
+		},
+	)
+	m.Label = labels
+	return nil
+}
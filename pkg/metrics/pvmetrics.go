@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"github.com/kubecost/cost-model/pkg/clustercache"
+	"github.com/kubecost/cost-model/pkg/prom"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	v1 "k8s.io/api/core/v1"
@@ -19,8 +20,9 @@ type KubePVCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (kpvcb KubePVCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("kube_persistentvolume_capacity_bytes", "The pv storage capacity in bytes", []string{}, nil)
-	ch <- prometheus.NewDesc("kube_persistentvolume_status_phase", "The phase indicates if a volume is available, bound to a claim, or released by a claim.", []string{}, nil)
+	ch <- cachedDesc("kube_persistentvolume_capacity_bytes", "The pv storage capacity in bytes")
+	ch <- cachedDesc("kube_persistentvolume_status_phase", "The phase indicates if a volume is available, bound to a claim, or released by a claim.")
+	ch <- cachedDesc("kube_persistentvolume_labels", "All labels for each pv prefixed with label_")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -49,6 +51,9 @@ func (kpvcb KubePVCollector) Collect(ch chan<- prometheus.Metric) {
 		m := newKubePVCapacityBytesMetric("kube_persistentvolume_capacity_bytes", pv.Name, float64(storage.Value()))
 
 		ch <- m
+
+		labelNames, labelValues := prom.KubePrependQualifierToLabels(pv.GetLabels(), "label_")
+		ch <- newKubePVLabelsMetric("kube_persistentvolume_labels", pv.Name, labelNames, labelValues)
 	}
 }
 
@@ -77,10 +82,7 @@ func newKubePVCapacityBytesMetric(fqname, pv string, value float64) KubePVCapaci
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubePVCapacityBytesMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"persistentvolume": kpcrr.pv,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -126,11 +128,7 @@ func newKubePVStatusPhaseMetric(fqname, pv, phase string, value float64) KubePVS
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (kpcrr KubePVStatusPhaseMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"persistentvolume": kpcrr.pv,
-		"phase":            kpcrr.phase,
-	}
-	return prometheus.NewDesc(kpcrr.fqName, kpcrr.help, []string{}, l)
+	return cachedDesc(kpcrr.fqName, kpcrr.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -152,3 +150,59 @@ func (kpcrr KubePVStatusPhaseMetric) Write(m *dto.Metric) error {
 	}
 	return nil
 }
+
+//--------------------------------------------------------------------------
+//  KubePVLabelsMetric
+//--------------------------------------------------------------------------
+
+// KubePVLabelsMetric is a prometheus.Metric used to encode persistent volume labels
+type KubePVLabelsMetric struct {
+	fqName      string
+	help        string
+	labelNames  []string
+	labelValues []string
+	pv          string
+}
+
+// Creates a new KubePVLabelsMetric, implementation of prometheus.Metric
+func newKubePVLabelsMetric(fqname, pv string, labelNames []string, labelValues []string) KubePVLabelsMetric {
+	return KubePVLabelsMetric{
+		fqName:      fqname,
+		help:        "kube_persistentvolume_labels all labels for each pv prefixed with label_",
+		pv:          pv,
+		labelNames:  labelNames,
+		labelValues: labelValues,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (kpvl KubePVLabelsMetric) Desc() *prometheus.Desc {
+	return cachedDesc(kpvl.fqName, kpvl.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (kpvl KubePVLabelsMetric) Write(m *dto.Metric) error {
+	h := float64(1)
+	m.Gauge = &dto.Gauge{
+		Value: &h,
+	}
+
+	var labels []*dto.LabelPair
+	for i := range kpvl.labelNames {
+		labels = append(labels, &dto.LabelPair{
+			Name:  &kpvl.labelNames[i],
+			Value: &kpvl.labelValues[i],
+		})
+	}
+
+	labels = append(labels,
+		&dto.LabelPair{
+			Name:  toStringPtr("persistentvolume"),
+			Value: &kpvl.pv,
+		},
+	)
+	m.Label = labels
+	return nil
+}
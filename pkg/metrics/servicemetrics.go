@@ -20,7 +20,7 @@ type KubecostServiceCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (sc KubecostServiceCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("service_selector_labels", "service selector labels", []string{}, nil)
+	ch <- cachedDesc("service_selector_labels", "service selector labels")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -67,11 +67,7 @@ func newServiceSelectorLabelsMetric(name, namespace, fqname string, labelNames,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (s ServiceSelectorLabelsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"service":   s.serviceName,
-		"namespace": s.namespace,
-	}
-	return prometheus.NewDesc(s.fqName, s.help, s.labelNames, l)
+	return cachedDesc(s.fqName, s.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
@@ -99,3 +95,168 @@ func (s ServiceSelectorLabelsMetric) Write(m *dto.Metric) error {
 	m.Label = labels
 	return nil
 }
+
+//--------------------------------------------------------------------------
+//  KubeServiceLabelsCollector
+//--------------------------------------------------------------------------
+
+// KubeServiceLabelsCollector is a prometheus collector that emits service labels and info
+type KubeServiceLabelsCollector struct {
+	KubeClusterCache clustercache.ClusterCache
+}
+
+// Describe sends the super-set of all possible descriptors of metrics
+// collected by this Collector.
+func (kslc KubeServiceLabelsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cachedDesc("kube_service_labels", "All labels for each service prefixed with label_")
+	ch <- cachedDesc("kube_service_info", "Information about the service")
+}
+
+// Collect is called by the Prometheus registry when collecting metrics.
+func (kslc KubeServiceLabelsCollector) Collect(ch chan<- prometheus.Metric) {
+	svcs := kslc.KubeClusterCache.GetAllServices()
+	for _, svc := range svcs {
+		serviceName := svc.GetName()
+		serviceNS := svc.GetNamespace()
+
+		labelNames, labelValues := prom.KubePrependQualifierToLabels(svc.GetLabels(), "label_")
+		ch <- newKubeServiceLabelsMetric("kube_service_labels", serviceNS, serviceName, labelNames, labelValues)
+
+		var loadBalancerIP string
+		if len(svc.Status.LoadBalancer.Ingress) > 0 {
+			loadBalancerIP = svc.Status.LoadBalancer.Ingress[0].IP
+		}
+
+		ch <- newKubeServiceInfoMetric("kube_service_info", serviceNS, serviceName, string(svc.Spec.Type), svc.Spec.ClusterIP, svc.Spec.ExternalName, loadBalancerIP)
+	}
+}
+
+//--------------------------------------------------------------------------
+//  KubeServiceLabelsMetric
+//--------------------------------------------------------------------------
+
+// KubeServiceLabelsMetric is a prometheus.Metric used to encode service labels
+type KubeServiceLabelsMetric struct {
+	fqName      string
+	help        string
+	labelNames  []string
+	labelValues []string
+	serviceName string
+	namespace   string
+}
+
+// Creates a new KubeServiceLabelsMetric, implementation of prometheus.Metric
+func newKubeServiceLabelsMetric(fqname, namespace, name string, labelNames []string, labelValues []string) KubeServiceLabelsMetric {
+	return KubeServiceLabelsMetric{
+		fqName:      fqname,
+		help:        "kube_service_labels all labels for each service prefixed with label_",
+		labelNames:  labelNames,
+		labelValues: labelValues,
+		serviceName: name,
+		namespace:   namespace,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (s KubeServiceLabelsMetric) Desc() *prometheus.Desc {
+	return cachedDesc(s.fqName, s.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (s KubeServiceLabelsMetric) Write(m *dto.Metric) error {
+	h := float64(1)
+	m.Gauge = &dto.Gauge{
+		Value: &h,
+	}
+	var labels []*dto.LabelPair
+	for i := range s.labelNames {
+		labels = append(labels, &dto.LabelPair{
+			Name:  &s.labelNames[i],
+			Value: &s.labelValues[i],
+		})
+	}
+	labels = append(labels, &dto.LabelPair{
+		Name:  toStringPtr("namespace"),
+		Value: &s.namespace,
+	})
+	labels = append(labels, &dto.LabelPair{
+		Name:  toStringPtr("service"),
+		Value: &s.serviceName,
+	})
+	m.Label = labels
+	return nil
+}
+
+//--------------------------------------------------------------------------
+//  KubeServiceInfoMetric
+//--------------------------------------------------------------------------
+
+// KubeServiceInfoMetric is a prometheus.Metric used to encode service information
+type KubeServiceInfoMetric struct {
+	fqName         string
+	help           string
+	serviceName    string
+	namespace      string
+	serviceType    string
+	clusterIP      string
+	externalName   string
+	loadBalancerIP string
+}
+
+// Creates a new KubeServiceInfoMetric, implementation of prometheus.Metric
+func newKubeServiceInfoMetric(fqname, namespace, name, serviceType, clusterIP, externalName, loadBalancerIP string) KubeServiceInfoMetric {
+	return KubeServiceInfoMetric{
+		fqName:         fqname,
+		help:           "kube_service_info information about the service",
+		serviceName:    name,
+		namespace:      namespace,
+		serviceType:    serviceType,
+		clusterIP:      clusterIP,
+		externalName:   externalName,
+		loadBalancerIP: loadBalancerIP,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (s KubeServiceInfoMetric) Desc() *prometheus.Desc {
+	return cachedDesc(s.fqName, s.help)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (s KubeServiceInfoMetric) Write(m *dto.Metric) error {
+	h := float64(1)
+	m.Gauge = &dto.Gauge{
+		Value: &h,
+	}
+	m.Label = []*dto.LabelPair{
+		{
+			Name:  toStringPtr("namespace"),
+			Value: &s.namespace,
+		},
+		{
+			Name:  toStringPtr("service"),
+			Value: &s.serviceName,
+		},
+		{
+			Name:  toStringPtr("type"),
+			Value: &s.serviceType,
+		},
+		{
+			Name:  toStringPtr("cluster_ip"),
+			Value: &s.clusterIP,
+		},
+		{
+			Name:  toStringPtr("external_name"),
+			Value: &s.externalName,
+		},
+		{
+			Name:  toStringPtr("load_balancer_ip"),
+			Value: &s.loadBalancerIP,
+		},
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKubeServiceLabelsCollectorGather(t *testing.T) {
+	cache := &testClusterCache{
+		services: []*v1.Service{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "web",
+					Labels:    map[string]string{"app.kubernetes.io/name": "web", "team": "frontend"},
+				},
+				Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer, ClusterIP: "10.0.0.10"},
+				Status: v1.ServiceStatus{
+					LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "203.0.113.10"}, {IP: "203.0.113.11"}}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kubecost", Name: "external"},
+				Spec:       v1.ServiceSpec{Type: v1.ServiceTypeExternalName, ExternalName: "db.example.com"},
+			},
+		},
+	}
+
+	expected := `
+# HELP kube_service_info kube_service_info information about the service
+# TYPE kube_service_info gauge
+kube_service_info{cluster_ip="",external_name="db.example.com",load_balancer_ip="",namespace="kubecost",service="external",type="ExternalName"} 1
+kube_service_info{cluster_ip="10.0.0.10",external_name="",load_balancer_ip="203.0.113.10",namespace="default",service="web",type="LoadBalancer"} 1
+# HELP kube_service_labels kube_service_labels all labels for each service prefixed with label_
+# TYPE kube_service_labels gauge
+kube_service_labels{label_app_kubernetes_io_name="web",label_team="frontend",namespace="default",service="web"} 1
+kube_service_labels{namespace="kubecost",service="external"} 1
+`
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(KubeServiceLabelsCollector{KubeClusterCache: cache})
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
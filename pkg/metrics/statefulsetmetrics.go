@@ -20,7 +20,7 @@ type KubecostStatefulsetCollector struct {
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector.
 func (sc KubecostStatefulsetCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- prometheus.NewDesc("statefulSet_match_labels", "statfulSet match labels", []string{}, nil)
+	ch <- cachedDesc("statefulSet_match_labels", "statfulSet match labels")
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -67,11 +67,7 @@ func newStatefulsetMatchLabelsMetric(name, namespace, fqname string, labelNames,
 // Desc returns the descriptor for the Metric. This method idempotently
 // returns the same descriptor throughout the lifetime of the Metric.
 func (s StatefulsetMatchLabelsMetric) Desc() *prometheus.Desc {
-	l := prometheus.Labels{
-		"statefulSet": s.statefulsetName,
-		"namespace":   s.namespace,
-	}
-	return prometheus.NewDesc(s.fqName, s.help, s.labelNames, l)
+	return cachedDesc(s.fqName, s.help)
 }
 
 // Write encodes the Metric into a "Metric" Protocol Buffer data
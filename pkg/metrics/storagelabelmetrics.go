@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/kubecost/cost-model/pkg/clustercache"
+	"github.com/kubecost/cost-model/pkg/prom"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//--------------------------------------------------------------------------
+//  KubePersistentVolumeClaimLabelsCollector
+//--------------------------------------------------------------------------
+
+// KubePersistentVolumeClaimLabelsCollector is a prometheus collector that emits pvc labels and
+// info only, which is used to attribute storage costs by label
+type KubePersistentVolumeClaimLabelsCollector struct {
+	KubeClusterCache clustercache.ClusterCache
+}
+
+// Describe sends the super-set of all possible descriptors of pvc labels only
+// collected by this Collector.
+func (kpvclc KubePersistentVolumeClaimLabelsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cachedDesc("kube_persistentvolumeclaim_labels", "All labels for each pvc prefixed with label_")
+	ch <- cachedDesc("kube_persistentvolumeclaim_info", "The pvc storage resource requests in bytes")
+}
+
+// Collect is called by the Prometheus registry when collecting metrics.
+func (kpvclc KubePersistentVolumeClaimLabelsCollector) Collect(ch chan<- prometheus.Metric) {
+	pvcs := kpvclc.KubeClusterCache.GetAllPersistentVolumeClaims()
+	for _, pvc := range pvcs {
+		storageClass := getPersistentVolumeClaimClass(pvc)
+		volume := pvc.Spec.VolumeName
+
+		ch <- newKubePVCInfoMetric("kube_persistentvolumeclaim_info", pvc.Name, pvc.Namespace, storageClass, volume)
+
+		labelNames, labelValues := prom.KubePrependQualifierToLabels(pvc.GetLabels(), "label_")
+		ch <- newKubePVCLabelsMetric("kube_persistentvolumeclaim_labels", pvc.Namespace, pvc.Name, labelNames, labelValues)
+	}
+}
+
+//--------------------------------------------------------------------------
+//  KubePersistentVolumeLabelsCollector
+//--------------------------------------------------------------------------
+
+// KubePersistentVolumeLabelsCollector is a prometheus collector that emits pv labels only
+type KubePersistentVolumeLabelsCollector struct {
+	KubeClusterCache clustercache.ClusterCache
+}
+
+// Describe sends the super-set of all possible descriptors of pv labels only
+// collected by this Collector.
+func (kpvlc KubePersistentVolumeLabelsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cachedDesc("kube_persistentvolume_labels", "All labels for each pv prefixed with label_")
+}
+
+// Collect is called by the Prometheus registry when collecting metrics.
+func (kpvlc KubePersistentVolumeLabelsCollector) Collect(ch chan<- prometheus.Metric) {
+	pvs := kpvlc.KubeClusterCache.GetAllPersistentVolumes()
+	for _, pv := range pvs {
+		labelNames, labelValues := prom.KubePrependQualifierToLabels(pv.GetLabels(), "label_")
+		ch <- newKubePVLabelsMetric("kube_persistentvolume_labels", pv.Name, labelNames, labelValues)
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKubePersistentVolumeClaimLabelsCollectorGather(t *testing.T) {
+	ssd := "ssd"
+	cache := &testClusterCache{
+		pvcs: []*v1.PersistentVolumeClaim{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "data",
+					Labels:    map[string]string{"app.kubernetes.io/name": "db", "team": "storage"},
+				},
+				Spec: v1.PersistentVolumeClaimSpec{StorageClassName: &ssd, VolumeName: "pv-data"},
+			},
+			{
+				// the beta annotation takes precedence over the storage class name, and labels which
+				// sanitize to the same name collapse into one, taking the value of the last in order
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "kubecost",
+					Name:        "colliding",
+					Labels:      map[string]string{"app.kubernetes.io/name": "first", "app_kubernetes_io_name": "last"},
+					Annotations: map[string]string{v1.BetaStorageClassAnnotation: "standard"},
+				},
+				Spec: v1.PersistentVolumeClaimSpec{StorageClassName: &ssd},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unlabeled"},
+			},
+		},
+	}
+
+	expected := `
+# HELP kube_persistentvolumeclaim_info kube_persistentvolumeclaim_info pvc storage resource requests in bytes
+# TYPE kube_persistentvolumeclaim_info gauge
+kube_persistentvolumeclaim_info{namespace="default",persistentvolumeclaim="data",storageclass="ssd",volumename="pv-data"} 1
+kube_persistentvolumeclaim_info{namespace="default",persistentvolumeclaim="unlabeled",storageclass="<none>",volumename=""} 1
+kube_persistentvolumeclaim_info{namespace="kubecost",persistentvolumeclaim="colliding",storageclass="standard",volumename=""} 1
+# HELP kube_persistentvolumeclaim_labels kube_persistentvolumeclaim_labels all labels for each pvc prefixed with label_
+# TYPE kube_persistentvolumeclaim_labels gauge
+kube_persistentvolumeclaim_labels{label_app_kubernetes_io_name="db",label_team="storage",namespace="default",persistentvolumeclaim="data"} 1
+kube_persistentvolumeclaim_labels{label_app_kubernetes_io_name="last",namespace="kubecost",persistentvolumeclaim="colliding"} 1
+kube_persistentvolumeclaim_labels{namespace="default",persistentvolumeclaim="unlabeled"} 1
+`
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(KubePersistentVolumeClaimLabelsCollector{KubeClusterCache: cache})
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
+
+func TestKubePersistentVolumeLabelsCollectorGather(t *testing.T) {
+	cache := &testClusterCache{
+		pvs: []*v1.PersistentVolume{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "pv-data",
+					Labels: map[string]string{"topology.kubernetes.io/zone": "us-east-1a", "team": "storage"},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-unlabeled"},
+			},
+		},
+	}
+
+	expected := `
+# HELP kube_persistentvolume_labels kube_persistentvolume_labels all labels for each pv prefixed with label_
+# TYPE kube_persistentvolume_labels gauge
+kube_persistentvolume_labels{label_team="storage",label_topology_kubernetes_io_zone="us-east-1a",persistentvolume="pv-data"} 1
+kube_persistentvolume_labels{persistentvolume="pv-unlabeled"} 1
+`
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(KubePersistentVolumeLabelsCollector{KubeClusterCache: cache})
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Fatalf("Unexpected metrics: %s", err)
+	}
+}
@@ -0,0 +1,83 @@
+package prom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kubecost/cost-model/pkg/log"
+)
+
+// DefaultBatchLabel is the discriminator label used to identify the input query of each series
+// in a batched query.
+const DefaultBatchLabel = "kubecost_batch_index"
+
+// BatchQuery combines multiple instant vector queries into a single query using `or`. Each input
+// query is wrapped with label_replace to add the discriminator label, set to the index of the
+// query, so the results can be demultiplexed using SplitBatchResults. Because every series carries
+// a distinct discriminator, `or` never drops series with otherwise identical labels.
+//
+// Batching trades N round trips for a single, larger request:
+//   - The combined query is only as fast as its slowest input, and a failure in any input fails
+//     the whole batch.
+//   - Server limits (ie: query.max-samples) apply to the sum of all inputs, so batching several
+//     high cardinality queries may exceed limits each query would individually satisfy.
+//   - `or` matches series by their full label set, which costs an additional hash of every
+//     returned series.
+//
+// Batching is best suited to many cheap, low cardinality queries. Inputs must return instant
+// vectors; scalar queries should be wrapped with vector().
+func BatchQuery(label string, queries ...string) string {
+	parts := make([]string, 0, len(queries))
+	for i, query := range queries {
+		parts = append(parts, fmt.Sprintf(`label_replace(%s, "%s", "%d", "", "")`, query, label, i))
+	}
+
+	return strings.Join(parts, " or ")
+}
+
+// SplitBatchResults demultiplexes the results of a query built with BatchQuery into one
+// QueryResults per input query, in input order. The discriminator label is removed from each
+// series. If the batched query failed, the error is set on every returned QueryResults.
+func SplitBatchResults(label string, queries []string, batched *QueryResults) []*QueryResults {
+	split := make([]*QueryResults, len(queries))
+	for i, query := range queries {
+		split[i] = &QueryResults{
+			Query:       query,
+			ContextName: batched.ContextName,
+		}
+	}
+
+	if batched.Error != nil {
+		for _, qrs := range split {
+			qrs.Error = batched.Error
+		}
+		return split
+	}
+
+	for _, result := range batched.Results {
+		value, _ := result.GetLabel(label)
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(split) {
+			log.DedupedWarningf(5, "Dropping series with invalid batch label '%s=%s' for query: %s", label, value, batched.Query)
+			continue
+		}
+
+		delete(result.Metric, label)
+		split[index].Results = append(split[index].Results, result)
+	}
+
+	return split
+}
+
+// QueryBatch executes the queries as a single batched request, returning one QueryResults per
+// input query in input order. See BatchQuery for the trade-offs of batching.
+func (ctx *Context) QueryBatch(queries ...string) []*QueryResults {
+	if len(queries) == 0 {
+		return nil
+	}
+
+	resCh := ctx.Query(BatchQuery(DefaultBatchLabel, queries...))
+
+	return SplitBatchResults(DefaultBatchLabel, queries, resCh.read())
+}
@@ -0,0 +1,60 @@
+package prom
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestBatchQuery(t *testing.T) {
+	expected := `label_replace(up, "kubecost_batch_index", "0", "", "") or label_replace(sum(kube_pod_labels) by (namespace), "kubecost_batch_index", "1", "", "")`
+
+	actual := BatchQuery(DefaultBatchLabel, "up", "sum(kube_pod_labels) by (namespace)")
+	if actual != expected {
+		t.Fatalf("BatchQuery: exp (%s); act (%s)", expected, actual)
+	}
+}
+
+func TestQueryBatch(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"job":"kubecost","kubecost_batch_index":"0"},"value":[1622505600,"1"]},` +
+		`{"metric":{"namespace":"default","kubecost_batch_index":"1"},"value":[1622505600,"4"]},` +
+		`{"metric":{"namespace":"kubecost","kubecost_batch_index":"1"},"value":[1622505600,"9"]},` +
+		`{"metric":{"namespace":"dropped"},"value":[1622505600,"9"]}]}}`)})
+
+	queries := []string{"up", "sum(kube_pod_labels) by (namespace)", "absent(foo)"}
+	results := NewContext(client).QueryBatch(queries...)
+
+	if len(client.Requests()) != 1 {
+		t.Fatalf("Expected a single request, got %d", len(client.Requests()))
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	for i, expected := range []int{1, 2, 0} {
+		if results[i].Query != queries[i] {
+			t.Fatalf("Query: exp (%s); act (%s)", queries[i], results[i].Query)
+		}
+		if results[i].Len() != expected {
+			t.Fatalf("Results for '%s': exp (%d); act (%d)", queries[i], expected, results[i].Len())
+		}
+		for _, result := range results[i].Results {
+			if _, ok := result.GetLabel(DefaultBatchLabel); ok {
+				t.Fatalf("Expected batch label to be removed: %v", result.Metric)
+			}
+		}
+	}
+}
+
+func TestQueryBatchError(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: http.StatusBadRequest, Body: []byte(`bad request`)})
+
+	results := NewContext(client).QueryBatch("up", "down")
+	for _, qrs := range results {
+		if qrs.Error == nil {
+			t.Fatalf("Expected error for query '%s'", qrs.Query)
+		}
+	}
+}
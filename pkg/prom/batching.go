@@ -0,0 +1,102 @@
+package prom
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/errors"
+)
+
+// BatchingContext wraps a Context, coalescing the queries which arrive within a short window into a
+// single QueryAll, which amortizes the scheduling overhead of dashboards issuing many queries at
+// nearly the same time. Each query is still sent as its own request; see BatchQuery to combine
+// queries into a single request instead.
+type BatchingContext struct {
+	ctx      *Context
+	window   time.Duration
+	maxBatch int
+
+	lock    sync.Mutex
+	pending []*pendingQuery
+	timer   *time.Timer
+}
+
+// pendingQuery is a query waiting for its batch to be issued, and the channel of its caller
+type pendingQuery struct {
+	query string
+	resCh QueryResultsChan
+}
+
+// NewBatchingContext creates a BatchingContext which issues the queries buffered using ctx once the
+// window has elapsed since the first query of the batch arrived, or once maxBatch queries are
+// buffered, whichever is first. A maxBatch <= 0 does not limit the size of a batch, and a window
+// <= 0 issues each query as it arrives.
+func NewBatchingContext(ctx *Context, window time.Duration, maxBatch int) *BatchingContext {
+	return &BatchingContext{
+		ctx:      ctx,
+		window:   window,
+		maxBatch: maxBatch,
+	}
+}
+
+// Query buffers the query to be issued with its batch, and returns a QueryResultsChan which
+// receives its results. The channel is buffered, so results are routed to each caller without
+// waiting for the callers of earlier queries in the batch to read theirs.
+func (bc *BatchingContext) Query(query string) QueryResultsChan {
+	resCh := make(QueryResultsChan, 1)
+
+	bc.lock.Lock()
+	bc.pending = append(bc.pending, &pendingQuery{query: query, resCh: resCh})
+
+	full := bc.window <= 0 || (bc.maxBatch > 0 && len(bc.pending) >= bc.maxBatch)
+	if !full && bc.timer == nil {
+		bc.timer = time.AfterFunc(bc.window, bc.Flush)
+	}
+	bc.lock.Unlock()
+
+	if full {
+		bc.Flush()
+	}
+
+	return resCh
+}
+
+// Flush issues the buffered queries immediately, without waiting for the window to elapse
+func (bc *BatchingContext) Flush() {
+	bc.lock.Lock()
+	batch := bc.pending
+	bc.pending = nil
+	if bc.timer != nil {
+		bc.timer.Stop()
+		bc.timer = nil
+	}
+	bc.lock.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	queries := make([]string, len(batch))
+	for i, pq := range batch {
+		queries[i] = pq.query
+	}
+
+	// each channel is read in its own goroutine, so a slow query does not hold up the delivery of
+	// the results of the other queries in the batch
+	resChs := bc.ctx.QueryAll(queries...)
+	go func() {
+		defer errors.HandlePanic()
+
+		var wg sync.WaitGroup
+		for i, resCh := range resChs {
+			wg.Add(1)
+			go func(pq *pendingQuery, resCh QueryResultsChan) {
+				defer wg.Done()
+				defer errors.HandlePanic()
+
+				bc.ctx.sendResults(pq.resCh, resCh.read())
+			}(batch[i], resCh)
+		}
+		wg.Wait()
+	}()
+}
@@ -0,0 +1,110 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+// queryEchoHandler responds to each query with a single series labeled with the query
+func queryEchoHandler(req *http.Request) []byte {
+	query := promtest.RequestParams(req).Get("query")
+	return []byte(fmt.Sprintf(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"query":"%s"},"value":[1622505600,"1"]}]}}`, query))
+}
+
+func TestBatchingContextRouting(t *testing.T) {
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(queryEchoHandler))
+	bc := NewBatchingContext(NewContext(client), 20*time.Millisecond, 0)
+
+	const count = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			query := fmt.Sprintf("query_%d", i)
+			results, err := bc.Query(query).Await()
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", query, err)
+				return
+			}
+			if len(results) != 1 || results[0].GetLabelOr("query", "") != query {
+				t.Errorf("%s: routed unexpected results: %+v", query, results)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(client.Requests()) != count {
+		t.Fatalf("requests: exp (%d); act (%d)", count, len(client.Requests()))
+	}
+}
+
+func TestBatchingContextMaxBatch(t *testing.T) {
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(queryEchoHandler))
+
+	// the window is long enough that only reaching the max batch size issues the queries
+	bc := NewBatchingContext(NewContext(client), time.Hour, 2)
+
+	first := bc.Query("first")
+	second := bc.Query("second")
+
+	for query, resCh := range map[string]QueryResultsChan{"first": first, "second": second} {
+		select {
+		case results := <-resCh:
+			if results.Results[0].GetLabelOr("query", "") != query {
+				t.Fatalf("%s: routed unexpected results: %+v", query, results.Results)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: timed out waiting for a full batch", query)
+		}
+	}
+
+	// a partial batch is issued by Flush
+	third := bc.Query("third")
+	bc.Flush()
+
+	select {
+	case results := <-third:
+		if results.Results[0].GetLabelOr("query", "") != "third" {
+			t.Fatalf("third: routed unexpected results: %+v", results.Results)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("third: timed out waiting for flush")
+	}
+}
+
+func TestBatchingContextSlowQuery(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	client := promtest.NewClient().SetHandler(func(ctx context.Context, req *http.Request) *promtest.Response {
+		if promtest.RequestParams(req).Get("query") == "slow" {
+			<-release
+		}
+		return &promtest.Response{Body: queryEchoHandler(req)}
+	})
+	bc := NewBatchingContext(NewContext(client), time.Hour, 2)
+
+	// the slow query is first in the batch, but does not hold up the results of the fast query
+	slow := bc.Query("slow")
+	fast := bc.Query("fast")
+
+	select {
+	case results := <-fast:
+		if results.Results[0].GetLabelOr("query", "") != "fast" {
+			t.Fatalf("fast: routed unexpected results: %+v", results.Results)
+		}
+	case <-slow:
+		t.Fatalf("slow: unexpected results before release")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fast: timed out waiting behind the slow query")
+	}
+}
@@ -0,0 +1,216 @@
+package prom
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// WithQueryDedup enables or disables the deduplication of concurrent identical instant queries.
+// When enabled, queries with the same normalized query and evaluation time which are in-flight at
+// the same time share a single request, and each caller receives its own copy of the results,
+// warnings, and error. The shared request is not bound to the request context of any one caller:
+// a caller which is cancelled or times out stops waiting and returns its own error, while the
+// request continues for the remaining callers, and is only cancelled once every caller has stopped
+// waiting or the Context is closed. Copies of the Context made with WithName deduplicate their
+// queries separately, as their options may differ. Disabled by default. Returns the Context to
+// allow chaining.
+func (ctx *Context) WithQueryDedup(enabled bool) *Context {
+	if enabled {
+		ctx.queryDedup = newDedupGroup()
+	} else {
+		ctx.queryDedup = nil
+	}
+	return ctx
+}
+
+// dedupResult is the shared result of a deduplicated query
+type dedupResult struct {
+	results  *QueryResults
+	warnings prometheus.Warnings
+}
+
+// dedupCall is an in-flight deduplicated request
+type dedupCall struct {
+	// done is closed once result and err are set
+	done   chan struct{}
+	result *dedupResult
+	err    error
+
+	// waiters is the number of callers waiting on the request, guarded by the dedupGroup lock.
+	// The request is cancelled once it drops to 0.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// dedupGroup tracks the in-flight deduplicated requests of a Context by key
+type dedupGroup struct {
+	lock  sync.Mutex
+	calls map[string]*dedupCall
+}
+
+// newDedupGroup creates a new dedupGroup with no in-flight requests
+func newDedupGroup() *dedupGroup {
+	return &dedupGroup{
+		calls: make(map[string]*dedupCall),
+	}
+}
+
+// join returns the in-flight call for the key, registering the caller as a waiter. If there is no
+// call in-flight, a new call is started in its own goroutine, which runs f with a context carrying
+// the values of reqCtx which is cancelled once the lifetime is done or the call has no waiters.
+func (g *dedupGroup) join(lifetime, reqCtx context.Context, key string, f func(context.Context) (*dedupResult, error)) *dedupCall {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		return call
+	}
+
+	callCtx, cancel := context.WithCancel(detachedContext{Context: lifetime, values: reqCtx})
+	call := &dedupCall{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+	}
+	g.calls[key] = call
+
+	go func() {
+		defer cancel()
+
+		call.result, call.err = f(callCtx)
+
+		g.lock.Lock()
+		if g.calls[key] == call {
+			delete(g.calls, key)
+		}
+		g.lock.Unlock()
+
+		close(call.done)
+	}()
+
+	return call
+}
+
+// leave unregisters a caller which stopped waiting on the call, cancelling the call if it was the
+// last waiter. A cancelled call is removed, so later callers start a new request.
+func (g *dedupGroup) leave(key string, call *dedupCall) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	call.cancel()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// detachedContext carries the values of a request context, ie: its request id and trace span,
+// while its deadline and cancellation are those of the embedded Context
+type detachedContext struct {
+	context.Context
+	values context.Context
+}
+
+// Value returns the value of the request context for the key
+func (dc detachedContext) Value(key interface{}) interface{} {
+	return dc.values.Value(key)
+}
+
+// dedupJoined, if set, is called with the key of a deduplicated query once the query has started,
+// or joined, the request for the key. It's nil outside of tests, which use it to wait for concurrent
+// queries to share a request.
+var dedupJoined func(key string)
+
+// dedupQuery executes the instant query, sharing the request with any concurrent identical query.
+// The caller stops waiting once reqCtx is done, returning reqCtx.Err(), without cancelling the
+// request for the other callers.
+func (ctx *Context) dedupQuery(reqCtx context.Context, query string, evalTime string) (*QueryResults, prometheus.Warnings, error) {
+	key := normalizeQuery(query) + "@" + evalTime
+
+	call := ctx.queryDedup.join(ctx.baseContext(), reqCtx, key, func(callCtx context.Context) (*dedupResult, error) {
+		body, contentType, err := ctx.rawQuery(callCtx, query, evalTime, ctx.acceptHeader())
+		if err != nil {
+			return nil, err
+		}
+
+		results, warnings, err := ctx.decodeQueryBody(callCtx, query, contentType, body)
+		return &dedupResult{results: results, warnings: warnings}, err
+	})
+	if dedupJoined != nil {
+		dedupJoined(key)
+	}
+
+	select {
+	case <-call.done:
+	case <-reqCtx.Done():
+		ctx.queryDedup.leave(key, call)
+		return nil, nil, reqCtx.Err()
+	}
+
+	dr, err := call.result, call.err
+	if dr == nil {
+		return nil, nil, err
+	}
+
+	// each caller receives a copy, as callers may modify their results, ie: SplitBatchResults
+	var results *QueryResults
+	if dr.results != nil {
+		results = dr.results.clone()
+		results.Query = query
+	}
+
+	var warnings prometheus.Warnings
+	if dr.warnings != nil {
+		warnings = append(prometheus.Warnings{}, dr.warnings...)
+	}
+
+	return results, warnings, err
+}
+
+// normalizeQuery collapses whitespace outside of string literals, so queries which differ only in
+// formatting share a deduplication key.
+func normalizeQuery(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	var quote byte
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if quote != 0 {
+			sb.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(query) {
+				i++
+				sb.WriteByte(query[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			space = true
+			continue
+		case '"', '\'', '`':
+			quote = c
+		}
+
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteByte(c)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,227 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+func TestQueryDedup(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	body := []byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"namespace":"kubecost"},"value":[1622505600,"1"]}]},"warnings":["partial data"]}`)
+	client := promtest.NewClient().SetHandler(gatedHandler(started, release, body))
+	ctx := NewContext(client).WithQueryDedup(true)
+
+	// the evaluation time is part of the key, so pin the clock to ensure all queries resolve the
+	// same time
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ctx.clock = func() time.Time { return now }
+
+	queries := []string{`sum(up) by (namespace)`, `sum(up)  by (namespace)`, "sum(up)\nby (namespace)"}
+
+	joined := make(chan string, len(queries))
+	dedupJoined = func(key string) { joined <- key }
+	defer func() { dedupJoined = nil }()
+
+	var wg sync.WaitGroup
+	results := make([]*QueryResults, len(queries))
+	warnings := make([]prometheus.Warnings, len(queries))
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			results[i], warnings[i], _ = ctx.query(context.Background(), query)
+		}(i, query)
+
+		// wait for the first query to be in-flight before launching the others
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// wait for every query to join the in-flight request before releasing it
+	key := <-joined
+	for i := 1; i < len(queries); i++ {
+		if k := <-joined; k != key {
+			t.Fatalf("Key: exp (%s); act (%s)", key, k)
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if requests := len(client.Requests()); requests != 1 {
+		t.Fatalf("Requests: exp (1); act (%d)", requests)
+	}
+
+	for i, query := range queries {
+		if results[i] == nil || results[i].Len() != 1 || len(warnings[i]) != 1 {
+			t.Fatalf("Query %d: expected 1 result and 1 warning; act (%+v, %v)", i, results[i], warnings[i])
+		}
+		if results[i].Query != query {
+			t.Fatalf("Query %d: exp (%s); act (%s)", i, query, results[i].Query)
+		}
+	}
+
+	// each caller's results may be modified independently
+	delete(results[0].Results[0].Metric, "namespace")
+	if _, ok := results[1].Results[0].GetLabel("namespace"); !ok {
+		t.Fatalf("Expected results to be copied for each caller")
+	}
+}
+
+func TestQueryDedupLeaderCancelled(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	client := promtest.NewClient().SetHandler(gatedHandler(started, release, promtest.VectorBody([]promtest.Series{
+		{Metric: map[string]string{"pod": "a"}, Points: []promtest.Point{{Value: 1}}},
+	})))
+	ctx := NewContext(client).WithQueryDedup(true)
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ctx.clock = func() time.Time { return now }
+
+	joined := make(chan string, 2)
+	dedupJoined = func(key string) { joined <- key }
+	defer func() { dedupJoined = nil }()
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := ctx.query(leaderCtx, "up")
+		leaderErr <- err
+	}()
+	<-started
+	<-joined
+
+	type followerResult struct {
+		results *QueryResults
+		err     error
+	}
+	followerCh := make(chan followerResult, 1)
+	go func() {
+		results, _, err := ctx.query(context.Background(), "up")
+		followerCh <- followerResult{results: results, err: err}
+	}()
+	<-joined
+
+	// the leader stops waiting, but the request continues for the follower
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected leader to be cancelled, got: %v", err)
+	}
+
+	close(release)
+	follower := <-followerCh
+	if follower.err != nil {
+		t.Fatalf("Unexpected follower error: %s", follower.err)
+	}
+	if follower.results.Len() != 1 {
+		t.Fatalf("Results: exp (1); act (%d)", follower.results.Len())
+	}
+	if requests := len(client.Requests()); requests != 1 {
+		t.Fatalf("Requests: exp (1); act (%d)", requests)
+	}
+}
+
+func TestQueryDedupAllCancelled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{})
+	client := promtest.NewClient().SetHandler(func(reqCtx context.Context, req *http.Request) *promtest.Response {
+		started <- struct{}{}
+		<-reqCtx.Done()
+		close(cancelled)
+		return &promtest.Response{Err: reqCtx.Err()}
+	})
+	ctx := NewContext(client).WithQueryDedup(true)
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx.query(reqCtx, "up")
+	}()
+	<-started
+
+	// the request is cancelled once its only caller stops waiting
+	cancel()
+	<-done
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the shared request to be cancelled")
+	}
+}
+
+func TestQueryDedupWithName(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	client := promtest.NewClient().SetHandler(gatedHandler(started, release, promtest.VectorBody([]promtest.Series{
+		{Metric: map[string]string{"pod": "a"}, Points: []promtest.Point{{Value: 1}}},
+	})))
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ctx := NewContext(client).WithQueryDedup(true)
+	ctx.clock = func() time.Time { return now }
+	clone := ctx.WithName("clone").WithDefaultResultLabels(map[string]string{"cluster_id": "cluster-two"})
+
+	// the copies do not share requests, so each decodes its results with its own options
+	var wg sync.WaitGroup
+	results := make([]*QueryResults, 2)
+	for i, c := range []*Context{ctx, clone} {
+		wg.Add(1)
+		go func(i int, c *Context) {
+			defer wg.Done()
+			results[i], _, _ = c.query(context.Background(), "up")
+		}(i, c)
+	}
+	<-started
+	<-started
+	close(release)
+	wg.Wait()
+
+	if _, ok := results[0].Results[0].GetLabel("cluster_id"); ok {
+		t.Fatalf("Expected no cluster_id label on the results of the original")
+	}
+	if id, _ := results[1].Results[0].GetLabel("cluster_id"); id != "cluster-two" {
+		t.Fatalf("cluster_id: exp (cluster-two); act (%s)", id)
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	testCases := map[string]struct {
+		query    string
+		expected string
+	}{
+		"collapses whitespace": {
+			query:    "  sum(up)\n\tby   (namespace) ",
+			expected: "sum(up) by (namespace)",
+		},
+		"preserves string literals": {
+			query:    `up{job="kube  cost", path='a  b'}`,
+			expected: `up{job="kube  cost", path='a  b'}`,
+		},
+		"escaped quotes": {
+			query:    `up{job="say \"hi  there\""}   or   vector(0)`,
+			expected: `up{job="say \"hi  there\""} or vector(0)`,
+		},
+		"raw strings": {
+			query:    "up{job=~`a\\  b`}",
+			expected: "up{job=~`a\\  b`}",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := normalizeQuery(test.query); actual != test.expected {
+				t.Fatalf("exp (%s); act (%s)", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package prom
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding header value set on outbound query requests. Note that
+// setting this header explicitly disables the transparent decompression of the http.Transport,
+// so responses must be decoded using decodeResponseBody.
+const acceptEncoding = "gzip, deflate"
+
+// DefaultMaxResponseBytes is the default maximum size of a decoded response body. This is far larger
+// than any expected query response, but protects against exhausting memory decoding a pathological
+// response.
+const DefaultMaxResponseBytes int64 = 1 << 30
+
+// WithMaxResponseBytes sets the maximum size of a response body, after decompression, which the
+// Context will decode. Responses exceeding the limit fail with an error rather than being decoded.
+// Note that the prometheus client reads the full response from the connection before the limit can
+// be checked, so the limit bounds the memory used by decompression and decoding. A value <= 0
+// disables the limit. Returns the Context to allow chaining.
+func (ctx *Context) WithMaxResponseBytes(n int64) *Context {
+	ctx.maxResponseBytes = n
+	return ctx
+}
+
+// decodeResponseBody decompresses the response body using the Content-Encoding of the response.
+// Bodies without a Content-Encoding, or with an identity encoding, are returned unchanged. An error
+// is returned if the body, before or after decompression, exceeds maxBytes. A maxBytes <= 0
+// disables the limit.
+func decodeResponseBody(resp *http.Response, body []byte, maxBytes int64) ([]byte, error) {
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return nil, responseTooLargeErr(maxBytes)
+	}
+
+	if resp == nil || len(body) == 0 {
+		return body, nil
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+
+	var reader io.ReadCloser
+	var err error
+
+	switch encoding {
+	case "", "identity":
+		return body, nil
+
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+
+	case "deflate":
+		// deflate is specified as zlib wrapped, but some servers send raw deflate streams
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+
+	default:
+		return nil, fmt.Errorf("Unsupported Content-Encoding: %s", encoding)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode %s response body: %s", encoding, err)
+	}
+	defer reader.Close()
+
+	var r io.Reader = reader
+	if maxBytes > 0 {
+		// read one byte past the limit to detect bodies exceeding it
+		r = io.LimitReader(reader, maxBytes+1)
+	}
+
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode %s response body: %s", encoding, err)
+	}
+	if maxBytes > 0 && int64(len(decoded)) > maxBytes {
+		return nil, responseTooLargeErr(maxBytes)
+	}
+
+	return decoded, nil
+}
+
+// responseTooLargeErr returns the error for a response body exceeding the maximum size
+func responseTooLargeErr(maxBytes int64) error {
+	return fmt.Errorf("Response body exceeds the maximum size of %d bytes", maxBytes)
+}
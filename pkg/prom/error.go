@@ -1,12 +1,16 @@
 package prom
 
 import (
+	"errors"
 	"fmt"
+	"mime"
+	"net/http"
 	"reflect"
 	"strings"
 	"sync"
 
 	"github.com/kubecost/cost-model/pkg/log"
+	"github.com/kubecost/cost-model/pkg/util/json"
 )
 
 // errorType used to check HasError
@@ -17,9 +21,33 @@ var errorType = reflect.TypeOf((*error)(nil)).Elem()
 // this warning and convert it to an error.
 const NoStoreAPIWarning string = "No StoreAPIs matched for this query"
 
-// IsNoStoreAPIWarning checks a warning to determine if it is equivalent to a no store API query.
+// noStoreAPIPhrases are the stable, lowercase phrasings of the no store API warning across Thanos
+// versions, which are matched anywhere within a warning with whitespace collapsed. These cover:
+//
+//	"No StoreAPIs matched for this query"
+//	"no StoreAPIs matched for this query, stores: ..."
+//	"proxy: no StoreAPI matched for this query"
+//	"No stores matched for this query"
+//	"no store matched for this query"
+var noStoreAPIPhrases = []string{
+	"no storeapis matched",
+	"no storeapi matched",
+	"no stores matched",
+	"no store matched",
+}
+
+// IsNoStoreAPIWarning checks a warning to determine if it is equivalent to a no store API query. The
+// match is case-insensitive and tolerant of the wording changes between Thanos versions, see
+// noStoreAPIPhrases.
 func IsNoStoreAPIWarning(warning string) bool {
-	return strings.EqualFold(warning, NoStoreAPIWarning)
+	normalized := strings.ToLower(strings.Join(strings.Fields(warning), " "))
+	for _, phrase := range noStoreAPIPhrases {
+		if strings.Contains(normalized, phrase) {
+			return true
+		}
+	}
+
+	return false
 }
 
 //--------------------------------------------------------------------------
@@ -151,6 +179,25 @@ func (ec *QueryErrorCollector) Errors() []*QueryError {
 	return errs
 }
 
+// FailedQueries returns the distinct queries of the errors caught by the collector, in the order
+// they first failed
+func (ec *QueryErrorCollector) FailedQueries() []string {
+	ec.m.RLock()
+	defer ec.m.RUnlock()
+
+	seen := make(map[string]struct{}, len(ec.errors))
+	queries := []string{}
+	for _, e := range ec.errors {
+		if _, ok := seen[e.Query]; ok {
+			continue
+		}
+
+		seen[e.Query] = struct{}{}
+		queries = append(queries, e.Query)
+	}
+	return queries
+}
+
 // Implement the error interface to allow returning as an aggregated error
 func (ec *QueryErrorCollector) Error() string {
 	ec.m.RLock()
@@ -250,8 +297,21 @@ func WrapError(err error, msg string) error {
 	}
 }
 
-// CommError describes an error communicating with Prometheus
+// CommError describes an error communicating with Prometheus. If the error resulted from
+// an unsuccessful response, the StatusCode and Body of the response are set.
 type CommError struct {
+	StatusCode int
+	Body       string
+	Query      string
+
+	// ErrorType and ErrorMessage are set when the response body contained a Prometheus
+	// error envelope, ie: {"status":"error","errorType":"bad_data","error":"..."}
+	ErrorType    string
+	ErrorMessage string
+
+	// RequestID is the id sent with the request, if request ids are enabled (see WithRequestID)
+	RequestID string
+
 	messages []string
 }
 
@@ -265,15 +325,41 @@ func CommErrorf(format string, args ...interface{}) CommError {
 	return NewCommError(fmt.Sprintf(format, args...))
 }
 
-// IsCommError returns true if the given error is a CommError
+// NewCommResponseError creates a new CommError for an unsuccessful response from Prometheus
+// using a string formatter for the message.
+func NewCommResponseError(statusCode int, body []byte, query string, format string, args ...interface{}) CommError {
+	return CommError{
+		StatusCode: statusCode,
+		Body:       string(body),
+		Query:      query,
+		messages:   []string{fmt.Sprintf(format, args...)},
+	}
+}
+
+// NewCommEnvelopeError creates a new CommError for an unsuccessful response from Prometheus
+// which contained a json error envelope.
+func NewCommEnvelopeError(statusCode int, body []byte, query string, envelope *ErrorEnvelope) CommError {
+	return CommError{
+		StatusCode:   statusCode,
+		Body:         string(body),
+		Query:        query,
+		ErrorType:    envelope.ErrorType,
+		ErrorMessage: envelope.Error,
+		messages: []string{
+			fmt.Sprintf("%d (%s) %s: %s, Query: %s", statusCode, http.StatusText(statusCode), envelope.ErrorType, envelope.Error, query),
+		},
+	}
+}
+
+// IsCommError returns true if the given error is a CommError or wraps a CommError
 func IsCommError(err error) bool {
-	_, ok := err.(CommError)
-	return ok
+	var pce CommError
+	return errors.As(err, &pce)
 }
 
 // Error prints the error as a string
 func (pce CommError) Error() string {
-	return fmt.Sprintf("Prometheus communication error: %s", strings.Join(pce.messages, ": "))
+	return fmt.Sprintf("Prometheus communication error: %s%s", strings.Join(pce.messages, ": "), formatRequestID(pce.RequestID))
 }
 
 // Wrap wraps the error with the given message, but persists the error type.
@@ -282,6 +368,43 @@ func (pce CommError) Wrap(message string) CommError {
 	return pce
 }
 
+// IsClientError returns true if the error resulted from a 4xx response
+func (pce CommError) IsClientError() bool {
+	return pce.StatusCode >= 400 && pce.StatusCode < 500
+}
+
+// IsServerError returns true if the error resulted from a 5xx response
+func (pce CommError) IsServerError() bool {
+	return pce.StatusCode >= 500 && pce.StatusCode < 600
+}
+
+// ErrorEnvelope is the json body returned by the Prometheus HTTP API when a request fails
+type ErrorEnvelope struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+}
+
+// DecodeErrorEnvelope attempts to decode a Prometheus error envelope from a response body. The
+// ok return is false if the content type is not json, or the body is not a valid error envelope.
+func DecodeErrorEnvelope(contentType string, body []byte) (envelope *ErrorEnvelope, ok bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/json" {
+		return nil, false
+	}
+
+	var ee ErrorEnvelope
+	if err := json.Unmarshal(body, &ee); err != nil {
+		return nil, false
+	}
+
+	if ee.Status != "error" || (ee.ErrorType == "" && ee.Error == "") {
+		return nil, false
+	}
+
+	return &ee, true
+}
+
 // NoDataError indicates that no data was returned by Prometheus. This should
 // be treated like an EOF error, in that it may be expected.
 type NoDataError struct {
@@ -309,3 +432,111 @@ func (nde NoDataError) Wrap(message string) NoDataError {
 	nde.messages = append([]string{message}, nde.messages...)
 	return nde
 }
+
+// UnsupportedEndpointError indicates that the Prometheus compatible server does not implement
+// the requested API endpoint. This is common for Thanos and Cortex, which do not implement
+// all of the Prometheus status endpoints.
+type UnsupportedEndpointError struct {
+	Endpoint string
+}
+
+// NewUnsupportedEndpointError creates a new UnsupportedEndpointError
+func NewUnsupportedEndpointError(endpoint string) UnsupportedEndpointError {
+	return UnsupportedEndpointError{Endpoint: endpoint}
+}
+
+// IsUnsupportedEndpointError returns true if the given error is an UnsupportedEndpointError
+func IsUnsupportedEndpointError(err error) bool {
+	var uee UnsupportedEndpointError
+	return errors.As(err, &uee)
+}
+
+// Error prints the error as a string
+func (uee UnsupportedEndpointError) Error() string {
+	return fmt.Sprintf("Unsupported endpoint: '%s' is not implemented by the server", uee.Endpoint)
+}
+
+// MetricAbsentError indicates that a query returned no results because the metric does not exist
+// in Prometheus at all, ie: the exporter is not installed or not being scraped. This is distinct
+// from a query which returns no results because the metric currently has no matching series.
+type MetricAbsentError struct {
+	Metric string
+	Query  string
+}
+
+// NewMetricAbsentError creates a new MetricAbsentError
+func NewMetricAbsentError(metric string, query string) MetricAbsentError {
+	return MetricAbsentError{Metric: metric, Query: query}
+}
+
+// IsMetricAbsentError returns true if the given error is a MetricAbsentError
+func IsMetricAbsentError(err error) bool {
+	var mae MetricAbsentError
+	return errors.As(err, &mae)
+}
+
+// Error prints the error as a string
+func (mae MetricAbsentError) Error() string {
+	return fmt.Sprintf("Metric '%s' does not exist fetching query '%s'", mae.Metric, mae.Query)
+}
+
+// QueryValidationError indicates that a query failed the client-side structural check enabled by
+// WithQueryValidation, and was not sent to Prometheus.
+type QueryValidationError struct {
+	Query    string
+	Position int
+	Reason   string
+}
+
+// NewQueryValidationError creates a new QueryValidationError. Position is the 1-based position of
+// the problem in the query, or 0 if the problem is not at a specific position.
+func NewQueryValidationError(query string, position int, reason string) QueryValidationError {
+	return QueryValidationError{Query: query, Position: position, Reason: reason}
+}
+
+// IsQueryValidationError returns true if the given error is a QueryValidationError
+func IsQueryValidationError(err error) bool {
+	var qve QueryValidationError
+	return errors.As(err, &qve)
+}
+
+// Error prints the error as a string
+func (qve QueryValidationError) Error() string {
+	if qve.Position > 0 {
+		return fmt.Sprintf("Invalid query: %s at position %d, Query: %s", qve.Reason, qve.Position, qve.Query)
+	}
+
+	return fmt.Sprintf("Invalid query: %s, Query: %s", qve.Reason, qve.Query)
+}
+
+// AuthError indicates that Prometheus rejected a request with a 401 (Unauthorized) or 403
+// (Forbidden), which means the configured credentials are missing, invalid, or lack access, rather
+// than a problem with the query.
+type AuthError struct {
+	StatusCode int
+	Query      string
+
+	// RequestID is the id sent with the request, if request ids are enabled (see WithRequestID)
+	RequestID string
+}
+
+// NewAuthError creates a new AuthError for the status code of the response
+func NewAuthError(statusCode int, query string) AuthError {
+	return AuthError{StatusCode: statusCode, Query: query}
+}
+
+// IsAuthError returns true if the given error is an AuthError
+func IsAuthError(err error) bool {
+	var ae AuthError
+	return errors.As(err, &ae)
+}
+
+// Error prints the error as a string
+func (ae AuthError) Error() string {
+	reason := "authentication failed"
+	if ae.StatusCode == http.StatusForbidden {
+		reason = "authorization denied"
+	}
+
+	return fmt.Sprintf("Prometheus %s: %d (%s), check the configured credentials, Query: %s%s", reason, ae.StatusCode, http.StatusText(ae.StatusCode), ae.Query, formatRequestID(ae.RequestID))
+}
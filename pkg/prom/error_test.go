@@ -3,7 +3,13 @@ package prom
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
 )
 
 func newCommError() error {
@@ -83,3 +89,255 @@ func TestAllErrorsFor(t *testing.T) {
 		return
 	}
 }
+
+func TestCommErrorResponseFields(t *testing.T) {
+	const query = `sum(up)`
+
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: 503, Body: []byte("service unavailable")})
+	ctx := NewContext(client)
+
+	_, err := ctx.RawQuery(query)
+	if err == nil {
+		t.Fatalf("Expected error for 503 response, got nil")
+	}
+
+	wrapped := fmt.Errorf("Wrap Error: %w", err)
+
+	var commErr CommError
+	if !errors.As(wrapped, &commErr) {
+		t.Fatalf("Expected there to exist a CommError, but failed.")
+	}
+	if !IsCommError(wrapped) {
+		t.Fatalf("IsCommError() returned false for wrapped CommError")
+	}
+
+	if commErr.StatusCode != 503 {
+		t.Errorf("Expected StatusCode 503, got %d", commErr.StatusCode)
+	}
+	if commErr.Body != "service unavailable" {
+		t.Errorf("Unexpected Body: %s", commErr.Body)
+	}
+	if commErr.Query != query {
+		t.Errorf("Unexpected Query: %s", commErr.Query)
+	}
+	if !commErr.IsServerError() || commErr.IsClientError() {
+		t.Errorf("Expected server error classification for 503")
+	}
+
+	// Wrapping should persist the response fields
+	commErr = commErr.Wrap("Context")
+	if commErr.StatusCode != 503 || commErr.Query != query {
+		t.Errorf("Response fields were not persisted after Wrap")
+	}
+}
+
+func TestAuthError(t *testing.T) {
+	testCases := map[string]struct {
+		status  int
+		message string
+	}{
+		"unauthorized": {
+			status:  http.StatusUnauthorized,
+			message: "authentication failed",
+		},
+		"forbidden": {
+			status:  http.StatusForbidden,
+			message: "authorization denied",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: test.status, Body: []byte("<html>" + http.StatusText(test.status) + "</html>")})
+			ctx := NewContext(client)
+
+			for _, query := range []func() error{
+				func() error { _, err := ctx.RawQuery("up"); return err },
+				func() error {
+					_, err := ctx.RawQueryRange("up", time.Now().Add(-time.Hour), time.Now(), time.Minute)
+					return err
+				},
+			} {
+				err := fmt.Errorf("Wrap Error: %w", query())
+
+				var authErr AuthError
+				if !errors.As(err, &authErr) {
+					t.Fatalf("Expected AuthError, got: %s", err)
+				}
+				if authErr.StatusCode != test.status {
+					t.Fatalf("StatusCode: exp (%d); act (%d)", test.status, authErr.StatusCode)
+				}
+				if !strings.Contains(err.Error(), test.message) || strings.Contains(err.Error(), "<html>") {
+					t.Fatalf("Unexpected message: %s", err)
+				}
+				if IsCommError(err) {
+					t.Fatalf("Expected AuthError not to be a CommError")
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeErrorEnvelope(t *testing.T) {
+	body := []byte(`{"status":"error","errorType":"bad_data","error":"invalid parameter \"query\": 1:5: parse error"}`)
+
+	envelope, ok := DecodeErrorEnvelope("application/json; charset=utf-8", body)
+	if !ok {
+		t.Fatalf("Expected error envelope to be decoded")
+	}
+	if envelope.ErrorType != "bad_data" {
+		t.Errorf("Unexpected ErrorType: %s", envelope.ErrorType)
+	}
+	if envelope.Error != `invalid parameter "query": 1:5: parse error` {
+		t.Errorf("Unexpected Error: %s", envelope.Error)
+	}
+
+	if _, ok := DecodeErrorEnvelope("text/html", body); ok {
+		t.Errorf("Expected non-json content type to be ignored")
+	}
+	if _, ok := DecodeErrorEnvelope("application/json", []byte("<html>Bad Gateway</html>")); ok {
+		t.Errorf("Expected invalid json body to be ignored")
+	}
+	if _, ok := DecodeErrorEnvelope("application/json", []byte(`{"status":"success","data":{}}`)); ok {
+		t.Errorf("Expected success envelope to be ignored")
+	}
+}
+
+func TestCommErrorEnvelopeFields(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: 400, Header: http.Header{"Content-Type": []string{"application/json"}}, Body: []byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`)})
+	ctx := NewContext(client)
+
+	_, err := ctx.RawQuery(`sum(up`)
+
+	var commErr CommError
+	if !errors.As(err, &commErr) {
+		t.Fatalf("Expected there to exist a CommError, but failed.")
+	}
+	if commErr.ErrorType != "bad_data" || commErr.ErrorMessage != "parse error" {
+		t.Errorf("Unexpected envelope fields: %s, %s", commErr.ErrorType, commErr.ErrorMessage)
+	}
+	if !commErr.IsClientError() {
+		t.Errorf("Expected client error classification for 400")
+	}
+
+	// Non-json bodies fall back to the raw body
+	client = promtest.NewClient().SetDefault(&promtest.Response{StatusCode: 502, Header: http.Header{"Content-Type": []string{"text/html"}}, Body: []byte("<html>Bad Gateway</html>")})
+	ctx = NewContext(client)
+
+	_, err = ctx.RawQueryRange(`up`, time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	if !errors.As(err, &commErr) {
+		t.Fatalf("Expected there to exist a CommError, but failed.")
+	}
+	if commErr.ErrorType != "" || commErr.Body != "<html>Bad Gateway</html>" {
+		t.Errorf("Expected raw body fallback, got ErrorType: %s, Body: %s", commErr.ErrorType, commErr.Body)
+	}
+}
+
+func TestFailedQueries(t *testing.T) {
+	qc := &QueryErrorCollector{}
+	if queries := qc.FailedQueries(); len(queries) != 0 {
+		t.Fatalf("Expected no failed queries, got: %v", queries)
+	}
+
+	qc.Report("test_query1", nil, NewCommError("Failed to connect"), nil)
+	qc.Report("test_query2", nil, nil, errors.New("Parsing error"))
+	qc.Report("test_query1", nil, NewCommError("Failed to connect"), nil)
+	qc.Report("test_query3", []string{"partial data"}, nil, nil)
+
+	expected := []string{"test_query1", "test_query2"}
+	if queries := qc.FailedQueries(); !reflect.DeepEqual(queries, expected) {
+		t.Fatalf("Failed queries: exp (%v); act (%v)", expected, queries)
+	}
+
+	if errs := qc.Errors(); len(errs) != 3 {
+		t.Fatalf("Errors: exp (3); act (%d)", len(errs))
+	}
+}
+
+func TestIsNoStoreAPIWarning(t *testing.T) {
+	cases := map[string]struct {
+		warning  string
+		expected bool
+	}{
+		"constant": {
+			warning:  NoStoreAPIWarning,
+			expected: true,
+		},
+		"lowercase": {
+			warning:  "no StoreAPIs matched for this query",
+			expected: true,
+		},
+		"with stores": {
+			warning:  "No StoreAPIs matched for this query, stores: store Addr: thanos-store:10901 LabelSets: {cluster=\"a\"} Mint: 0 Maxt: 1622505600000 filtered out",
+			expected: true,
+		},
+		"component prefix": {
+			warning:  "proxy: no StoreAPI matched for this query",
+			expected: true,
+		},
+		"stores wording": {
+			warning:  "No stores matched for this query",
+			expected: true,
+		},
+		"extra whitespace": {
+			warning:  "No  StoreAPIs\tmatched for this query",
+			expected: true,
+		},
+		"partial data": {
+			warning:  "partial response: receive series from Addr: thanos-store:10901: rpc error",
+			expected: false,
+		},
+		"empty": {
+			warning:  "",
+			expected: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if actual := IsNoStoreAPIWarning(tc.warning); actual != tc.expected {
+				t.Fatalf("IsNoStoreAPIWarning: exp (%t); act (%t)", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestClassifyWarning(t *testing.T) {
+	testCases := map[string]struct {
+		warning  string
+		expected WarningType
+	}{
+		"no store api": {
+			warning:  NoStoreAPIWarning,
+			expected: WarningTypeNoStoreAPI,
+		},
+		"thanos fetch series": {
+			warning:  `fetch series for {cluster="cluster-one"} 10.0.0.1:10901: rpc error: code = Unavailable`,
+			expected: WarningTypePartialResponse,
+		},
+		"thanos receive series": {
+			warning:  "receive series from Addr: 10.0.0.2:10901: context deadline exceeded",
+			expected: WarningTypePartialResponse,
+		},
+		"cortex partial response": {
+			warning:  "Partial response: querier failed to respond",
+			expected: WarningTypePartialResponse,
+		},
+		"promql annotation": {
+			warning:  `PromQL info: metric might not be a counter, name does not end in _total/_sum/_count/_bucket: "node_cpu"`,
+			expected: WarningTypePromQL,
+		},
+		"unknown": {
+			warning:  "query is slow",
+			expected: WarningTypeUnknown,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := ClassifyWarning(tc.warning); actual != tc.expected {
+				t.Fatalf("ClassifyWarning: exp (%s); act (%s)", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package prom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// EstimateSeries returns the number of series the instant query currently returns, which estimates
+// the cardinality of the query before running it as a costly range query. The estimate is computed
+// by Prometheus, so only the count is transferred, regardless of the number of series. The query may
+// be any expression returning an instant vector, ie: a selector or an aggregation; 0 is returned if
+// it returns no series. Expressions which do not return an instant vector, ie: scalars or range
+// vector selectors, cannot be counted, and return an error describing why. The query is validated,
+// retried and cancelled with the Context, like any other query.
+func (ctx *Context) EstimateSeries(query string) (int, error) {
+	if err := ctx.validateQuery(query); err != nil {
+		return 0, err
+	}
+
+	// the query is placed on its own lines, so a trailing comment cannot comment out the paren
+	countQuery := "count by () (\n" + query + "\n)"
+
+	results, _, err := ctx.querySync(ctx.baseContext(), countQuery)
+	if err != nil {
+		var ce CommError
+		if errors.As(err, &ce) && ce.ErrorType == "bad_data" {
+			return 0, fmt.Errorf("Unable to estimate series: the query does not return an instant vector: %s, Query: %s", ce.ErrorMessage, query)
+		}
+
+		return 0, err
+	}
+
+	if results.Len() == 0 && results.Error == nil {
+		return 0, nil
+	}
+
+	count, err := results.SingleValue()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(math.Round(count)), nil
+}
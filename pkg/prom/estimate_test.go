@@ -0,0 +1,104 @@
+package prom
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestEstimateSeries(t *testing.T) {
+	testCases := map[string]struct {
+		query     string
+		status    int
+		body      string
+		expected  int
+		expectErr string
+	}{
+		"selector": {
+			query:    `container_memory_working_set_bytes{namespace="kubecost"}`,
+			body:     `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"1250"]}]}}`,
+			expected: 1250,
+		},
+		"no series": {
+			query:    `absent_metric`,
+			body:     `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			expected: 0,
+		},
+		"trailing comment": {
+			query:    "sum(up) by (job) # jobs",
+			body:     `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"3"]}]}}`,
+			expected: 3,
+		},
+		"range vector": {
+			query:     `up[5m]`,
+			status:    http.StatusBadRequest,
+			body:      `{"status":"error","errorType":"bad_data","error":"expected type instant vector in aggregation expression, got range vector"}`,
+			expectErr: "does not return an instant vector",
+		},
+		"malformed": {
+			query:     `sum(up`,
+			expectErr: "unclosed",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: test.status, Header: http.Header{"Content-Type": []string{"application/json"}}, Body: []byte(test.body)})
+
+			count, err := NewContext(client).WithQueryValidation(true).EstimateSeries(test.query)
+			if test.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+					t.Fatalf("Expected error containing '%s', got: %v", test.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if count != test.expected {
+				t.Fatalf("count: exp (%d); act (%d)", test.expected, count)
+			}
+
+			sent := promtest.RequestParams(client.Requests()[0]).Get("query")
+			if sent != "count by () (\n"+test.query+"\n)" {
+				t.Fatalf("Unexpected query: %s", sent)
+			}
+			if err := checkQueryStructure(sent); err != nil {
+				t.Fatalf("Unexpected validation error: %s", err)
+			}
+		})
+	}
+}
+
+func TestEstimateSeriesClose(t *testing.T) {
+	client := promtest.NewClient()
+	ctx := NewContext(client)
+	ctx.Close()
+
+	if _, err := ctx.EstimateSeries("up"); !IsQueryCancelledError(err) {
+		t.Fatalf("Expected cancelled error, got: %v", err)
+	}
+	if len(client.Requests()) != 0 {
+		t.Fatalf("Expected no request to be sent by a closed context")
+	}
+}
+
+func TestEstimateSeriesValidation(t *testing.T) {
+	const query = `sum(up`
+
+	client := promtest.NewClient()
+	if _, err := NewContext(client).WithQueryValidation(true).EstimateSeries(query); err == nil {
+		t.Fatalf("Expected validation error for malformed query")
+	}
+	if len(client.Requests()) != 0 {
+		t.Fatalf("Expected no request to be sent for an invalid query")
+	}
+
+	// without validation, the query is left to Prometheus to reject
+	NewContext(client).EstimateSeries(query)
+	if len(client.Requests()) != 1 {
+		t.Fatalf("Expected the query to be sent without validation")
+	}
+}
@@ -0,0 +1,222 @@
+package prom
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/util"
+	"github.com/kubecost/cost-model/pkg/util/httputil"
+)
+
+const epFederate = "/federate"
+
+// Federate fetches the latest sample of each series matching any of the series selectors from
+// the federation endpoint, ie: Federate([]string{`{job="kubecost"}`}). Unlike the query APIs,
+// federation responds in the text exposition format, which is parsed by parseExposition. Each
+// returned result contains a single sample, with the timestamp returned as-is, without being
+// aligned to a step.
+func (ctx *Context) Federate(matches []string) ([]*QueryResult, error) {
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("Federation requires at least one series selector")
+	}
+
+	params := url.Values{}
+	for _, match := range matches {
+		params.Add("match[]", match)
+	}
+
+	query := strings.Join(matches, ", ")
+
+	u := ctx.Client.URL(epFederate, nil)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set QueryContext name if non empty
+	if ctx.name != "" {
+		req = httputil.SetName(req, ctx.name)
+	}
+	req = httputil.SetQuery(req, query)
+	ctx.setUserAgent(req)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	req.Header.Set("Accept", "text/plain")
+
+	resp, body, _, err := ctx.Client.Do(context.Background(), req)
+	if err != nil {
+		if resp == nil {
+			return nil, fmt.Errorf("federate error: '%s' fetching series '%s'", err.Error(), query)
+		}
+
+		return nil, fmt.Errorf("federate error %d: '%s' fetching series '%s'", resp.StatusCode, err.Error(), query)
+	}
+
+	body, err = decodeResponseBody(resp, body, ctx.maxResponseBytes)
+	if err != nil {
+		return nil, CommErrorf("%s, Query: %s", err, query)
+	}
+
+	statusCode := resp.StatusCode
+	if statusCode == http.StatusNotFound {
+		return nil, NewUnsupportedEndpointError(epFederate)
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		return nil, NewCommResponseError(statusCode, body, query, "%d (%s) Headers: %s, Body: %s Query: %s", statusCode, http.StatusText(statusCode), httputil.HeaderString(resp.Header), body, query)
+	}
+
+	results, err := parseExposition(body, ctx.now())
+	if err != nil {
+		return nil, fmt.Errorf("Parse Error: %s\nQuery: %s", err, query)
+	}
+
+	ctx.setDefaultResultLabels(results)
+
+	return results, nil
+}
+
+//--------------------------------------------------------------------------
+//  Text Exposition Format
+//--------------------------------------------------------------------------
+
+// parseExposition parses the samples of a text exposition format body into one QueryResult per
+// sample line, with the metric name set as the __name__ label. Comment lines, including HELP and
+// TYPE metadata, are ignored, so histogram and summary series are returned as their individual
+// _bucket, _sum and _count series. Samples without a timestamp use now. NaN and Inf values are
+// replaced with 0.
+func parseExposition(body []byte, now time.Time) ([]*QueryResult, error) {
+	var results []*QueryResult
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		result, err := parseSampleLine(line, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNum, err)
+		}
+
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// parseSampleLine parses a single sample line, ie: up{job="kubecost"} 1 1622505600000
+func parseSampleLine(line string, now time.Time) (*QueryResult, error) {
+	metric := make(map[string]interface{})
+
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return nil, fmt.Errorf("invalid sample: '%s'", line)
+	}
+	metric["__name__"] = line[:nameEnd]
+
+	rest := line[nameEnd:]
+	if rest[0] == '{' {
+		n, err := parseLabels(rest, metric)
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[n:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid sample: '%s'", line)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sample value '%s': %s", fields[0], err)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		value = 0
+	}
+
+	timestamp := float64(now.UnixNano()/int64(time.Millisecond)) / 1000
+	if len(fields) == 2 {
+		ms, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample timestamp '%s': %s", fields[1], err)
+		}
+		timestamp = float64(ms) / 1000
+	}
+
+	return &QueryResult{
+		Metric: metric,
+		Values: []*util.Vector{{Timestamp: timestamp, Value: value}},
+	}, nil
+}
+
+// parseLabels parses the label set starting at the opening brace of s into metric, returning the
+// number of bytes consumed, including the closing brace.
+func parseLabels(s string, metric map[string]interface{}) (int, error) {
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return 0, fmt.Errorf("unterminated label set: '%s'", s)
+		}
+		if s[i] == '}' {
+			return i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq <= 0 {
+			return 0, fmt.Errorf("invalid label set: '%s'", s)
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+
+		if i >= len(s) || s[i] != '"' {
+			return 0, fmt.Errorf("unquoted value for label '%s'", name)
+		}
+		i++
+
+		var value strings.Builder
+		for {
+			if i >= len(s) {
+				return 0, fmt.Errorf("unterminated value for label '%s'", name)
+			}
+
+			c := s[i]
+			i++
+			if c == '"' {
+				break
+			}
+			if c == '\\' && i < len(s) {
+				c = s[i]
+				i++
+				if c == 'n' {
+					c = '\n'
+				}
+			}
+			value.WriteByte(c)
+		}
+
+		metric[name] = value.String()
+	}
+}
@@ -0,0 +1,123 @@
+package prom
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+func TestParseExposition(t *testing.T) {
+	now := time.Unix(1622505700, 0)
+
+	testCases := map[string]struct {
+		body     string
+		expected []*QueryResult
+		err      bool
+	}{
+		"labeled samples": {
+			body: "# TYPE up untyped\n" +
+				"up{instance=\"localhost:9090\",job=\"prometheus\"} 1 1622505600000\n" +
+				"up{job=\"kubecost\",} 0 1622505615500\n",
+			expected: []*QueryResult{
+				{
+					Metric: map[string]interface{}{"__name__": "up", "instance": "localhost:9090", "job": "prometheus"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 1}},
+				},
+				{
+					Metric: map[string]interface{}{"__name__": "up", "job": "kubecost"},
+					Values: []*util.Vector{{Timestamp: 1622505615.5, Value: 0}},
+				},
+			},
+		},
+		"no labels or timestamp": {
+			body: "kubecost_cluster_count 3\n",
+			expected: []*QueryResult{
+				{
+					Metric: map[string]interface{}{"__name__": "kubecost_cluster_count"},
+					Values: []*util.Vector{{Timestamp: 1622505700, Value: 3}},
+				},
+			},
+		},
+		"escaped label values": {
+			body: `node_info{path="C:\\data",quote="say \"hi\"",multi="a\nb"} 1 1622505600000`,
+			expected: []*QueryResult{
+				{
+					Metric: map[string]interface{}{"__name__": "node_info", "path": `C:\data`, "quote": `say "hi"`, "multi": "a\nb"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 1}},
+				},
+			},
+		},
+		"histogram series": {
+			body: "# HELP latency Request latency\n" +
+				"# TYPE latency histogram\n" +
+				"latency_bucket{le=\"+Inf\"} 2 1622505600000\n" +
+				"latency_sum NaN 1622505600000\n",
+			expected: []*QueryResult{
+				{
+					Metric: map[string]interface{}{"__name__": "latency_bucket", "le": "+Inf"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 2}},
+				},
+				{
+					Metric: map[string]interface{}{"__name__": "latency_sum"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 0}},
+				},
+			},
+		},
+		"empty body": {
+			body:     "",
+			expected: nil,
+		},
+		"invalid value": {
+			body: "up{job=\"kubecost\"} one\n",
+			err:  true,
+		},
+		"unterminated labels": {
+			body: "up{job=\"kubecost\" 1\n",
+			err:  true,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			results, err := parseExposition([]byte(test.body), now)
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected error parsing: %s", test.body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(results, test.expected) {
+				t.Fatalf("Results: exp (%+v); act (%+v)", test.expected, results)
+			}
+		})
+	}
+}
+
+func TestFederate(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Header: http.Header{"Content-Type": []string{"text/plain; version=0.0.4"}}, Body: []byte("up{job=\"kubecost\"} 1 1622505600000\n")})
+
+	matches := []string{`{job="kubecost"}`, `{__name__=~"node_.*"}`}
+	results, err := NewContext(client).WithUserAgent("test-agent").Federate(matches)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Results: exp (1); act (%d)", len(results))
+	}
+
+	req := client.Requests()[0]
+	if req.URL.Path != epFederate || !reflect.DeepEqual(req.URL.Query()["match[]"], matches) {
+		t.Fatalf("Unexpected request: %s", req.URL)
+	}
+	if req.Header.Get("User-Agent") != "test-agent" {
+		t.Fatalf("User-Agent: exp (test-agent); act (%s)", req.Header.Get("User-Agent"))
+	}
+}
@@ -0,0 +1,32 @@
+package prom
+
+import (
+	"strings"
+	"sync"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+)
+
+// Only allow the label metrics to be instantiated and registered once
+var labelMetricsInit sync.Once
+
+var labelCollisionsCv *promclient.CounterVec
+
+// initLabelMetrics uses a sync.Once to ensure that the label metrics are only created and
+// registered once
+func initLabelMetrics() {
+	labelMetricsInit.Do(func() {
+		labelCollisionsCv = promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "kubecost_collector_label_collisions_total",
+			Help: "kubecost_collector_label_collisions_total Number of kubernetes labels or annotations dropped because their sanitized names collided",
+		}, []string{"qualifier"})
+
+		promclient.MustRegister(labelCollisionsCv)
+	})
+}
+
+// recordLabelCollision increments the label collisions metric for the qualifier, ie: label_
+func recordLabelCollision(qualifier string) {
+	initLabelMetrics()
+	labelCollisionsCv.WithLabelValues(strings.TrimSuffix(qualifier, "_")).Inc()
+}
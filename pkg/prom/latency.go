@@ -0,0 +1,190 @@
+package prom
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxTrackedQueries is the default number of distinct queries the latency of which is
+// tracked by a Context, see WithLatencyTracking
+const DefaultMaxTrackedQueries = 500
+
+// latencyEMAWeight is the weight of the newest observation in the moving average latency of a query
+const latencyEMAWeight = 0.2
+
+// latencySampleWindow is the number of most recent observations of a query used to compute its p95
+const latencySampleWindow = 20
+
+// QueryLatency is a snapshot of the observed latency of a query
+type QueryLatency struct {
+	// Query is the normalized query, see WithQueryDedup
+	Query string
+	// Count is the number of observations of the query
+	Count int64
+	// Average is the exponential moving average latency of the query, which favors recent
+	// observations
+	Average time.Duration
+	// P95 is the 95th percentile latency of the most recent observations of the query
+	P95 time.Duration
+	// Last is the latency of the most recent observation
+	Last time.Duration
+	// LastSeen is the time of the most recent observation
+	LastSeen time.Time
+}
+
+// WithLatencyTracking sets the maximum number of distinct queries the Context tracks the latency
+// of, which may be retrieved using QueryStats. Queries are tracked by the fingerprint of their
+// normalized query, and the least recently observed query is evicted once the maximum is reached.
+// Tracking is enabled by default with DefaultMaxTrackedQueries. A value <= 0 disables tracking.
+// Changing the maximum discards the latencies tracked so far. Copies of the Context made with
+// WithName share latency tracking with the original. Returns the Context to allow chaining.
+func (ctx *Context) WithLatencyTracking(maxQueries int) *Context {
+	if maxQueries <= 0 {
+		ctx.latencies = nil
+	} else {
+		ctx.latencies = newLatencyTracker(maxQueries)
+	}
+	return ctx
+}
+
+// QueryStats returns a snapshot of the observed latency of the query and query_range requests made
+// by the Context, keyed by the fingerprint of the normalized query. The latency of each attempt is
+// observed separately, excluding any wait for the scheduler, retry backoff, and decoding. Successful
+// requests are observed, as are requests cut off by a deadline, ie: an adaptive timeout, which are
+// observed at the time they were cut off. Other failed requests are not observed. Returns nil if
+// latency tracking is disabled.
+func (ctx *Context) QueryStats() map[string]QueryLatency {
+	if ctx.latencies == nil {
+		return nil
+	}
+
+	return ctx.latencies.snapshot()
+}
+
+// observeAttempt records the latency of a request for the query which started at start if latency
+// tracking is enabled, and the request either succeeded or was cut off by the deadline of reqCtx.
+// Timed out requests must be observed, otherwise a query which slows beyond its adaptive timeout
+// would never grow its history, and would be cut off indefinitely.
+func (ctx *Context) observeAttempt(reqCtx context.Context, query string, start time.Time, resp *http.Response, err error) {
+	if ctx.latencies == nil {
+		return
+	}
+
+	succeeded := err == nil && resp != nil && resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !succeeded && reqCtx.Err() != context.DeadlineExceeded {
+		return
+	}
+
+	normalized := normalizeQuery(query)
+	ctx.latencies.observe(queryHash(normalized), normalized, time.Since(start), time.Now())
+}
+
+// latencyEntry is the element value of a tracked query in the latencyTracker
+type latencyEntry struct {
+	fingerprint string
+	latency     QueryLatency
+
+	// samples is a ring of the most recent observations, the oldest of which is at next once full
+	samples []time.Duration
+	next    int
+}
+
+// add adds an observation to the samples of the entry and updates the p95 latency
+func (le *latencyEntry) add(latency time.Duration) {
+	if len(le.samples) < latencySampleWindow {
+		le.samples = append(le.samples, latency)
+	} else {
+		le.samples[le.next] = latency
+		le.next = (le.next + 1) % latencySampleWindow
+	}
+
+	sorted := make([]time.Duration, len(le.samples))
+	copy(sorted, le.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	le.latency.P95 = sorted[(len(sorted)*95+99)/100-1]
+}
+
+// latencyTracker tracks the moving average latency of a bounded number of queries, evicting the
+// least recently observed query when full
+type latencyTracker struct {
+	lock       sync.Mutex
+	maxQueries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// newLatencyTracker creates a new latencyTracker which tracks up to maxQueries queries
+func newLatencyTracker(maxQueries int) *latencyTracker {
+	return &latencyTracker{
+		maxQueries: maxQueries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// observe records an observed latency of the query with the provided fingerprint
+func (lt *latencyTracker) observe(fingerprint string, query string, latency time.Duration, now time.Time) {
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	if elem, ok := lt.entries[fingerprint]; ok {
+		entry := elem.Value.(*latencyEntry)
+		entry.latency.Count++
+		entry.latency.Average += time.Duration(latencyEMAWeight * float64(latency-entry.latency.Average))
+		entry.latency.Last = latency
+		entry.latency.LastSeen = now
+		entry.add(latency)
+		lt.order.MoveToFront(elem)
+		return
+	}
+
+	if lt.order.Len() >= lt.maxQueries {
+		oldest := lt.order.Back()
+		lt.order.Remove(oldest)
+		delete(lt.entries, oldest.Value.(*latencyEntry).fingerprint)
+	}
+
+	entry := &latencyEntry{
+		fingerprint: fingerprint,
+		latency: QueryLatency{
+			Query:    query,
+			Count:    1,
+			Average:  latency,
+			Last:     latency,
+			LastSeen: now,
+		},
+	}
+	entry.add(latency)
+	lt.entries[fingerprint] = lt.order.PushFront(entry)
+}
+
+// get returns the latency of the query with the provided fingerprint, if tracked
+func (lt *latencyTracker) get(fingerprint string) (QueryLatency, bool) {
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	elem, ok := lt.entries[fingerprint]
+	if !ok {
+		return QueryLatency{}, false
+	}
+
+	return elem.Value.(*latencyEntry).latency, true
+}
+
+// snapshot returns a copy of the tracked latencies keyed by fingerprint
+func (lt *latencyTracker) snapshot() map[string]QueryLatency {
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	stats := make(map[string]QueryLatency, len(lt.entries))
+	for fingerprint, elem := range lt.entries {
+		stats[fingerprint] = elem.Value.(*latencyEntry).latency
+	}
+
+	return stats
+}
@@ -0,0 +1,70 @@
+package prom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	lt := newLatencyTracker(2)
+	now := time.Now()
+
+	lt.observe("a", "query_a", 10*time.Second, now)
+	lt.observe("a", "query_a", 20*time.Second, now)
+
+	a := lt.snapshot()["a"]
+	if a.Count != 2 {
+		t.Fatalf("Count: exp (2); act (%d)", a.Count)
+	}
+	if a.Average != 12*time.Second {
+		t.Fatalf("Average: exp (%s); act (%s)", 12*time.Second, a.Average)
+	}
+	if a.Last != 20*time.Second {
+		t.Fatalf("Last: exp (%s); act (%s)", 20*time.Second, a.Last)
+	}
+
+	// observing a again makes b the least recently observed, which is evicted by c
+	lt.observe("b", "query_b", time.Second, now)
+	lt.observe("a", "query_a", time.Second, now)
+	lt.observe("c", "query_c", time.Second, now)
+
+	stats := lt.snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("tracked: exp (2); act (%d)", len(stats))
+	}
+	if _, ok := stats["b"]; ok {
+		t.Fatalf("Expected b to be evicted")
+	}
+	if _, ok := stats["a"]; !ok {
+		t.Fatalf("Expected a to be tracked")
+	}
+}
+
+func TestQueryLatencyStats(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	ctx := NewContext(client)
+
+	ctx.Query(`sum(up) by (namespace)`).Await()
+	ctx.Query(`sum(up)  by (namespace)`).Await()
+
+	stats := ctx.QueryStats()
+	if len(stats) != 1 {
+		t.Fatalf("tracked: exp (1); act (%d)", len(stats))
+	}
+	for _, latency := range stats {
+		if latency.Count != 2 {
+			t.Fatalf("Count: exp (2); act (%d)", latency.Count)
+		}
+		if latency.Query != normalizeQuery(`sum(up) by (namespace)`) {
+			t.Fatalf("Query: exp (%s); act (%s)", normalizeQuery(`sum(up) by (namespace)`), latency.Query)
+		}
+	}
+
+	ctx.WithLatencyTracking(0)
+	ctx.Query("up").Await()
+	if stats := ctx.QueryStats(); stats != nil {
+		t.Fatalf("Expected no stats when latency tracking is disabled")
+	}
+}
@@ -0,0 +1,89 @@
+package prom
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/log"
+)
+
+// Logger receives the logs of a Context, which allows embedding applications to route the logs of
+// queries through their own logging. See WithLogger.
+type Logger interface {
+	Warningf(format string, a ...interface{})
+	Infof(format string, a ...interface{})
+	Profilef(format string, a ...interface{})
+}
+
+// globalLogger is the Logger used when no Logger is set on the Context, which writes to the
+// package-global log functions
+type globalLogger struct{}
+
+func (globalLogger) Warningf(format string, a ...interface{}) {
+	log.Warningf(format, a...)
+}
+
+func (globalLogger) Infof(format string, a ...interface{}) {
+	log.Infof(format, a...)
+}
+
+func (globalLogger) Profilef(format string, a ...interface{}) {
+	log.Profilef(format, a...)
+}
+
+// WithLogger sets the Logger used for the warnings, slow query, and profile logs of queries made by
+// the Context. A nil Logger uses the package-global log functions, which is the default. Returns the
+// Context to allow chaining.
+func (ctx *Context) WithLogger(l Logger) *Context {
+	ctx.logger = l
+	return ctx
+}
+
+// getLogger returns the Logger set on the Context, or the global logger if unset
+func (ctx *Context) getLogger() Logger {
+	if ctx.logger == nil {
+		return globalLogger{}
+	}
+	return ctx.logger
+}
+
+// WithProfileAll enables or disables profiling every query and range query made by the Context, as
+// if made with ProfileQuery and ProfileQueryRange, which is useful when temporarily diagnosing slow
+// operations without changing call sites. Queries without a profile label are labeled "Query" or
+// "QueryRange", and are identified by the context name and query hash of the profile log. Disabled
+// by default. Returns the Context to allow chaining.
+func (ctx *Context) WithProfileAll(enabled bool) *Context {
+	ctx.profileAll = enabled
+	return ctx
+}
+
+// profileLabelFor returns the profile label of a query, which is the provided label, or the default
+// label if unset and all queries are profiled
+func (ctx *Context) profileLabelFor(profileLabel string, defaultLabel string) string {
+	if profileLabel == "" && ctx.profileAll {
+		return defaultLabel
+	}
+
+	return profileLabel
+}
+
+// logProfile logs the duration of a profiled query, attributed to the context name and a short hash
+// of the query, so the output of concurrent profiles can be told apart, ie:
+// 1.2s: [allocation 3f2a1b9c] Profiled Query
+func (ctx *Context) logProfile(start time.Time, query string, profileLabel string) {
+	name := ctx.name
+	if name == "" {
+		name = "unnamed"
+	}
+
+	ctx.getLogger().Profilef("%s: [%s %s] %s", time.Since(start), name, queryHash(query), profileLabel)
+}
+
+// queryHash returns a short hex hash of the query, which identifies the query in logs without
+// including the full query
+func queryHash(query string) string {
+	h := fnv.New32a()
+	h.Write([]byte(query))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
@@ -0,0 +1,34 @@
+package prom
+
+import (
+	"net/url"
+)
+
+const epMetadata = apiPrefix + "/metadata"
+
+// MetricMetadata is the metadata of a metric as reported by the targets exposing it
+type MetricMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+// Metadata returns the metadata of the metric, ie: its type, help text, and unit, keyed by metric
+// name. Each metric may have multiple entries if the targets exposing it disagree. If metric is
+// empty, the metadata of all metrics is returned. Metrics which are not exposed by any target are
+// not included in the result. Servers which do not implement the endpoint (ie: Prometheus < 2.15)
+// return an UnsupportedEndpointError.
+func (ctx *Context) Metadata(metric string) (map[string][]MetricMetadata, error) {
+	var params url.Values
+	if metric != "" {
+		params = url.Values{}
+		params.Set("metric", metric)
+	}
+
+	metadata := make(map[string][]MetricMetadata)
+	if err := ctx.apiGet(epMetadata, params, &metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,37 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestMetadata(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"node_total_hourly_cost":[{"type":"gauge","help":"node_total_hourly_cost Total node cost per hour","unit":""}]}}`)})
+	ctx := NewContext(client)
+
+	metadata, err := ctx.Metadata("node_total_hourly_cost")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	entries := metadata["node_total_hourly_cost"]
+	if len(entries) != 1 {
+		t.Fatalf("Metadata: exp (%d); act (%d)", 1, len(entries))
+	}
+	if entries[0].Type != "gauge" || entries[0].Help != "node_total_hourly_cost Total node cost per hour" {
+		t.Errorf("Unexpected metadata: %+v", entries[0])
+	}
+
+	req := client.Requests()[0]
+	if req.URL.Path != epMetadata || req.URL.Query().Get("metric") != "node_total_hourly_cost" {
+		t.Errorf("Unexpected request: %s", req.URL)
+	}
+
+	if _, err := ctx.Metadata(""); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := client.Requests()[1].URL.Query()["metric"]; ok {
+		t.Errorf("Expected no metric parameter for all metadata: %s", client.Requests()[1].URL)
+	}
+}
@@ -74,6 +74,11 @@ func LabelNamesFrom(labels map[string]string) []string {
 }
 
 // Prepends a qualifier string to the keys provided in the m map and returns the new keys and values.
+// Keys are sanitized using SanitizeLabelName, so distinct keys may collide, ie: app.kubernetes.io/name
+// and app_kubernetes_io_name. Since a metric cannot contain duplicate label names, which would fail the
+// entire scrape, each label name is only returned once, with the value of the colliding key which sorts
+// last. Sorting keeps the chosen value stable between scrapes. Each dropped value increments the
+// kubecost_collector_label_collisions_total metric.
 func KubePrependQualifierToLabels(m map[string]string, qualifier string) ([]string, []string) {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -81,13 +86,23 @@ func KubePrependQualifierToLabels(m map[string]string, qualifier string) ([]stri
 	}
 	sort.Strings(keys)
 
+	labels := make([]string, 0, len(m))
 	values := make([]string, 0, len(m))
-	for i, k := range keys {
-		keys[i] = qualifier + SanitizeLabelName(k)
+	seen := make(map[string]int, len(m))
+	for _, k := range keys {
+		label := qualifier + SanitizeLabelName(k)
+		if i, ok := seen[label]; ok {
+			recordLabelCollision(qualifier)
+			values[i] = m[k]
+			continue
+		}
+		seen[label] = len(labels)
+
+		labels = append(labels, label)
 		values = append(values, m[k])
 	}
 
-	return keys, values
+	return labels, values
 }
 
 // Converts kubernetes labels into prometheus labels.
@@ -100,7 +115,10 @@ func KubeAnnotationsToLabels(labels map[string]string) ([]string, []string) {
 	return KubePrependQualifierToLabels(labels, "annotation_")
 }
 
-// Replaces all illegal prometheus label characters with _
+// Replaces all illegal prometheus label characters with _, ie: app.kubernetes.io/name becomes
+// app_kubernetes_io_name. Each illegal character is replaced individually, rather than collapsing
+// runs into a single _, as this matches the label names emitted by kube-state-metrics, which are
+// relied on when querying label_* series.
 func SanitizeLabelName(s string) string {
 	return invalidLabelCharRE.ReplaceAllString(s, "_")
 }
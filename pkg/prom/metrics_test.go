@@ -3,6 +3,9 @@ package prom
 import (
 	"fmt"
 	"testing"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func checkSlice(s1, s2 []string) error {
@@ -93,3 +96,134 @@ func TestKubeLabelsToPromLabels(t *testing.T) {
 		t.Errorf("%s", err)
 	}
 }
+
+func TestSanitizeLabelName(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		expected string
+	}{
+		"valid": {
+			name:     "app",
+			expected: "app",
+		},
+		"recommended label": {
+			name:     "app.kubernetes.io/name",
+			expected: "app_kubernetes_io_name",
+		},
+		"dashes": {
+			name:     "pod-template-hash",
+			expected: "pod_template_hash",
+		},
+		"consecutive illegal characters": {
+			name:     "example.com/--team",
+			expected: "example_com___team",
+		},
+		"existing underscores": {
+			name:     "cost_center",
+			expected: "cost_center",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual := SanitizeLabelName(tc.name)
+			if actual != tc.expected {
+				t.Fatalf("SanitizeLabelName: exp (%s); act (%s)", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestKubeLabelsToPromLabelsRecommendedLabels(t *testing.T) {
+	kubeLabels := map[string]string{
+		"app.kubernetes.io/name":       "cost-analyzer",
+		"app.kubernetes.io/managed-by": "Helm",
+		"helm.sh/chart":                "cost-analyzer-1.80.0",
+	}
+
+	labels, values := KubeLabelsToLabels(kubeLabels)
+
+	err := checkSlice(labels, []string{
+		"label_app_kubernetes_io_managed_by",
+		"label_app_kubernetes_io_name",
+		"label_helm_sh_chart",
+	})
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	err = checkSlice(values, []string{
+		"Helm",
+		"cost-analyzer",
+		"cost-analyzer-1.80.0",
+	})
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+}
+
+func TestKubePrependQualifierToLabelsCollisions(t *testing.T) {
+	kubeLabels := map[string]string{
+		"app.kubernetes.io/name": "first",
+		"app_kubernetes_io_name": "second",
+		"app-kubernetes-io-name": "third",
+		"team":                   "cost",
+	}
+
+	initLabelMetrics()
+	before := testutil.ToFloat64(labelCollisionsCv.WithLabelValues("collision"))
+
+	labels, values := KubePrependQualifierToLabels(kubeLabels, "collision_")
+
+	// app_kubernetes_io_name sorts last, so its value is kept
+	err := checkSlice(labels, []string{
+		"collision_app_kubernetes_io_name",
+		"collision_team",
+	})
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	err = checkSlice(values, []string{
+		"second",
+		"cost",
+	})
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	collisions := testutil.ToFloat64(labelCollisionsCv.WithLabelValues("collision")) - before
+	if collisions != 2 {
+		t.Fatalf("collisions: exp (%d); act (%f)", 2, collisions)
+	}
+}
+
+func TestKubeAnnotationsToLabelsDuplicatesScrape(t *testing.T) {
+	annotations := map[string]string{
+		"kubecost.com/owner": "first",
+		"kubecost_com/owner": "second",
+		"kubecost.com_owner": "third",
+	}
+
+	// kubecost_com/owner sorts last, so its value is kept
+	labels, values := KubeAnnotationsToLabels(annotations)
+	if len(labels) != 1 || values[0] != "second" {
+		t.Fatalf("Expected a single annotation_kubecost_com_owner label with the last value, got %v=%v", labels, values)
+	}
+
+	// a metric with duplicate label names fails to register and gather, failing the whole scrape
+	gauge := promclient.NewGaugeVec(promclient.GaugeOpts{
+		Name: "kube_test_annotations",
+		Help: "kube_test_annotations Test annotations",
+	}, labels)
+
+	registry := promclient.NewRegistry()
+	if err := registry.Register(gauge); err != nil {
+		t.Fatalf("Unexpected error registering metric: %s", err)
+	}
+	gauge.WithLabelValues(values...).Set(1)
+
+	if _, err := registry.Gather(); err != nil {
+		t.Fatalf("Unexpected error gathering metrics: %s", err)
+	}
+}
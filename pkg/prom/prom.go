@@ -276,8 +276,12 @@ func LogQueryRequest(l *golog.Logger, req *http.Request, queueTime time.Duration
 	if l == nil {
 		return
 	}
-	qp := httputil.NewQueryParams(req.URL.Query())
-	query := qp.Get("query", "<Unknown>")
+	// query parameters are sent in the request body, so prefer the query set on the request context
+	query, ok := httputil.GetQuery(req)
+	if !ok {
+		qp := httputil.NewQueryParams(req.URL.Query())
+		query = qp.Get("query", "<Unknown>")
+	}
 
 	l.Printf("[Queue: %fs, Outbound: %fs][Query: %s]\n", queueTime.Seconds(), sendTime.Seconds(), query)
 }
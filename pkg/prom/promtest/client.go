@@ -0,0 +1,207 @@
+// Package promtest provides a fake prometheus.Client which returns canned responses, allowing
+// code built on the prom package to be tested without a running Prometheus server.
+package promtest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// Response is a canned response returned by Client. If Err is set, it is returned from Do along
+// with the response, which mirrors a transport error. Warnings are returned from Do as-is, and
+// should also be included in the Body (see VectorBody and MatrixBody) for the prom package to
+// report them.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Warnings   prometheus.Warnings
+	Err        error
+}
+
+// Handler returns the response for a request, or nil to use the canned responses. A Handler may
+// block, ie: until ctx is done, to simulate a slow or unresponsive server, and must be safe to call
+// concurrently.
+type Handler func(ctx context.Context, req *http.Request) *Response
+
+// BodyHandler returns a Handler which responds successfully with the body returned by f, ie: to
+// echo the query of each request.
+func BodyHandler(f func(req *http.Request) []byte) Handler {
+	return func(ctx context.Context, req *http.Request) *Response {
+		return &Response{StatusCode: http.StatusOK, Body: f(req)}
+	}
+}
+
+// Client is a fake prometheus.Client which returns canned responses keyed by the query parameter
+// of each request. Requests without a query parameter, ie: status endpoints, are keyed by the URL
+// path. Requests which have no matching response receive the default response, which is an empty
+// vector unless set using SetDefault. If a Handler is set, it takes precedence over the canned
+// responses. All requests are recorded, and are safe to inspect after
+// the queries using them have completed.
+type Client struct {
+	lock      sync.Mutex
+	address   *url.URL
+	responses map[string]*Response
+	fallback  *Response
+	handler   Handler
+	requests  []*http.Request
+}
+
+// NewClient creates a new fake Client with no canned responses
+func NewClient() *Client {
+	return &Client{
+		address:   &url.URL{Scheme: "http", Host: "prometheus:9090"},
+		responses: make(map[string]*Response),
+		fallback:  &Response{StatusCode: http.StatusOK, Body: VectorBody(nil)},
+	}
+}
+
+// SetResponse sets the response returned for requests with the query, or URL path for requests
+// without a query. Returns the Client to allow chaining.
+func (c *Client) SetResponse(query string, resp *Response) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.responses[query] = resp
+	return c
+}
+
+// SetBody sets a successful response with the body for requests with the query. Returns the Client
+// to allow chaining.
+func (c *Client) SetBody(query string, body []byte) *Client {
+	return c.SetResponse(query, &Response{StatusCode: http.StatusOK, Body: body})
+}
+
+// SetDefault sets the response returned for requests which have no matching response. Returns the
+// Client to allow chaining.
+func (c *Client) SetDefault(resp *Response) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.fallback = resp
+	return c
+}
+
+// SetHandler sets the Handler called for every request, the non-nil responses of which are returned
+// instead of the canned responses. Returns the Client to allow chaining.
+func (c *Client) SetHandler(handler Handler) *Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.handler = handler
+	return c
+}
+
+// Requests returns the requests received by the client, in the order they were received
+func (c *Client) Requests() []*http.Request {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	requests := make([]*http.Request, len(c.requests))
+	copy(requests, c.requests)
+	return requests
+}
+
+// Queries returns the query parameter of each request received by the client, in the order they
+// were received. Requests without a query are omitted.
+func (c *Client) Queries() []string {
+	var queries []string
+	for _, req := range c.Requests() {
+		if query := RequestParams(req).Get("query"); query != "" {
+			queries = append(queries, query)
+		}
+	}
+	return queries
+}
+
+// URL returns the URL of the endpoint on the fake server, ie: http://prometheus:9090/api/v1/query
+func (c *Client) URL(ep string, args map[string]string) *url.URL {
+	p := ep
+	for arg, val := range args {
+		p = strings.Replace(p, ":"+arg, val, -1)
+	}
+
+	u := *c.address
+	u.Path = p
+	return &u
+}
+
+// Do records the request and returns the matching canned response
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, []byte, prometheus.Warnings, error) {
+	key := RequestParams(req).Get("query")
+	if key == "" {
+		key = req.URL.Path
+	}
+
+	c.lock.Lock()
+	c.requests = append(c.requests, req)
+	handler := c.handler
+	resp, ok := c.responses[key]
+	if !ok {
+		resp = c.fallback
+	}
+	c.lock.Unlock()
+
+	if handler != nil {
+		if handled := handler(ctx, req); handled != nil {
+			return c.response(req, handled)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return c.response(req, resp)
+}
+
+// response creates the http.Response and body returned by Do for the canned response
+func (c *Client) response(req *http.Request, resp *Response) (*http.Response, []byte, prometheus.Warnings, error) {
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	header := resp.Header
+	if header == nil {
+		header = http.Header{}
+	}
+
+	httpResp := &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     header,
+		Request:    req,
+	}
+
+	return httpResp, resp.Body, resp.Warnings, resp.Err
+}
+
+// RequestParams returns the query string and form encoded body parameters of the request. The
+// body is read using GetBody, so the request body is left unread.
+func RequestParams(req *http.Request) url.Values {
+	params := req.URL.Query()
+	if req.GetBody == nil {
+		return params
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return params
+	}
+	defer body.Close()
+
+	b, _ := ioutil.ReadAll(body)
+	form, _ := url.ParseQuery(string(b))
+	for k, v := range form {
+		params[k] = append(params[k], v...)
+	}
+
+	return params
+}
@@ -0,0 +1,125 @@
+package promtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom"
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestClientVector(t *testing.T) {
+	ts := time.Unix(1622505600, 0)
+	client := promtest.NewClient().SetBody("up", promtest.VectorBody([]promtest.Series{
+		{Metric: map[string]string{"job": "kubecost"}, Points: []promtest.Point{{Timestamp: ts, Value: 1}}},
+	}))
+
+	results, _, err := prom.NewContext(client).QuerySync("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Results: exp (1); act (%d)", len(results))
+	}
+	if job, _ := results[0].GetLabel("job"); job != "kubecost" {
+		t.Fatalf("Label: exp (kubecost); act (%s)", job)
+	}
+	if v := results[0].Values[0]; v.Timestamp != 1622505600 || v.Value != 1 {
+		t.Fatalf("Value: exp (1622505600, 1); act (%f, %f)", v.Timestamp, v.Value)
+	}
+
+	if queries := client.Queries(); len(queries) != 1 || queries[0] != "up" {
+		t.Fatalf("Queries: exp ([up]); act (%v)", queries)
+	}
+}
+
+func TestClientMatrix(t *testing.T) {
+	start := time.Unix(1622505600, 0)
+	client := promtest.NewClient().SetBody("up", promtest.MatrixBody([]promtest.Series{
+		{
+			Metric: map[string]string{"job": "kubecost"},
+			Points: []promtest.Point{{Timestamp: start, Value: 1}, {Timestamp: start.Add(time.Minute), Value: 0.5}},
+		},
+	}))
+
+	results := <-prom.NewContext(client).QueryRange("up", start, start.Add(time.Minute), time.Minute)
+	if results.Error != nil {
+		t.Fatalf("Unexpected error: %s", results.Error)
+	}
+
+	if len(results.Results) != 1 || len(results.Results[0].Values) != 2 {
+		t.Fatalf("Unexpected results: %+v", results.Results)
+	}
+	if v := results.Results[0].Values[1]; v.Value != 0.5 {
+		t.Fatalf("Value: exp (0.5); act (%f)", v.Value)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	client := promtest.NewClient().
+		SetResponse("bad", &promtest.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       promtest.ErrorBody("bad_data", "parse error"),
+		}).
+		SetResponse("unreachable", &promtest.Response{Err: errors.New("connection refused")}).
+		SetBody("partial", promtest.VectorBody(nil, "No StoreAPIs matched for this query"))
+
+	ctx := prom.NewContext(client)
+
+	if _, _, err := ctx.QuerySync("bad"); !prom.IsCommError(err) {
+		t.Fatalf("Expected CommError for bad request, got: %v", err)
+	}
+
+	if _, _, err := ctx.QuerySync("unreachable"); err == nil {
+		t.Fatalf("Expected error for transport failure")
+	}
+
+	if _, warnings, err := ctx.QuerySync("partial"); err == nil || len(warnings) != 1 {
+		t.Fatalf("Expected NoStoreAPI warning to be an error, got: %v (%v)", err, warnings)
+	}
+
+	if len(client.Requests()) != 3 {
+		t.Fatalf("Requests: exp (3); act (%d)", len(client.Requests()))
+	}
+}
+
+func TestClientHandler(t *testing.T) {
+	client := promtest.NewClient().
+		SetBody("canned", promtest.VectorBody([]promtest.Series{
+			{Metric: map[string]string{"job": "kubecost"}, Points: []promtest.Point{{Timestamp: time.Unix(1622505600, 0), Value: 1}}},
+		})).
+		SetHandler(func(ctx context.Context, req *http.Request) *promtest.Response {
+			switch promtest.RequestParams(req).Get("query") {
+			case "hang":
+				<-ctx.Done()
+				return &promtest.Response{Err: ctx.Err()}
+			case "echo":
+				return promtest.BodyHandler(func(req *http.Request) []byte {
+					return promtest.VectorBody(nil)
+				})(ctx, req)
+			}
+			return nil
+		})
+
+	ctx := prom.NewContext(client)
+
+	// requests the handler does not respond to receive the canned responses
+	if results, _, err := ctx.QuerySync("canned"); err != nil || len(results) != 1 {
+		t.Fatalf("Expected canned response, got: %v (%v)", results, err)
+	}
+
+	if results, _, err := ctx.QuerySync("echo"); err != nil || len(results) != 0 {
+		t.Fatalf("Expected handler response, got: %v (%v)", results, err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := ctx.QuerySyncCtx(reqCtx, "hang"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded error, got: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package promtest
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Point is a single sample of a series
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Series is a labeled series used to build response bodies. Vector bodies use the first point of
+// each series, and matrix bodies use all points.
+type Series struct {
+	Metric map[string]string
+	Points []Point
+}
+
+// successBody is the json body of a successful query response
+type successBody struct {
+	Status   string   `json:"status"`
+	Data     dataBody `json:"data"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type dataBody struct {
+	ResultType string        `json:"resultType"`
+	Result     []interface{} `json:"result"`
+}
+
+type vectorSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+type matrixSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][]interface{}   `json:"values"`
+}
+
+// errorBody is the json body of a failed query response
+type errorBody struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+}
+
+// VectorBody returns the json body of a successful instant query returning a vector of the series.
+// Series without points are omitted. Any warnings are included in the body.
+func VectorBody(series []Series, warnings ...string) []byte {
+	result := []interface{}{}
+	for _, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+
+		result = append(result, vectorSeries{
+			Metric: metricOf(s),
+			Value:  pointOf(s.Points[0]),
+		})
+	}
+
+	return marshalBody(successBody{
+		Status:   "success",
+		Data:     dataBody{ResultType: "vector", Result: result},
+		Warnings: warnings,
+	})
+}
+
+// MatrixBody returns the json body of a successful range query returning a matrix of the series.
+// Any warnings are included in the body.
+func MatrixBody(series []Series, warnings ...string) []byte {
+	result := []interface{}{}
+	for _, s := range series {
+		values := make([][]interface{}, 0, len(s.Points))
+		for _, p := range s.Points {
+			values = append(values, pointOf(p))
+		}
+
+		result = append(result, matrixSeries{
+			Metric: metricOf(s),
+			Values: values,
+		})
+	}
+
+	return marshalBody(successBody{
+		Status:   "success",
+		Data:     dataBody{ResultType: "matrix", Result: result},
+		Warnings: warnings,
+	})
+}
+
+// ErrorBody returns the json body of a failed query, ie: ErrorBody("bad_data", "parse error")
+func ErrorBody(errorType, message string) []byte {
+	return marshalBody(errorBody{
+		Status:    "error",
+		ErrorType: errorType,
+		Error:     message,
+	})
+}
+
+// metricOf returns the non-nil metric labels of the series
+func metricOf(s Series) map[string]string {
+	if s.Metric == nil {
+		return map[string]string{}
+	}
+	return s.Metric
+}
+
+// pointOf returns the point in the [timestamp, "value"] form of the query API
+func pointOf(p Point) []interface{} {
+	ts := float64(p.Timestamp.UnixNano()) / float64(time.Second)
+	return []interface{}{ts, strconv.FormatFloat(p.Value, 'f', -1, 64)}
+}
+
+// marshalBody marshals the body, which cannot fail for the body types of this package
+func marshalBody(body interface{}) []byte {
+	b, err := json.Marshal(body)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+package prom
+
+import (
+	"math"
+	"mime"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protobufContentType is the media type of protobuf encoded query responses
+const protobufContentType = "application/vnd.google.protobuf"
+
+// acceptProtobuf is the Accept header sent when protobuf responses are enabled. JSON is accepted
+// with a lower preference, so servers without protobuf support fall back to JSON.
+const acceptProtobuf = protobufContentType + ";proto=prometheus.QueryResult, application/json;q=0.5"
+
+// WithProtobufResponses enables negotiating protobuf encoded query and query_range responses, which
+// decode significantly faster than JSON. Servers which do not support protobuf for the query API
+// respond with JSON, which is decoded as usual. Protobuf responses are expected to be encoded as a
+// prompb.QueryResult message, as used by the remote read protocol. Disabled by default, as most
+// Prometheus builds do not expose protobuf for the query API. The raw query methods (RawQuery and
+// RawQueryRange) always request JSON. Returns the Context to allow chaining.
+func (ctx *Context) WithProtobufResponses(enabled bool) *Context {
+	ctx.protobufResponses = enabled
+	return ctx
+}
+
+// acceptHeader returns the Accept header to send for query and query_range requests, or empty to
+// leave the header unset.
+func (ctx *Context) acceptHeader() string {
+	if ctx.protobufResponses {
+		return acceptProtobuf
+	}
+
+	return ""
+}
+
+// isProtobufContentType returns true if the Content-Type is the protobuf media type
+func isProtobufContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == protobufContentType
+}
+
+// decodeProtobufResponse decodes a protobuf encoded prompb.QueryResult into QueryResults. Sample
+// timestamps are rounded to the nearest 10 seconds and NaN or Inf values are decoded according to
+// the mode, which matches the JSON decoding of query responses.
+func decodeProtobufResponse(query string, b []byte, mode SpecialValueMode) (*QueryResults, error) {
+	qrs := &QueryResults{Query: query}
+
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		// QueryResult.timeseries
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+
+		result, err := decodeTimeSeries(value, mode)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range result.Values {
+			v.Timestamp = math.Round(v.Timestamp/10) * 10
+		}
+
+		qrs.Results = append(qrs.Results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if qrs.Results == nil {
+		qrs.Results = []*QueryResult{}
+	}
+
+	return qrs, nil
+}
@@ -1,27 +1,43 @@
 package prom
 
 import (
+	"bytes"
 	"context"
+	gojson "encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/kubecost/cost-model/pkg/env"
 	"github.com/kubecost/cost-model/pkg/errors"
 	"github.com/kubecost/cost-model/pkg/log"
 	"github.com/kubecost/cost-model/pkg/util/httputil"
-	"github.com/kubecost/cost-model/pkg/util/json"
 	prometheus "github.com/prometheus/client_golang/api"
+	"github.com/prometheus/common/model"
 )
 
 const (
+	// apiPrefix is the default prefix of API endpoints, which can be changed per Context using
+	// WithAPIPrefix
 	apiPrefix    = "/api/v1"
 	epQuery      = apiPrefix + "/query"
 	epQueryRange = apiPrefix + "/query_range"
 )
 
+// DefaultMaxRangePoints is the default maximum number of points per series a range query may
+// request, which matches the limit enforced by the Prometheus query_range API.
+const DefaultMaxRangePoints = 11000
+
+// DefaultReceiveTimeout is the default time the goroutine executing a query waits for the receiver
+// of its QueryResultsChan, after which the results are discarded, see WithReceiveTimeout. It's
+// generous, so only abandoned channels are affected.
+const DefaultReceiveTimeout = 10 * time.Minute
+
 // prometheus query offset to apply to each non-range query
 // package scope to prevent calling duration parse each use
 var promQueryOffset time.Duration = env.GetPrometheusQueryOffset()
@@ -32,16 +48,106 @@ type Context struct {
 	Client         prometheus.Client
 	name           string
 	errorCollector *QueryErrorCollector
+	maxRangePoints int
+	widenRangeStep bool
+	remoteRead     bool
+
+	queryStats            bool
+	statsSamplesThreshold int64
+
+	userAgent   string
+	queryMethod string
+
+	protobufResponses bool
+	retainRawBody     bool
+
+	// ignoreOffset disables promQueryOffset for instant queries, see QueryNoOffset
+	ignoreOffset bool
+
+	retryAttempts uint
+	retryDelay    time.Duration
+
+	defaultResultLabels map[string]string
+
+	sumDuplicateKeys bool
+
+	logger Logger
+
+	profileAll bool
+
+	maxResponseBytes int64
+
+	queryDedup *dedupGroup
+
+	unixTimestamps bool
+
+	// clock is used in place of time.Now to determine the evaluation time of queries, which allows
+	// tests to pin the time. Unset uses time.Now.
+	clock func() time.Time
+
+	requestIDs      bool
+	requestIDHeader string
+	requestID       string
+
+	validateQueries bool
+
+	// queryComment is the purpose included in the comment prepended to queries, see WithQueryComment
+	queryComment string
+
+	resultBuffer int
+
+	// receiveTimeout bounds the wait for the receiver of query results, see WithReceiveTimeout
+	receiveTimeout time.Duration
+
+	responseTransformers []ResponseTransformer
+
+	scheduler *queryScheduler
+	priority  QueryPriority
+
+	latencies *latencyTracker
+
+	timeoutMultiplier float64
+	timeoutFloor      time.Duration
+	timeoutCeiling    time.Duration
+
+	// serverTimeout is sent as the timeout parameter of requests, see WithServerTimeout
+	serverTimeout time.Duration
+
+	// specialValues determines how NaN and Inf sample values are decoded, see WithSpecialValues
+	specialValues SpecialValueMode
+
+	// durationStep sends the step of range queries as a duration string, see WithDurationStep
+	durationStep bool
+
+	// apiPrefix replaces the default apiPrefix of API endpoints, see WithAPIPrefix
+	apiPrefix string
+
+	// lifetime is the parent of all requests made by runQuery and runQueryRange, which is
+	// cancelled by Close
+	lifetime context.Context
+	cancel   context.CancelFunc
 }
 
 // NewContext creates a new Promethues querying context from the given client
 func NewContext(client prometheus.Client) *Context {
 	var ec QueryErrorCollector
+	lifetime, cancel := context.WithCancel(context.Background())
 
 	return &Context{
-		Client:         client,
-		name:           "",
-		errorCollector: &ec,
+		Client:           client,
+		name:             "",
+		errorCollector:   &ec,
+		maxRangePoints:   DefaultMaxRangePoints,
+		widenRangeStep:   false,
+		remoteRead:       false,
+		queryStats:       false,
+		userAgent:        DefaultUserAgent(),
+		queryMethod:      http.MethodPost,
+		maxResponseBytes: DefaultMaxResponseBytes,
+		latencies:        newLatencyTracker(DefaultMaxTrackedQueries),
+		receiveTimeout:   DefaultReceiveTimeout,
+		lifetime:         lifetime,
+		cancel:           cancel,
 	}
 }
 
@@ -52,6 +158,278 @@ func NewNamedContext(client prometheus.Client, name string) *Context {
 	return ctx
 }
 
+// WithName returns a copy of the Context with the provided name. The copy shares the client and
+// all options of the original, but has its own error collector, so errors and warnings reported by
+// queries on either Context are not visible to the other.
+func (ctx *Context) WithName(name string) *Context {
+	var ec QueryErrorCollector
+
+	clone := *ctx
+	clone.name = name
+	clone.errorCollector = &ec
+	clone.lifetime, clone.cancel = context.WithCancel(ctx.baseContext())
+	if ctx.queryDedup != nil {
+		clone.queryDedup = newDedupGroup()
+	}
+	return &clone
+}
+
+// Close cancels all in-flight queries launched by the Context, which return a QueryCancelledErr
+// to readers. A closed Context cannot launch new queries; any further queries immediately return a
+// QueryCancelledErr. Closing a Context also closes the copies made from it with WithName, but
+// closing a copy does not close the original. Close is safe to call multiple times.
+func (ctx *Context) Close() {
+	if ctx.cancel != nil {
+		ctx.cancel()
+	}
+}
+
+// IsClosed returns true if the Context has been closed
+func (ctx *Context) IsClosed() bool {
+	return ctx.baseContext().Err() != nil
+}
+
+// baseContext returns the parent context of asynchronous queries
+func (ctx *Context) baseContext() context.Context {
+	if ctx.lifetime == nil {
+		return context.Background()
+	}
+	return ctx.lifetime
+}
+
+// runCancellable runs the query function, unless the Context is closed. Errors from queries which
+// fail because the Context was closed are replaced with a QueryCancelledErr.
+func (ctx *Context) runCancellable(reqCtx context.Context, query string, f func(context.Context) (*QueryResults, prometheus.Warnings, error)) (*QueryResults, prometheus.Warnings, error) {
+	if ctx.IsClosed() {
+		return nil, nil, QueryCancelledErr(query)
+	}
+
+	results, warnings, err := ctx.retryQuery(reqCtx, f)
+	if err != nil && ctx.IsClosed() {
+		return nil, warnings, QueryCancelledErr(query)
+	}
+
+	return results, warnings, err
+}
+
+// querySync runs the instant query with reqCtx through runCancellable, so synchronous queries are
+// retried and cancelled by Close like asynchronous queries.
+func (ctx *Context) querySync(reqCtx context.Context, query string) (*QueryResults, prometheus.Warnings, error) {
+	return ctx.runCancellable(reqCtx, query, func(reqCtx context.Context) (*QueryResults, prometheus.Warnings, error) {
+		return ctx.query(reqCtx, query)
+	})
+}
+
+// queryRangeSync runs the range query with reqCtx through runCancellable, so synchronous queries
+// are retried and cancelled by Close like asynchronous queries.
+func (ctx *Context) queryRangeSync(reqCtx context.Context, query string, start, end time.Time, step time.Duration) (*QueryResults, prometheus.Warnings, error) {
+	return ctx.runCancellable(reqCtx, query, func(reqCtx context.Context) (*QueryResults, prometheus.Warnings, error) {
+		return ctx.queryRange(reqCtx, query, start, end, step)
+	})
+}
+
+// withLifetime returns a copy of reqCtx which is also cancelled once the Context is closed. The
+// returned cancel func must be called to release the copy.
+func (ctx *Context) withLifetime(reqCtx context.Context) (context.Context, context.CancelFunc) {
+	reqCtx, cancel := context.WithCancel(reqCtx)
+
+	go func() {
+		select {
+		case <-ctx.baseContext().Done():
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+
+	return reqCtx, cancel
+}
+
+// WithResultBuffer sets the buffer size of the QueryResultsChan returned by each query, which is
+// unbuffered by default. With a buffer, the goroutine executing a query hands off its results and
+// exits without waiting for the receiver, so a busy receiver does not hold up the query goroutines.
+// Each query still has its own channel, so the results of QueryAll remain matched to their
+// channels in the order the queries were provided, regardless of the order they complete in. As
+// each channel receives a single result, a buffer larger than 1 has no further effect. A value
+// <= 0 is unbuffered. Returns the Context to allow chaining.
+func (ctx *Context) WithResultBuffer(n int) *Context {
+	if n < 0 {
+		n = 0
+	}
+
+	ctx.resultBuffer = n
+	return ctx
+}
+
+// WithReceiveTimeout bounds how long the goroutine executing a query waits for the receiver of its
+// QueryResultsChan once the query completes. If the results are not read within the timeout, ie:
+// the caller abandoned the channel after timing out itself, the results are discarded with a
+// warning, the channel is closed, and the goroutine exits rather than blocking. Regardless of the
+// timeout, results which have not been read when the Context is closed are discarded in the same
+// way. Reading a channel the results of which were discarded returns a ResultsDiscardedErr. The
+// default is DefaultReceiveTimeout. A value <= 0 waits until the Context is closed, so the goroutine
+// of an abandoned channel is only released by Close. Returns the Context to allow chaining.
+func (ctx *Context) WithReceiveTimeout(timeout time.Duration) *Context {
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	ctx.receiveTimeout = timeout
+	return ctx
+}
+
+// WithAPIPrefix sets the prefix of the API endpoints used by the Context, ie: /prometheus/api/v1 for
+// a Prometheus mounted under a subpath by a path-based reverse proxy. The prefix replaces the
+// default of /api/v1 for query, query_range, and the other /api/v1 endpoints. Management endpoints,
+// ie: /-/ready and /federate, are not affected. An empty prefix uses the default. Returns the
+// Context to allow chaining.
+func (ctx *Context) WithAPIPrefix(prefix string) *Context {
+	ctx.apiPrefix = strings.TrimSuffix(prefix, "/")
+	return ctx
+}
+
+// endpoint returns the path of the endpoint using the API prefix of the Context
+func (ctx *Context) endpoint(ep string) string {
+	if ctx.apiPrefix == "" || !strings.HasPrefix(ep, apiPrefix+"/") {
+		return ep
+	}
+
+	return ctx.apiPrefix + strings.TrimPrefix(ep, apiPrefix)
+}
+
+// newResultsChan creates a QueryResultsChan using the configured result buffer
+func (ctx *Context) newResultsChan() QueryResultsChan {
+	return make(QueryResultsChan, ctx.resultBuffer)
+}
+
+// sendResults sends the results of a query to its channel, unless the Context is closed or the
+// receive timeout elapses before the receiver reads them, in which case the results are discarded,
+// so the goroutine executing the query does not leak. The channel is closed either way, so late
+// readers do not block. sendResults is the only owner of the close, so receivers never close the
+// channel. See WithReceiveTimeout.
+func (ctx *Context) sendResults(resCh QueryResultsChan, results *QueryResults) {
+	defer close(resCh)
+
+	// prefer a waiting receiver over discarding the results of a closed Context
+	select {
+	case resCh <- results:
+		return
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if ctx.receiveTimeout > 0 {
+		timer := time.NewTimer(ctx.receiveTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case resCh <- results:
+		return
+	case <-ctx.baseContext().Done():
+		ctx.getLogger().Warningf("discarding results of query '%s': not received before the context was closed", results.Query)
+	case <-timeout:
+		ctx.getLogger().Warningf("discarding results of query '%s': not received within %s", results.Query, ctx.receiveTimeout)
+	}
+}
+
+// WithMaxRangePoints sets the maximum number of points per series a range query may request,
+// computed as (end - start) / step. If widenStep is true, range queries exceeding the maximum
+// have their step widened to fit. Otherwise, an error is returned without sending the request.
+// A maxPoints value <= 0 disables the check. Returns the Context to allow chaining.
+func (ctx *Context) WithMaxRangePoints(maxPoints int, widenStep bool) *Context {
+	ctx.maxRangePoints = maxPoints
+	ctx.widenRangeStep = widenStep
+	return ctx
+}
+
+// WithUserAgent sets the User-Agent header sent on each request, which allows Kubecost traffic to be
+// identified in Prometheus access logs. An empty user agent uses the default of the http client.
+// Returns the Context to allow chaining.
+func (ctx *Context) WithUserAgent(userAgent string) *Context {
+	ctx.userAgent = userAgent
+	return ctx
+}
+
+// DefaultUserAgent returns the default User-Agent sent on prometheus requests,
+// ie: kubecost-cost-model/1.89.0
+func DefaultUserAgent() string {
+	return "kubecost-cost-model/" + env.GetAppVersion()
+}
+
+// setUserAgent sets the User-Agent header on the request if configured
+func (ctx *Context) setUserAgent(req *http.Request) {
+	if ctx.userAgent != "" {
+		req.Header.Set("User-Agent", ctx.userAgent)
+	}
+}
+
+// WithQueryMethod sets the HTTP method used for query and query_range requests. POST requests (the
+// default) send the parameters as a form encoded body, which avoids the URL length limits of proxies
+// for large queries. GET requests send the parameters in the query string. Returns the Context to
+// allow chaining.
+func (ctx *Context) WithQueryMethod(method string) *Context {
+	ctx.queryMethod = method
+	return ctx
+}
+
+// WithRawBody retains a copy of the response body of each query on QueryResults.RawBody, which is
+// intended for diagnostics only, as it roughly doubles the memory held by each result. Returns the
+// Context to allow chaining.
+func (ctx *Context) WithRawBody() *Context {
+	ctx.retainRawBody = true
+	return ctx
+}
+
+// WithDefaultResultLabels sets labels which are added to the metric of each result returned by the
+// Context when not already present, ie: a cluster_id label to attribute results when merging the
+// results of multiple clusters. Existing labels are never overwritten. Returns the Context to allow
+// chaining.
+func (ctx *Context) WithDefaultResultLabels(labels map[string]string) *Context {
+	ctx.defaultResultLabels = make(map[string]string, len(labels))
+	for name, value := range labels {
+		ctx.defaultResultLabels[name] = value
+	}
+	return ctx
+}
+
+// setDefaultResultLabels adds the default result labels to each result which does not have the label
+func (ctx *Context) setDefaultResultLabels(results []*QueryResult) {
+	if len(ctx.defaultResultLabels) == 0 {
+		return
+	}
+
+	for _, result := range results {
+		if result.Metric == nil {
+			result.Metric = make(map[string]interface{}, len(ctx.defaultResultLabels))
+		}
+
+		for name, value := range ctx.defaultResultLabels {
+			if _, ok := result.Metric[name]; !ok {
+				result.Metric[name] = value
+			}
+		}
+	}
+}
+
+// newQueryRequest creates the request for the query endpoint using the configured query method
+func (ctx *Context) newQueryRequest(ep string, params url.Values) (*http.Request, error) {
+	u := ctx.Client.URL(ctx.endpoint(ep), nil)
+
+	if ctx.queryMethod == http.MethodGet {
+		u.RawQuery = params.Encode()
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
 // Warnings returns the warnings collected from the Context's ErrorCollector
 func (ctx *Context) Warnings() []*QueryWarning {
 	return ctx.errorCollector.Warnings()
@@ -72,6 +450,12 @@ func (ctx *Context) HasErrors() bool {
 	return ctx.errorCollector.IsError()
 }
 
+// FailedQueries returns the distinct queries which have failed, in the order they first failed.
+// The full list of errors remains available using Errors.
+func (ctx *Context) FailedQueries() []string {
+	return ctx.errorCollector.FailedQueries()
+}
+
 // ErrorCollection returns the aggregation of errors if there exists errors. Otherwise,
 // nil is returned
 func (ctx *Context) ErrorCollection() error {
@@ -84,21 +468,37 @@ func (ctx *Context) ErrorCollection() error {
 }
 
 // Query returns a QueryResultsChan, then runs the given query and sends the
-// results on the provided channel. Receiver is responsible for closing the
-// channel, preferably using the Read method.
+// results on the provided channel, which is closed once the results are sent or discarded. Read
+// the results using Await.
 func (ctx *Context) Query(query string) QueryResultsChan {
-	resCh := make(QueryResultsChan)
+	resCh := ctx.newResultsChan()
 
 	go runQuery(query, ctx, resCh, "")
 
 	return resCh
 }
 
+// QueryNoOffset returns a QueryResultsChan, then runs the given query evaluated at the current time,
+// ignoring the configured query offset, and sends the results on the provided channel. This is
+// used for queries which require the latest data, ie: liveness checks, when the offset would
+// otherwise apply. The channel is closed once the results are sent or discarded. Read the results
+// using Await.
+func (ctx *Context) QueryNoOffset(query string) QueryResultsChan {
+	resCh := ctx.newResultsChan()
+
+	// the copy shares the error collector, so errors are reported to this context
+	noOffset := *ctx
+	noOffset.ignoreOffset = true
+	go runQuery(query, &noOffset, resCh, "")
+
+	return resCh
+}
+
 // ProfileQuery returns a QueryResultsChan, then runs the given query with a profile
-// label and sends the results on the provided channel. Receiver is responsible for closing the
-// channel, preferably using the Read method.
+// label and sends the results on the provided channel, which is closed once the results are sent
+// or discarded. Read the results using Await.
 func (ctx *Context) ProfileQuery(query string, profileLabel string) QueryResultsChan {
-	resCh := make(QueryResultsChan)
+	resCh := ctx.newResultsChan()
 
 	go runQuery(query, ctx, resCh, profileLabel)
 
@@ -133,13 +533,16 @@ func (ctx *Context) ProfileQueryAll(queries ...string) []QueryResultsChan {
 	return resChs
 }
 
+// QuerySync runs the query and blocks until the results are returned. Empty results with a nil
+// error mean no series matched the query, which does not distinguish a metric with no matching
+// series from a metric which does not exist. Use QuerySyncMetric to differentiate the two. The
+// query is retried and cancelled with the Context, like any other query.
 func (ctx *Context) QuerySync(query string) ([]*QueryResult, prometheus.Warnings, error) {
-	raw, warnings, err := ctx.query(query)
+	results, warnings, err := ctx.querySync(ctx.baseContext(), query)
 	if err != nil {
 		return nil, warnings, err
 	}
 
-	results := NewQueryResults(query, raw)
 	if results.Error != nil {
 		return nil, warnings, results.Error
 	}
@@ -147,9 +550,144 @@ func (ctx *Context) QuerySync(query string) ([]*QueryResult, prometheus.Warnings
 	return results.Results, warnings, nil
 }
 
+// QuerySyncCtx runs the query and blocks until the results are returned, like QuerySync, or until
+// reqCtx is done, in which case reqCtx.Err() is returned promptly, even if the server has yet to
+// respond. The request is made with reqCtx, so cancelling it also cancels the request in flight.
+// Closing the Context also cancels the request, which returns a QueryCancelledErr.
+func (ctx *Context) QuerySyncCtx(reqCtx context.Context, query string) ([]*QueryResult, prometheus.Warnings, error) {
+	type syncResult struct {
+		results  *QueryResults
+		warnings prometheus.Warnings
+		err      error
+	}
+
+	queryCtx, cancel := ctx.withLifetime(reqCtx)
+
+	// buffered so the query goroutine does not leak when the caller returns early
+	resCh := make(chan syncResult, 1)
+	go func() {
+		defer errors.HandlePanic()
+		defer cancel()
+
+		results, warnings, err := ctx.querySync(queryCtx, query)
+		resCh <- syncResult{results: results, warnings: warnings, err: err}
+	}()
+
+	select {
+	case <-reqCtx.Done():
+		return nil, nil, reqCtx.Err()
+	case res := <-resCh:
+		if res.err != nil {
+			if reqCtx.Err() != nil {
+				return nil, res.warnings, reqCtx.Err()
+			}
+			return nil, res.warnings, res.err
+		}
+
+		if res.results.Error != nil {
+			return nil, res.warnings, res.results.Error
+		}
+
+		return res.results.Results, res.warnings, nil
+	}
+}
+
+// QuerySyncPartial runs the query and blocks until the results are returned, like QuerySync, but
+// does not discard the results when some of the returned series are malformed. Malformed series
+// are skipped, the series which parsed are returned, and partial is set to true alongside the
+// first parse error. Request failures return nil results, partial set to false, and the error.
+// Callers which can tolerate missing series (ie: dashboards) may use the results when partial is
+// true, while QuerySync remains strict and returns no results for the same response.
+func (ctx *Context) QuerySyncPartial(query string) (results []*QueryResult, partial bool, warnings prometheus.Warnings, err error) {
+	qrs, warnings, err := ctx.querySync(ctx.baseContext(), query)
+	if err != nil {
+		return nil, false, warnings, err
+	}
+
+	if qrs.Error != nil {
+		return qrs.Results, true, warnings, qrs.Error
+	}
+
+	return qrs.Results, false, warnings, nil
+}
+
+// QueryScalarsByLabel runs the instant query and blocks until the results are returned, returning
+// the value of each series keyed by the value of the keyLabel label, ie: the value of up for each
+// job. Series without the label are keyed under MissingLabelGroup. Series with duplicate keys return
+// an error, unless summing duplicates is enabled using WithSumDuplicateKeys. See
+// QueryResults.ScalarsByLabel.
+func (ctx *Context) QueryScalarsByLabel(query string, keyLabel string) (map[string]float64, error) {
+	results, _, err := ctx.querySync(ctx.baseContext(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	return results.ScalarsByLabel(keyLabel, ctx.sumDuplicateKeys)
+}
+
+// WithSumDuplicateKeys sets whether QueryScalarsByLabel sums the values of series with the same
+// key, rather than returning an error. Disabled by default. Returns the Context to allow chaining.
+func (ctx *Context) WithSumDuplicateKeys(enabled bool) *Context {
+	ctx.sumDuplicateKeys = enabled
+	return ctx
+}
+
+// queryAtManyConcurrency is the maximum number of concurrent requests made by QueryAtMany
+const queryAtManyConcurrency = 8
+
+// QueryAtMany runs the instant query evaluated at each of the provided times and blocks until all
+// results are returned, which is convenient for sampling a query at irregular points in time. The
+// results are indexed by the input times, ie: results[1] is the result of the query evaluated at
+// times[1]. Up to 8 queries are run concurrently. As the evaluation times are explicit, the query
+// offset is not applied. If any query fails, nil results and the error of the earliest failed
+// input time are returned.
+func (ctx *Context) QueryAtMany(query string, times []time.Time) ([][]*QueryResult, error) {
+	results := make([][]*QueryResult, len(times))
+	errs := make([]error, len(times))
+
+	sem := make(chan struct{}, queryAtManyConcurrency)
+	var wg sync.WaitGroup
+	for i, t := range times {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, t time.Time) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer errors.HandlePanic()
+
+			evalTime := ctx.formatTime(t.UTC().Truncate(time.Second), time.RFC3339)
+			qrs, _, err := ctx.runCancellable(ctx.baseContext(), query, func(reqCtx context.Context) (*QueryResults, prometheus.Warnings, error) {
+				return ctx.queryAt(reqCtx, query, evalTime)
+			})
+			if err == nil && qrs == nil {
+				err = QueryResultNilErr(query)
+			}
+			if err == nil {
+				err = qrs.Error
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			results[i] = qrs.Results
+		}(i, t)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
 // QueryURL returns the URL used to query Prometheus
 func (ctx *Context) QueryURL() *url.URL {
-	return ctx.Client.URL(epQuery, nil)
+	return ctx.Client.URL(ctx.endpoint(epQuery), nil)
 }
 
 // runQuery executes the prometheus query asynchronously, collects results and
@@ -157,40 +695,109 @@ func (ctx *Context) QueryURL() *url.URL {
 func runQuery(query string, ctx *Context, resCh QueryResultsChan, profileLabel string) {
 	defer errors.HandlePanic()
 	startQuery := time.Now()
-
-	raw, warnings, requestError := ctx.query(query)
-	results := NewQueryResults(query, raw)
+	reqCtx, span := startQuerySpan(ctx.baseContext(), "prom.Query", ctx, query)
+
+	results, warnings, requestError := ctx.runCancellable(reqCtx, query, func(reqCtx context.Context) (*QueryResults, prometheus.Warnings, error) {
+		return ctx.query(reqCtx, query)
+	})
+	if results == nil {
+		results = &QueryResults{Query: query, Error: QueryResultNilErr(query)}
+		if IsQueryCancelledError(requestError) {
+			results.Error = requestError
+		}
+	}
+	results.ContextName = ctx.name
 
 	// report all warnings, request, and parse errors (nils will be ignored)
 	ctx.errorCollector.Report(query, warnings, requestError, results.Error)
+	endQuerySpan(span, startQuery, 0, results, firstError(requestError, results.Error))
 
+	profileLabel = ctx.profileLabelFor(profileLabel, "Query")
 	if profileLabel != "" {
-		log.Profile(startQuery, profileLabel)
+		ctx.logProfile(startQuery, query, profileLabel)
 	}
 
-	resCh <- results
+	ctx.sendResults(resCh, results)
 }
 
 // RawQuery is a direct query to the prometheus client and returns the body of the response
 func (ctx *Context) RawQuery(query string) ([]byte, error) {
-	u := ctx.Client.URL(epQuery, nil)
-	q := u.Query()
-	q.Set("query", query)
+	body, _, err := ctx.rawQuery(context.Background(), query, ctx.queryTime(), "")
+	return body, err
+}
 
+// queryTime returns the evaluation time of an instant query made now, formatted for the time
+// parameter of the query.
+func (ctx *Context) queryTime() string {
 	// for non-range queries, we set the timestamp for the query to time-offset
 	// this is a special use case that's typically only used when our primary
 	// prom db has delayed insertion (thanos, cortex, etc...)
-	if promQueryOffset != 0 && ctx.name != AllocationContextName {
-		q.Set("time", time.Now().Add(-promQueryOffset).UTC().Format(time.RFC3339))
-	} else {
-		q.Set("time", time.Now().UTC().Format(time.RFC3339))
+	if promQueryOffset != 0 && ctx.name != AllocationContextName && !ctx.ignoreOffset {
+		return ctx.formatTime(ctx.now().Add(-promQueryOffset).UTC().Truncate(time.Second), time.RFC3339)
+	}
+
+	return ctx.formatTime(ctx.now().UTC().Truncate(time.Second), time.RFC3339)
+}
+
+// now returns the current time using the clock of the Context, which defaults to time.Now
+func (ctx *Context) now() time.Time {
+	if ctx.clock == nil {
+		return time.Now()
+	}
+
+	return ctx.clock()
+}
+
+// WithUnixTimestamps sets whether the time parameters of requests are sent as Unix timestamps in
+// seconds, ie: 1622505600.5, rather than RFC3339, which some older Prometheus compatible backends
+// require. Both formats are accepted by Prometheus and Thanos. Disabled by default. Returns the
+// Context to allow chaining.
+func (ctx *Context) WithUnixTimestamps(enabled bool) *Context {
+	ctx.unixTimestamps = enabled
+	return ctx
+}
+
+// formatTime formats the time for a request parameter, using the layout unless Unix timestamps are
+// enabled. Unix timestamps have millisecond precision, which is the precision of Prometheus.
+func (ctx *Context) formatTime(t time.Time, layout string) string {
+	if ctx.unixTimestamps {
+		return strconv.FormatFloat(float64(t.UnixNano()/int64(time.Millisecond))/1000, 'f', -1, 64)
 	}
 
-	u.RawQuery = q.Encode()
+	return t.Format(layout)
+}
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+// rawQuery executes the instant query evaluated at evalTime using the provided request context,
+// which carries the trace span of the caller. If accept is non-empty, it is sent as the Accept
+// header, and the Content-Type of the response is returned.
+func (ctx *Context) rawQuery(reqCtx context.Context, query string, evalTime string, accept string) (body []byte, contentType string, err error) {
+	var statusCode int
+	reqCtx, span := startQuerySpan(reqCtx, "prom.RawQuery", ctx, query)
+	defer func(start time.Time) {
+		endQuerySpan(span, start, statusCode, nil, err)
+	}(time.Now())
+
+	release, err := ctx.schedule(reqCtx, query)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	defer release()
+
+	reqCtx, cancel := ctx.withAdaptiveTimeout(reqCtx, query)
+	defer cancel()
+
+	defer ctx.trackInflight()()
+
+	q := url.Values{}
+	q.Set("query", ctx.annotateQuery(query))
+
+	q.Set("time", evalTime)
+	ctx.setStatsParam(q)
+	ctx.setTimeoutParam(q)
+
+	req, err := ctx.newQueryRequest(epQuery, q)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Set QueryContext name if non empty
@@ -198,79 +805,122 @@ func (ctx *Context) RawQuery(query string) ([]byte, error) {
 		req = httputil.SetName(req, ctx.name)
 	}
 	req = httputil.SetQuery(req, query)
+	ctx.setUserAgent(req)
+	reqCtx = ctx.setRequestID(reqCtx, req)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	injectTraceHeaders(reqCtx, req)
 
 	// Note that the warnings return value from client.Do() is always nil using this
 	// version of the prometheus client library. We parse the warnings out of the response
 	// body after json decodidng completes.
-	resp, body, _, err := ctx.Client.Do(context.Background(), req)
+	attemptStart := time.Now()
+	resp, body, _, err := ctx.Client.Do(reqCtx, req)
+	ctx.observeAttempt(reqCtx, query, attemptStart, resp, err)
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
 	if err != nil {
 		if resp == nil {
-			return nil, fmt.Errorf("query error: '%s' fetching query '%s'", err.Error(), query)
+			return nil, "", fmt.Errorf("query error: '%s' fetching query '%s'%s", err.Error(), query, requestIDSuffix(reqCtx))
 		}
 
-		return nil, fmt.Errorf("query error %d: '%s' fetching query '%s'", resp.StatusCode, err.Error(), query)
+		return nil, "", fmt.Errorf("query error %d: '%s' fetching query '%s'%s", resp.StatusCode, err.Error(), query, requestIDSuffix(reqCtx))
+	}
+
+	body, err = decodeResponseBody(resp, body, ctx.maxResponseBytes)
+	if err != nil {
+		return nil, "", withErrorRequestID(reqCtx, CommErrorf("%s, Query: %s", err, query))
+	}
+
+	// Auth failures are reported without the response, as they require fixing the credentials
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return nil, "", withErrorRequestID(reqCtx, NewAuthError(statusCode, query))
 	}
 
 	// Unsuccessful Status Code, log body and status
-	statusCode := resp.StatusCode
 	statusText := http.StatusText(statusCode)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, CommErrorf("%d (%s) URL: '%s', Request Headers: '%s', Headers: '%s', Body: '%s' Query: '%s'", statusCode, statusText, req.URL, req.Header, httputil.HeaderString(resp.Header), body, query)
+		if envelope, ok := DecodeErrorEnvelope(resp.Header.Get("Content-Type"), body); ok {
+			return nil, "", withErrorRequestID(reqCtx, NewCommEnvelopeError(statusCode, body, query, envelope))
+		}
+
+		return nil, "", withErrorRequestID(reqCtx, NewCommResponseError(statusCode, body, query, "%d (%s) URL: '%s', Request Headers: '%s', Headers: '%s', Body: '%s' Query: '%s'", statusCode, statusText, req.URL, req.Header, httputil.HeaderString(resp.Header), body, query))
 	}
 
-	return body, err
+	return body, resp.Header.Get("Content-Type"), err
 }
 
-func (ctx *Context) query(query string) (interface{}, prometheus.Warnings, error) {
-	body, err := ctx.RawQuery(query)
-	if err != nil {
+func (ctx *Context) query(reqCtx context.Context, query string) (*QueryResults, prometheus.Warnings, error) {
+	if err := ctx.validateQuery(query); err != nil {
 		return nil, nil, err
 	}
 
-	var toReturn interface{}
-	err = json.Unmarshal(body, &toReturn)
-	if err != nil {
-		return nil, nil, fmt.Errorf("Unmarshal Error: %s\nQuery: %s", err, query)
-	}
+	return ctx.queryAt(reqCtx, query, ctx.queryTime())
+}
 
-	warnings := warningsFrom(toReturn)
-	for _, w := range warnings {
-		// NoStoreAPIWarning is a warning that we would consider an error. It returns partial data relating only to the
-		// store apis which were reachable. In order to ensure integrity of data across all clusters, we'll need to identify
-		// this warning and convert it to an error.
-		if IsNoStoreAPIWarning(w) {
-			return nil, warnings, CommErrorf("Error: %s, Body: %s, Query: %s", w, body, query)
-		}
+// queryAt executes the instant query evaluated at evalTime, which must be formatted for the time
+// parameter of the query
+func (ctx *Context) queryAt(reqCtx context.Context, query string, evalTime string) (*QueryResults, prometheus.Warnings, error) {
+	reqCtx = ctx.withRequestID(reqCtx)
+	if ctx.queryDedup != nil {
+		return ctx.dedupQuery(reqCtx, query, evalTime)
+	}
 
-		log.Warningf("fetching query '%s': %s", query, w)
+	body, contentType, err := ctx.rawQuery(reqCtx, query, evalTime, ctx.acceptHeader())
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return toReturn, warnings, nil
+	return ctx.decodeQueryBody(reqCtx, query, contentType, body)
 }
 
 func (ctx *Context) QueryRange(query string, start, end time.Time, step time.Duration) QueryResultsChan {
-	resCh := make(QueryResultsChan)
+	resCh := ctx.newResultsChan()
 
 	go runQueryRange(query, start, end, step, ctx, resCh, "")
 
 	return resCh
 }
 
+// QueryRangePoints runs a range query returning approximately the requested number of points per
+// series, regardless of the length of the window. The step is computed as (end - start) / points,
+// rounded to whole seconds with a minimum of 1s.
+func (ctx *Context) QueryRangePoints(query string, start, end time.Time, points int) QueryResultsChan {
+	step, err := stepForPoints(start, end, points)
+	if err != nil {
+		resCh := ctx.newResultsChan()
+
+		go ctx.sendResults(resCh, &QueryResults{
+			Query:       query,
+			Error:       fmt.Errorf("%s, Query: %s", err, query),
+			ContextName: ctx.name,
+		})
+
+		return resCh
+	}
+
+	return ctx.QueryRange(query, start, end, step)
+}
+
 func (ctx *Context) ProfileQueryRange(query string, start, end time.Time, step time.Duration, profileLabel string) QueryResultsChan {
-	resCh := make(QueryResultsChan)
+	resCh := ctx.newResultsChan()
 
 	go runQueryRange(query, start, end, step, ctx, resCh, profileLabel)
 
 	return resCh
 }
 
+// QueryRangeSync runs the range query and blocks until the results are returned. The query is
+// retried and cancelled with the Context, like any other query.
 func (ctx *Context) QueryRangeSync(query string, start, end time.Time, step time.Duration) ([]*QueryResult, prometheus.Warnings, error) {
-	raw, warnings, err := ctx.queryRange(query, start, end, step)
+	results, warnings, err := ctx.queryRangeSync(ctx.baseContext(), query, start, end, step)
 	if err != nil {
 		return nil, warnings, err
 	}
 
-	results := NewQueryResults(query, raw)
 	if results.Error != nil {
 		return nil, warnings, results.Error
 	}
@@ -278,9 +928,69 @@ func (ctx *Context) QueryRangeSync(query string, start, end time.Time, step time
 	return results.Results, warnings, nil
 }
 
+// QueryRangeChunked splits the range query into sub-range queries spanning at most chunk, runs
+// them concurrently, then merges the results by series. Samples duplicated at the chunk
+// boundaries are removed. This is useful for long windows where a single range query would
+// time out or exceed sample limits. The chunk duration is aligned to a multiple of step. Each chunk
+// is retried and cancelled with the Context, like any other query.
+func (ctx *Context) QueryRangeChunked(query string, start, end time.Time, step, chunk time.Duration) ([]*QueryResult, prometheus.Warnings, error) {
+	if step <= 0 || chunk <= 0 || end.Sub(start) <= chunk {
+		return ctx.QueryRangeSync(query, start, end, step)
+	}
+
+	// align chunks to the step, so each chunk evaluates at the same timestamps a single
+	// range query would have
+	chunk = chunk.Truncate(step)
+	if chunk < step {
+		chunk = step
+	}
+
+	type chunkResult struct {
+		results  []*QueryResult
+		warnings prometheus.Warnings
+		err      error
+	}
+
+	var chunks []*chunkResult
+	var wg sync.WaitGroup
+
+	for s := start; s.Before(end); s = s.Add(chunk) {
+		e := s.Add(chunk)
+		if e.After(end) {
+			e = end
+		}
+
+		cr := &chunkResult{}
+		chunks = append(chunks, cr)
+
+		wg.Add(1)
+		go func(s, e time.Time, cr *chunkResult) {
+			defer wg.Done()
+			defer errors.HandlePanic()
+
+			cr.results, cr.warnings, cr.err = ctx.QueryRangeSync(query, s, e, step)
+		}(s, e, cr)
+	}
+
+	wg.Wait()
+
+	var warnings prometheus.Warnings
+	var sets [][]*QueryResult
+	for _, cr := range chunks {
+		warnings = append(warnings, cr.warnings...)
+		if cr.err != nil {
+			return nil, warnings, cr.err
+		}
+
+		sets = append(sets, cr.results)
+	}
+
+	return MergeQueryResults(sets...), warnings, nil
+}
+
 // QueryRangeURL returns the URL used to query_range Prometheus
 func (ctx *Context) QueryRangeURL() *url.URL {
-	return ctx.Client.URL(epQueryRange, nil)
+	return ctx.Client.URL(ctx.endpoint(epQueryRange), nil)
 }
 
 // runQueryRange executes the prometheus queryRange asynchronously, collects results and
@@ -288,33 +998,74 @@ func (ctx *Context) QueryRangeURL() *url.URL {
 func runQueryRange(query string, start, end time.Time, step time.Duration, ctx *Context, resCh QueryResultsChan, profileLabel string) {
 	defer errors.HandlePanic()
 	startQuery := time.Now()
-
-	raw, warnings, requestError := ctx.queryRange(query, start, end, step)
-	results := NewQueryResults(query, raw)
+	reqCtx, span := startQuerySpan(ctx.baseContext(), "prom.QueryRange", ctx, query)
+
+	results, warnings, requestError := ctx.runCancellable(reqCtx, query, func(reqCtx context.Context) (*QueryResults, prometheus.Warnings, error) {
+		return ctx.queryRange(reqCtx, query, start, end, step)
+	})
+	if results == nil {
+		results = &QueryResults{Query: query, Error: QueryResultNilErr(query)}
+		if IsQueryCancelledError(requestError) {
+			results.Error = requestError
+		}
+	}
+	results.ContextName = ctx.name
 
 	// report all warnings, request, and parse errors (nils will be ignored)
 	ctx.errorCollector.Report(query, warnings, requestError, results.Error)
+	endQuerySpan(span, startQuery, 0, results, firstError(requestError, results.Error))
 
+	profileLabel = ctx.profileLabelFor(profileLabel, "QueryRange")
 	if profileLabel != "" {
-		log.Profile(startQuery, profileLabel)
+		ctx.logProfile(startQuery, query, profileLabel)
 	}
 
-	resCh <- results
+	ctx.sendResults(resCh, results)
 }
 
-// RawQuery is a direct query to the prometheus client and returns the body of the response
+// RawQueryRange is a direct range query to the prometheus client and returns the body of the response
 func (ctx *Context) RawQueryRange(query string, start, end time.Time, step time.Duration) ([]byte, error) {
-	u := ctx.Client.URL(epQueryRange, nil)
-	q := u.Query()
-	q.Set("query", query)
-	q.Set("start", start.Format(time.RFC3339Nano))
-	q.Set("end", end.Format(time.RFC3339Nano))
-	q.Set("step", strconv.FormatFloat(step.Seconds(), 'f', 3, 64))
-	u.RawQuery = q.Encode()
-
-	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	body, _, err := ctx.rawQueryRange(context.Background(), query, start, end, step, "")
+	return body, err
+}
+
+// rawQueryRange executes the range query using the provided request context, which carries the
+// trace span of the caller. If accept is non-empty, it is sent as the Accept header, and the
+// Content-Type of the response is returned.
+func (ctx *Context) rawQueryRange(reqCtx context.Context, query string, start, end time.Time, step time.Duration, accept string) (body []byte, contentType string, err error) {
+	var statusCode int
+	reqCtx, span := startQuerySpan(reqCtx, "prom.RawQueryRange", ctx, query)
+	defer func(start time.Time) {
+		endQuerySpan(span, start, statusCode, nil, err)
+	}(time.Now())
+
+	release, err := ctx.schedule(reqCtx, query)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	defer release()
+
+	reqCtx, cancel := ctx.withAdaptiveTimeout(reqCtx, query)
+	defer cancel()
+
+	defer ctx.trackInflight()()
+
+	step, err = clampRangeStep(start, end, step, ctx.maxRangePoints, ctx.widenRangeStep)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s, Query: %s", err, query)
+	}
+
+	q := url.Values{}
+	q.Set("query", ctx.annotateQuery(query))
+	q.Set("start", ctx.formatTime(start, time.RFC3339Nano))
+	q.Set("end", ctx.formatTime(end, time.RFC3339Nano))
+	q.Set("step", ctx.formatStep(step))
+	ctx.setStatsParam(q)
+	ctx.setTimeoutParam(q)
+
+	req, err := ctx.newQueryRequest(epQueryRange, q)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Set QueryContext name if non empty
@@ -322,42 +1073,109 @@ func (ctx *Context) RawQueryRange(query string, start, end time.Time, step time.
 		req = httputil.SetName(req, ctx.name)
 	}
 	req = httputil.SetQuery(req, query)
+	ctx.setUserAgent(req)
+	reqCtx = ctx.setRequestID(reqCtx, req)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	injectTraceHeaders(reqCtx, req)
 
 	// Note that the warnings return value from client.Do() is always nil using this
 	// version of the prometheus client library. We parse the warnings out of the response
 	// body after json decodidng completes.
-	resp, body, _, err := ctx.Client.Do(context.Background(), req)
+	attemptStart := time.Now()
+	resp, body, _, err := ctx.Client.Do(reqCtx, req)
+	ctx.observeAttempt(reqCtx, query, attemptStart, resp, err)
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
 	if err != nil {
 		if resp == nil {
-			return nil, fmt.Errorf("Error: %s, Body: %s Query: %s", err.Error(), body, query)
+			return nil, "", fmt.Errorf("Error: %s, Body: %s Query: %s%s", err.Error(), body, query, requestIDSuffix(reqCtx))
 		}
 
-		return nil, fmt.Errorf("%d (%s) Headers: %s Error: %s Body: %s Query: %s", resp.StatusCode, http.StatusText(resp.StatusCode), httputil.HeaderString(resp.Header), body, err.Error(), query)
+		return nil, "", fmt.Errorf("%d (%s) Headers: %s Error: %s Body: %s Query: %s%s", resp.StatusCode, http.StatusText(resp.StatusCode), httputil.HeaderString(resp.Header), body, err.Error(), query, requestIDSuffix(reqCtx))
+	}
+
+	body, err = decodeResponseBody(resp, body, ctx.maxResponseBytes)
+	if err != nil {
+		return nil, "", withErrorRequestID(reqCtx, CommErrorf("%s, Query: %s", err, query))
+	}
+
+	// Auth failures are reported without the response, as they require fixing the credentials
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		return nil, "", withErrorRequestID(reqCtx, NewAuthError(statusCode, query))
 	}
 
 	// Unsuccessful Status Code, log body and status
-	statusCode := resp.StatusCode
 	statusText := http.StatusText(statusCode)
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, CommErrorf("%d (%s) Headers: %s, Body: %s Query: %s", statusCode, statusText, httputil.HeaderString(resp.Header), body, query)
+		if envelope, ok := DecodeErrorEnvelope(resp.Header.Get("Content-Type"), body); ok {
+			return nil, "", withErrorRequestID(reqCtx, NewCommEnvelopeError(statusCode, body, query, envelope))
+		}
+
+		return nil, "", withErrorRequestID(reqCtx, NewCommResponseError(statusCode, body, query, "%d (%s) Headers: %s, Body: %s Query: %s", statusCode, statusText, httputil.HeaderString(resp.Header), body, query))
 	}
 
-	return body, err
+	return body, resp.Header.Get("Content-Type"), err
+}
+
+// queryRange executes the range query and decodes the response into QueryResults
+func (ctx *Context) queryRange(reqCtx context.Context, query string, start, end time.Time, step time.Duration) (*QueryResults, prometheus.Warnings, error) {
+	if err := ctx.validateQuery(query); err != nil {
+		return nil, nil, err
+	}
+
+	reqCtx = ctx.withRequestID(reqCtx)
+	body, contentType, err := ctx.rawQueryRange(reqCtx, query, start, end, step, ctx.acceptHeader())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx.decodeQueryBody(reqCtx, query, contentType, body)
 }
 
-func (ctx *Context) queryRange(query string, start, end time.Time, step time.Duration) (interface{}, prometheus.Warnings, error) {
-	body, err := ctx.RawQueryRange(query, start, end, step)
+// decodeQueryBody decodes the response body directly into QueryResults using the typed response
+// structs, records the number of series returned, and logs any warnings. A NoStoreAPIWarning is
+// converted into an error. Protobuf responses are decoded using decodeProtobufResponse. The request
+// id carried by reqCtx, if any, is included in the logged warnings.
+func (ctx *Context) decodeQueryBody(reqCtx context.Context, query string, contentType string, body []byte) (*QueryResults, prometheus.Warnings, error) {
+	body, err := ctx.transformResponse(query, body)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	var toReturn interface{}
-	err = json.Unmarshal(body, &toReturn)
+	if isProtobufContentType(contentType) {
+		results, err := decodeProtobufResponse(query, body, ctx.specialValues)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unmarshal Error: %s\nQuery: %s", err, query)
+		}
+
+		ctx.recordQueryResultSeries(results)
+		ctx.checkQueryStats(results)
+		ctx.setRawBody(results, body)
+		ctx.setDefaultResultLabels(results.Results)
+		return results, nil, nil
+	}
+
+	if err := checkContentType(query, contentType, body); err != nil {
+		return nil, nil, err
+	}
+
+	results, warnings, err := decodeResponse(query, bytes.NewReader(body), ctx.specialValues)
 	if err != nil {
+		// a connection closed mid-response yields a generic unmarshal error, so distinguish
+		// truncated responses from malformed responses
+		if isTruncatedJSON(body) {
+			return nil, nil, CommErrorf("Truncated response: received %d bytes before the response ended: %s, Query: %s", len(body), err, query)
+		}
+
 		return nil, nil, fmt.Errorf("Unmarshal Error: %s\nQuery: %s", err, query)
 	}
 
-	warnings := warningsFrom(toReturn)
+	ctx.recordQueryWarnings(warnings)
+
 	for _, w := range warnings {
 		// NoStoreAPIWarning is a warning that we would consider an error. It returns partial data relating only to the
 		// store apis which were reachable. In order to ensure integrity of data across all clusters, we'll need to identify
@@ -366,23 +1184,154 @@ func (ctx *Context) queryRange(query string, start, end time.Time, step time.Dur
 			return nil, warnings, CommErrorf("Error: %s, Body: %s, Query: %s", w, body, query)
 		}
 
-		log.Warningf("fetching query '%s': %s", query, w)
+		ctx.getLogger().Warningf("fetching query '%s'%s: %s", query, requestIDSuffix(reqCtx), w)
+	}
+
+	results.Warnings = ParseWarnings(warnings)
+	ctx.recordQueryResultSeries(results)
+	ctx.checkQueryStats(results)
+	ctx.setRawBody(results, body)
+	ctx.setDefaultResultLabels(results.Results)
+
+	return results, warnings, nil
+}
+
+// WithDurationStep sets whether the step of range queries is sent as a Prometheus duration string,
+// ie: 90s, rather than as float seconds, ie: 90.000. Both are accepted by Prometheus, but the
+// duration is easier to read in logs and is required by some gateways which validate the format.
+// Steps are sent as float seconds by default. Returns the Context to allow chaining.
+func (ctx *Context) WithDurationStep(enabled bool) *Context {
+	ctx.durationStep = enabled
+	return ctx
+}
+
+// formatStep formats the step parameter of a range query
+func (ctx *Context) formatStep(step time.Duration) string {
+	if ctx.durationStep {
+		return model.Duration(step).String()
+	}
+
+	return strconv.FormatFloat(step.Seconds(), 'f', 3, 64)
+}
+
+// stepForPoints computes the whole second step which divides the window into the requested number
+// of points.
+func stepForPoints(start, end time.Time, points int) (time.Duration, error) {
+	if points <= 0 {
+		return 0, fmt.Errorf("Invalid range query points: %d, points must be positive", points)
+	}
+
+	window := end.Sub(start)
+	if window <= 0 {
+		return 0, fmt.Errorf("Invalid range query window: end %s is not after start %s", end, start)
+	}
+
+	step := (window / time.Duration(points)).Round(time.Second)
+	if step < time.Second {
+		step = time.Second
+	}
+
+	return step, nil
+}
+
+// setRawBody sets a copy of the response body on the results if enabled. The body is copied, as it
+// may be aliased to a buffer owned by the client.
+func (ctx *Context) setRawBody(results *QueryResults, body []byte) {
+	if !ctx.retainRawBody || results == nil {
+		return
+	}
+
+	results.RawBody = append([]byte(nil), body...)
+}
+
+// clampRangeStep validates the number of points per series a range query will request against
+// maxPoints, returning an error if exceeded. If widen is true, a step exceeding the maximum is
+// widened to the smallest whole second step that fits instead.
+func clampRangeStep(start, end time.Time, step time.Duration, maxPoints int, widen bool) (time.Duration, error) {
+	if step <= 0 {
+		return step, fmt.Errorf("Invalid range query step: %s, step must be positive", step)
+	}
+
+	window := end.Sub(start)
+	if maxPoints <= 0 || window <= 0 {
+		return step, nil
+	}
+
+	points := int64(window / step)
+	if points <= int64(maxPoints) {
+		return step, nil
+	}
+
+	if !widen {
+		return step, fmt.Errorf("Range query exceeds maximum resolution: %d points per series with step %s, maximum is %d", points, step, maxPoints)
 	}
 
-	return toReturn, warnings, nil
+	widened := time.Duration(math.Ceil(float64(window) / float64(maxPoints)))
+	if widened%time.Second != 0 {
+		widened = widened.Truncate(time.Second) + time.Second
+	}
+
+	log.DedupedWarningf(5, "Widening range query step from %s to %s to remain within %d points per series", step, widened, maxPoints)
+	return widened, nil
+}
+
+// firstError returns the first non-nil error provided
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Extracts the warnings from the resulting json if they exist (part of the prometheus response api).
 func warningsFrom(result interface{}) prometheus.Warnings {
+	if resultMap, ok := result.(map[string]interface{}); ok {
+		return parseWarnings(resultMap["warnings"])
+	}
+
+	return nil
+}
+
+// parseWarnings converts the decoded warnings field of a prometheus response into Warnings. Generic
+// json decoding produces []interface{} rather than []string, so both are accepted. Some servers
+// return non-string warnings, which are converted on a best-effort basis by warningString so that
+// no warning is dropped.
+func parseWarnings(warningProp interface{}) prometheus.Warnings {
 	var warnings prometheus.Warnings
 
-	if resultMap, ok := result.(map[string]interface{}); ok {
-		if warningProp, ok := resultMap["warnings"]; ok {
-			if w, ok := warningProp.([]string); ok {
-				warnings = w
+	switch w := warningProp.(type) {
+	case []string:
+		warnings = w
+	case []interface{}:
+		for _, entry := range w {
+			if entry == nil {
+				continue
 			}
+			warnings = append(warnings, warningString(entry))
 		}
+	case string:
+		warnings = prometheus.Warnings{w}
 	}
 
 	return warnings
 }
+
+// warningString converts a single decoded warning into a string. Strings are returned as-is,
+// scalars are formatted, and objects and arrays are json encoded.
+func warningString(warning interface{}) string {
+	switch w := warning.(type) {
+	case string:
+		return w
+	case map[string]interface{}, []interface{}:
+		// gojson used here, as it sorts map keys, so warnings are deterministic
+		b, err := gojson.Marshal(w)
+		if err != nil {
+			return fmt.Sprint(w)
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(w)
+	}
+}
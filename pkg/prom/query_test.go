@@ -1,6 +1,42 @@
 package prom
 
-import "testing"
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	"github.com/kubecost/cost-model/pkg/util/json"
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// gatedHandler returns a promtest.Handler which signals started for each request, and responds with
+// the body once released by a send on, or close of, release
+func gatedHandler(started chan<- struct{}, release <-chan struct{}, body []byte) promtest.Handler {
+	return func(ctx context.Context, req *http.Request) *promtest.Response {
+		started <- struct{}{}
+		<-release
+		return &promtest.Response{Body: body}
+	}
+}
+
+// hangingHandler is a promtest.Handler which never responds, returning only once the request
+// context is done
+func hangingHandler(ctx context.Context, req *http.Request) *promtest.Response {
+	<-ctx.Done()
+	return &promtest.Response{Err: ctx.Err()}
+}
 
 func TestWarningsFrom(t *testing.T) {
 	var results interface{}
@@ -25,3 +61,1405 @@ func TestWarningsFrom(t *testing.T) {
 		t.Errorf("Unexpected second warning: %s", warnings[1])
 	}
 }
+
+func TestRawQueryRangeSubqueryEncoding(t *testing.T) {
+	queries := []string{
+		`max_over_time((rate(container_cpu_usage_seconds_total{namespace="kubecost"}[5m]))[1h:1m])`,
+		`avg_over_time(up[5m:30s] @ end())`,
+		`sum(kube_pod_container_resource_requests @ 1609746000) by (namespace)`,
+	}
+
+	for _, query := range queries {
+		client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+		ctx := NewContext(client)
+
+		end := time.Now()
+		_, err := ctx.RawQueryRange(query, end.Add(-time.Hour), end, time.Minute)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		if len(client.Requests()) != 1 {
+			t.Fatalf("Expected 1 request, got %d", len(client.Requests()))
+		}
+
+		sent := promtest.RequestParams(client.Requests()[0]).Get("query")
+		if sent != query {
+			t.Errorf("Query was altered in transit. Expected: %s, Actual: %s", query, sent)
+		}
+	}
+}
+
+func TestClampRangeStep(t *testing.T) {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		window       time.Duration
+		step         time.Duration
+		maxPoints    int
+		widen        bool
+		expectedStep time.Duration
+		expectError  bool
+	}{
+		"under maximum": {
+			window:       time.Hour,
+			step:         time.Minute,
+			maxPoints:    DefaultMaxRangePoints,
+			expectedStep: time.Minute,
+		},
+		"exactly maximum": {
+			window:       11000 * time.Second,
+			step:         time.Second,
+			maxPoints:    DefaultMaxRangePoints,
+			expectedStep: time.Second,
+		},
+		"one over maximum": {
+			window:      11001 * time.Second,
+			step:        time.Second,
+			maxPoints:   DefaultMaxRangePoints,
+			expectError: true,
+		},
+		"one over maximum widened": {
+			window:       11001 * time.Second,
+			step:         time.Second,
+			maxPoints:    DefaultMaxRangePoints,
+			widen:        true,
+			expectedStep: 2 * time.Second,
+		},
+		"widened to whole seconds": {
+			window:       30 * 24 * time.Hour,
+			step:         time.Minute,
+			maxPoints:    DefaultMaxRangePoints,
+			widen:        true,
+			expectedStep: 236 * time.Second,
+		},
+		"disabled": {
+			window:       30 * 24 * time.Hour,
+			step:         time.Second,
+			maxPoints:    0,
+			expectedStep: time.Second,
+		},
+		"zero step": {
+			window:      time.Hour,
+			step:        0,
+			maxPoints:   DefaultMaxRangePoints,
+			expectError: true,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			step, err := clampRangeStep(start, start.Add(test.window), test.step, test.maxPoints, test.widen)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got step %s", step)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if step != test.expectedStep {
+				t.Fatalf("clampRangeStep: exp (%s); act (%s)", test.expectedStep, step)
+			}
+			if int64(test.window/step) > int64(test.maxPoints) && test.maxPoints > 0 {
+				t.Fatalf("Step %s exceeds maximum points %d", step, test.maxPoints)
+			}
+		})
+	}
+}
+
+func TestRawQueryRangeMaxPoints(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+	ctx := NewContext(client)
+
+	end := time.Now()
+	start := end.Add(-7 * 24 * time.Hour)
+
+	_, err := ctx.RawQueryRange("up", start, end, time.Second)
+	if err == nil {
+		t.Fatalf("Expected error for range query exceeding maximum points")
+	}
+	if len(client.Requests()) != 0 {
+		t.Fatalf("Expected no requests to be sent, got %d", len(client.Requests()))
+	}
+
+	ctx.WithMaxRangePoints(DefaultMaxRangePoints, true)
+	_, err = ctx.RawQueryRange("up", start, end, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if step := promtest.RequestParams(client.Requests()[0]).Get("step"); step != "55.000" {
+		t.Fatalf("Expected widened step 55.000, got %s", step)
+	}
+}
+
+// matrixHandler responds to range queries with a matrix containing a single series per
+// namespace, with a sample at each step between the start and end parameters.
+func matrixHandler(namespaces ...string) func(*http.Request) []byte {
+	return func(req *http.Request) []byte {
+		q := promtest.RequestParams(req)
+		start, _ := time.Parse(time.RFC3339Nano, q.Get("start"))
+		end, _ := time.Parse(time.RFC3339Nano, q.Get("end"))
+		step, _ := strconv.ParseFloat(q.Get("step"), 64)
+
+		var series []string
+		for _, ns := range namespaces {
+			var values []string
+			for ts := start.Unix(); ts <= end.Unix(); ts += int64(step) {
+				values = append(values, fmt.Sprintf(`[%d,"%d"]`, ts, ts))
+			}
+			series = append(series, fmt.Sprintf(`{"metric":{"namespace":"%s"},"values":[%s]}`, ns, strings.Join(values, ",")))
+		}
+
+		return []byte(fmt.Sprintf(`{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, strings.Join(series, ",")))
+	}
+}
+
+func TestQueryRangeChunked(t *testing.T) {
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(matrixHandler("kubecost", "default")))
+	ctx := NewContext(client)
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+
+	results, _, err := ctx.QueryRangeChunked("up", start, end, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(client.Requests()) != 6 {
+		t.Errorf("Expected 6 chunked requests, got %d", len(client.Requests()))
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 merged series, got %d", len(results))
+	}
+
+	for _, result := range results {
+		// 6 hours at 1m step, inclusive of both start and end
+		if len(result.Values) != 361 {
+			t.Errorf("Expected 361 values for %v, got %d", result.Metric, len(result.Values))
+		}
+
+		for i := 1; i < len(result.Values); i++ {
+			if result.Values[i].Timestamp <= result.Values[i-1].Timestamp {
+				t.Fatalf("Values are not sorted and deduplicated at index %d", i)
+			}
+		}
+	}
+}
+
+func TestRawQueryGzipResponse(t *testing.T) {
+	const expected = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"kubecost"},"value":[1622505600,"1"]}]}}`
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(expected))
+	gz.Close()
+
+	client := promtest.NewClient().SetDefault(&promtest.Response{Header: http.Header{"Content-Encoding": []string{"gzip"}}, Body: buf.Bytes()})
+	ctx := NewContext(client)
+
+	body, err := ctx.RawQuery("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(body) != expected {
+		t.Fatalf("Unexpected decoded body: %s", body)
+	}
+	if ae := client.Requests()[0].Header.Get("Accept-Encoding"); !strings.Contains(ae, "gzip") {
+		t.Fatalf("Expected Accept-Encoding to include gzip, got: %s", ae)
+	}
+
+	// Uncompressed responses are passed through
+	client = promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(expected)})
+	ctx = NewContext(client)
+
+	results, _, err := ctx.QuerySync("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+}
+
+func TestQueryResultsContextName(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+
+	named := <-NewNamedContext(client, AllocationContextName).Query("up")
+	if named.ContextName != AllocationContextName {
+		t.Fatalf("ContextName: exp (%s); act (%s)", AllocationContextName, named.ContextName)
+	}
+
+	unnamed := <-NewContext(client).Query("up")
+	if unnamed.ContextName != "" {
+		t.Fatalf("ContextName: exp (); act (%s)", unnamed.ContextName)
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[],"stats":{"timings":{"evalTotalTime":0.25,"execTotalTime":0.5},"samples":{"totalQueryableSamples":120000,"peakSamples":800}}}}`
+
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+
+	<-NewContext(client).Query("up")
+	if promtest.RequestParams(client.Requests()[0]).Get("stats") != "" {
+		t.Fatalf("Unexpected stats parameter with stats disabled")
+	}
+
+	results := <-NewContext(client).WithQueryStats(true, 100000).Query("up")
+	if results.Error != nil {
+		t.Fatalf("Unexpected error: %s", results.Error)
+	}
+	if promtest.RequestParams(client.Requests()[1]).Get("stats") != "all" {
+		t.Fatalf("Expected stats=all parameter, got: %s", promtest.RequestParams(client.Requests()[1]).Encode())
+	}
+	if results.Stats == nil {
+		t.Fatalf("Expected stats to be parsed")
+	}
+	if results.Stats.Samples.TotalQueryableSamples != 120000 || results.Stats.Samples.PeakSamples != 800 {
+		t.Fatalf("Unexpected samples: %+v", results.Stats.Samples)
+	}
+	if results.Stats.Timings.EvalTotalTime != 0.25 {
+		t.Fatalf("Unexpected timings: %+v", results.Stats.Timings)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(func(req *http.Request) []byte {
+		req.ParseForm()
+		if req.Form.Get("query") == "bad" {
+			return []byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		}
+		return []byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"1"]}]}}`)
+	}))
+	ctx := NewContext(client)
+
+	chs := ctx.QueryAll("up", "bad")
+	chs = append(chs, nil, ctx.Query("count(up)"))
+
+	results := ReadAll(chs)
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	for i, query := range []string{"up", "bad", "", "count(up)"} {
+		if query == "" {
+			if results[i] != nil {
+				t.Fatalf("Expected nil result for nil channel at %d", i)
+			}
+			continue
+		}
+		if results[i].Query != query {
+			t.Fatalf("Query at %d: exp (%s); act (%s)", i, query, results[i].Query)
+		}
+	}
+
+	if results[0].Error != nil || results[0].Len() != 1 {
+		t.Fatalf("Unexpected result for 'up': %+v", results[0])
+	}
+	if results[1].Error == nil {
+		t.Fatalf("Expected error for 'bad'")
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+
+	NewContext(client).RawQuery("up")
+	if ua := client.Requests()[0].Header.Get("User-Agent"); ua != DefaultUserAgent() {
+		t.Fatalf("User-Agent: exp (%s); act (%s)", DefaultUserAgent(), ua)
+	}
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	NewContext(client).WithUserAgent("kubecost-test/1.0").RawQueryRange("up", start, start.Add(time.Hour), time.Minute)
+	if ua := client.Requests()[1].Header.Get("User-Agent"); ua != "kubecost-test/1.0" {
+		t.Fatalf("User-Agent: exp (%s); act (%s)", "kubecost-test/1.0", ua)
+	}
+}
+
+func TestStepForPoints(t *testing.T) {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		end         time.Time
+		points      int
+		expected    time.Duration
+		expectError bool
+	}{
+		"even division": {
+			end:      start.Add(5 * time.Hour),
+			points:   300,
+			expected: time.Minute,
+		},
+		"rounded to seconds": {
+			end:      start.Add(time.Hour),
+			points:   7,
+			expected: 514 * time.Second,
+		},
+		"minimum of one second": {
+			end:      start.Add(time.Minute),
+			points:   300,
+			expected: time.Second,
+		},
+		"zero points": {
+			end:         start.Add(time.Hour),
+			points:      0,
+			expectError: true,
+		},
+		"empty window": {
+			end:         start,
+			points:      300,
+			expectError: true,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			step, err := stepForPoints(start, test.end, test.points)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got step %s", step)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if step != test.expected {
+				t.Fatalf("Step: exp (%s); act (%s)", test.expected, step)
+			}
+		})
+	}
+}
+
+func TestQueryRangePoints(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+	ctx := NewContext(client)
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := ctx.QueryRangePoints("up", start, start.Add(5*time.Hour), 300).Await(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if step := promtest.RequestParams(client.Requests()[0]).Get("step"); step != "60.000" {
+		t.Fatalf("Step: exp (60.000); act (%s)", step)
+	}
+
+	if _, err := ctx.QueryRangePoints("up", start, start.Add(time.Hour), -1).Await(); err == nil {
+		t.Fatalf("Expected error for non-positive points")
+	}
+	if len(client.Requests()) != 1 {
+		t.Fatalf("Expected no request for invalid points, got %d requests", len(client.Requests()))
+	}
+}
+
+func TestRawQueryRangeFormEncoded(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+
+	// build a multi-kilobyte query, which would exceed common proxy URL length limits
+	var selectors []string
+	for i := 0; i < 200; i++ {
+		selectors = append(selectors, fmt.Sprintf(`kube_pod_labels{namespace="namespace-%d"}`, i))
+	}
+	query := strings.Join(selectors, " or ")
+	if len(query) < 8192 {
+		t.Fatalf("Expected query larger than 8KB, got %d bytes", len(query))
+	}
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	_, err := NewContext(client).RawQueryRange(query, start, start.Add(time.Hour), time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	req := client.Requests()[0]
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Fatalf("Unexpected request: %s %s", req.Method, req.Header.Get("Content-Type"))
+	}
+	if req.URL.RawQuery != "" {
+		t.Fatalf("Expected empty query string, got %d bytes", len(req.URL.RawQuery))
+	}
+	if sent := promtest.RequestParams(req).Get("query"); sent != query {
+		t.Fatalf("Query was not sent in the request body")
+	}
+
+	_, err = NewContext(client).WithQueryMethod(http.MethodGet).RawQueryRange("up", start, start.Add(time.Hour), time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	req = client.Requests()[1]
+	if req.Method != http.MethodGet || req.URL.Query().Get("query") != "up" {
+		t.Fatalf("Expected GET with query string parameters, got: %s %s", req.Method, req.URL)
+	}
+}
+
+func TestQueryResultsRawBody(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+	buf := []byte(body)
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: buf})
+
+	results := <-NewContext(client).Query("up")
+	if results.RawBody != nil {
+		t.Fatalf("Expected no raw body by default")
+	}
+
+	results = <-NewContext(client).WithRawBody().Query("up")
+	if string(results.RawBody) != body {
+		t.Fatalf("RawBody: exp (%s); act (%s)", body, results.RawBody)
+	}
+
+	// the retained body must not alias the client's buffer
+	buf[0] = '['
+	if results.RawBody[0] != '{' {
+		t.Fatalf("RawBody is aliased to the response buffer")
+	}
+}
+
+func TestContextWithName(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: http.StatusInternalServerError, Body: []byte("error")})
+
+	ctx := NewContext(client).WithUserAgent("test-agent")
+	named := ctx.WithName(AllocationContextName)
+
+	if named.Client != ctx.Client || named.userAgent != "test-agent" {
+		t.Fatalf("Expected clone to share client and options")
+	}
+
+	results := <-named.Query("up")
+	if results.ContextName != AllocationContextName {
+		t.Fatalf("ContextName: exp (%s); act (%s)", AllocationContextName, results.ContextName)
+	}
+
+	if !named.HasErrors() {
+		t.Fatalf("Expected error to be reported to the clone")
+	}
+	if ctx.HasErrors() {
+		t.Fatalf("Expected error collector not to be shared with the original")
+	}
+}
+
+func TestQueryTruncatedResponse(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up"},"value":[1622505600,"1"]}]}}`
+
+	cases := map[string]struct {
+		body      string
+		truncated bool
+	}{
+		"truncated mid-series": {
+			body:      body[:60],
+			truncated: true,
+		},
+		"truncated between fields": {
+			body:      body[:20],
+			truncated: true,
+		},
+		"empty body": {
+			body:      "",
+			truncated: true,
+		},
+		"malformed body": {
+			body:      `{"status":"success","data":}`,
+			truncated: false,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(test.body)})
+
+			_, _, err := NewContext(client).QuerySync("up")
+			if err == nil {
+				t.Fatalf("Expected error decoding body: %s", test.body)
+			}
+
+			isTruncated := IsCommError(err) && strings.Contains(err.Error(), fmt.Sprintf("received %d bytes", len(test.body)))
+			if isTruncated != test.truncated {
+				t.Fatalf("Truncated: exp (%t); act (%t): %s", test.truncated, isTruncated, err)
+			}
+		})
+	}
+}
+
+func TestQueryUnexpectedContentType(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+
+	cases := map[string]struct {
+		contentType string
+		body        string
+		expectErr   bool
+	}{
+		"html": {
+			contentType: "text/html; charset=utf-8",
+			body:        "<html><body><h1>404 Not Found</h1></body></html>",
+			expectErr:   true,
+		},
+		"json": {
+			contentType: "application/json",
+			body:        body,
+			expectErr:   false,
+		},
+		"json with charset": {
+			contentType: "application/json; charset=utf-8",
+			body:        body,
+			expectErr:   false,
+		},
+		"unset": {
+			contentType: "",
+			body:        body,
+			expectErr:   false,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetDefault(&promtest.Response{Header: http.Header{"Content-Type": []string{test.contentType}}, Body: []byte(test.body)})
+
+			_, _, err := NewContext(client).QuerySync("up")
+			if !test.expectErr {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return
+			}
+
+			if !IsCommError(err) {
+				t.Fatalf("Expected CommError, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), test.contentType) || !strings.Contains(err.Error(), "404 Not Found") {
+				t.Fatalf("Expected error to include the content type and body: %s", err)
+			}
+
+			_, err = NewContext(client).QueryRange("up", time.Now().Add(-time.Hour), time.Now(), time.Minute).Await()
+			if !IsCommError(err) {
+				t.Fatalf("Expected CommError for range query, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestQueryAtMany(t *testing.T) {
+	// respond with the evaluation time as the value of the sample
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(func(req *http.Request) []byte {
+		evalTime, _ := time.Parse(time.RFC3339, promtest.RequestParams(req).Get("time"))
+		return []byte(fmt.Sprintf(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[%d,"%d"]}]}}`, evalTime.Unix(), evalTime.Unix()))
+	}))
+	ctx := NewContext(client)
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	times := make([]time.Time, 20)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i*i) * time.Minute)
+	}
+
+	results, err := ctx.QueryAtMany("up", times)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != len(times) {
+		t.Fatalf("results: exp (%d); act (%d)", len(times), len(results))
+	}
+
+	for i, result := range results {
+		value := result[0].Values[0].Value
+		if int64(value) != times[i].Unix() {
+			t.Fatalf("results[%d]: exp (%d); act (%d)", i, times[i].Unix(), int64(value))
+		}
+	}
+
+	client.SetHandler(nil).SetDefault(&promtest.Response{StatusCode: http.StatusServiceUnavailable})
+	if _, err := ctx.QueryAtMany("up", times); err == nil {
+		t.Fatalf("Expected error for failed queries")
+	}
+}
+
+func TestQueryScalarsByLabel(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"job":"kubecost","instance":"a"},"value":[1622505600,"1"]},` +
+		`{"metric":{"job":"kubecost","instance":"b"},"value":[1622505600,"0"]},` +
+		`{"metric":{"job":"prometheus","instance":"c"},"value":[1622505600,"1"]}]}}`
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+	ctx := NewContext(client)
+
+	if _, err := ctx.QueryScalarsByLabel("up", "job"); err == nil {
+		t.Fatalf("Expected error for duplicate keys")
+	}
+
+	scalars, err := ctx.QueryScalarsByLabel("up", "instance")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(scalars) != 3 || scalars["b"] != 0 {
+		t.Fatalf("Unexpected scalars: %+v", scalars)
+	}
+
+	scalars, err = ctx.WithSumDuplicateKeys(true).QueryScalarsByLabel("up", "job")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if scalars["kubecost"] != 1 || scalars["prometheus"] != 1 {
+		t.Fatalf("Unexpected scalars: %+v", scalars)
+	}
+}
+
+func TestQueryNoOffset(t *testing.T) {
+	offset := promQueryOffset
+	defer func() { promQueryOffset = offset }()
+	promQueryOffset = time.Hour
+
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	ctx := NewContext(client)
+
+	// pin the clock, so the exact evaluation time can be asserted
+	now := time.Date(2021, 6, 1, 12, 30, 15, 500, time.UTC)
+	ctx.clock = func() time.Time { return now }
+
+	queryTime := func(resCh QueryResultsChan) string {
+		<-resCh
+
+		req := client.Requests()[len(client.Requests())-1]
+		return promtest.RequestParams(req).Get("time")
+	}
+
+	if ts := queryTime(ctx.Query("up")); ts != "2021-06-01T11:30:15Z" {
+		t.Fatalf("Query: exp (%s); act (%s)", "2021-06-01T11:30:15Z", ts)
+	}
+
+	if ts := queryTime(ctx.QueryNoOffset("up")); ts != "2021-06-01T12:30:15Z" {
+		t.Fatalf("QueryNoOffset: exp (%s); act (%s)", "2021-06-01T12:30:15Z", ts)
+	}
+
+	// the offset remains enabled for the context
+	if ts := queryTime(ctx.Query("up")); ts != "2021-06-01T11:30:15Z" {
+		t.Fatalf("Query: exp (%s); act (%s)", "2021-06-01T11:30:15Z", ts)
+	}
+
+	// the offset is not applied to the allocation context
+	if ts := queryTime(ctx.WithName(AllocationContextName).Query("up")); ts != "2021-06-01T12:30:15Z" {
+		t.Fatalf("Allocation Query: exp (%s); act (%s)", "2021-06-01T12:30:15Z", ts)
+	}
+
+	// unix timestamps are computed from the same clock
+	if ts := queryTime(ctx.WithUnixTimestamps(true).Query("up")); ts != "1622547015" {
+		t.Fatalf("Unix Query: exp (%s); act (%s)", "1622547015", ts)
+	}
+}
+
+func TestDefaultResultLabels(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"namespace":"kubecost"},"value":[1622505600,"1"]},` +
+		`{"metric":{"namespace":"kube-system","cluster_id":"cluster-two"},"value":[1622505600,"2"]}]}}`
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+
+	labels := map[string]string{"cluster_id": "cluster-one"}
+	ctx := NewContext(client).WithDefaultResultLabels(labels)
+
+	// the labels are copied, so later changes do not apply
+	labels["cluster_id"] = "changed"
+
+	results, _, err := ctx.QuerySync("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"cluster-one", "cluster-two"}
+	for i, result := range results {
+		if clusterID, _ := result.GetLabel("cluster_id"); clusterID != expected[i] {
+			t.Fatalf("cluster_id: exp (%s); act (%s)", expected[i], clusterID)
+		}
+	}
+
+	results, _, _ = NewContext(client).QuerySync("up")
+	if _, ok := results[0].GetLabel("cluster_id"); ok {
+		t.Fatalf("Expected no default labels by default")
+	}
+}
+
+func TestQuerySyncPartial(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"namespace":"kubecost"},"value":[1622505600,"1"]},` +
+		`{"metric":{"namespace":"kube-system"}},` +
+		`{"metric":{"namespace":"default"},"value":[1622505600,"3"]}]}}`
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+	ctx := NewContext(client)
+
+	results, _, err := ctx.QuerySync("up")
+	if err == nil {
+		t.Fatalf("Expected QuerySync to return an error for a malformed series")
+	}
+	if results != nil {
+		t.Fatalf("Expected QuerySync to return no results, got %d", len(results))
+	}
+
+	results, partial, _, err := ctx.QuerySyncPartial("up")
+	if err == nil {
+		t.Fatalf("Expected QuerySyncPartial to return the parse error")
+	}
+	if !partial {
+		t.Fatalf("Expected partial results")
+	}
+
+	expected := []string{"kubecost", "default"}
+	if len(results) != len(expected) {
+		t.Fatalf("results: exp (%d); act (%d)", len(expected), len(results))
+	}
+	for i, result := range results {
+		if ns, _ := result.GetLabel("namespace"); ns != expected[i] {
+			t.Fatalf("namespace: exp (%s); act (%s)", expected[i], ns)
+		}
+	}
+
+	client.SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"namespace":"kubecost"},"value":[1622505600,"1"]}]}}`)})
+	results, partial, _, err = ctx.QuerySyncPartial("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if partial || len(results) != 1 {
+		t.Fatalf("Expected 1 complete result, got %d (partial: %t)", len(results), partial)
+	}
+}
+
+func TestParseWarningsMixedTypes(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		expected prometheus.Warnings
+	}{
+		"strings": {
+			body:     `{"warnings":["partial data","slow query"]}`,
+			expected: prometheus.Warnings{"partial data", "slow query"},
+		},
+		"scalars": {
+			body:     `{"warnings":["partial data",42,true,1.5]}`,
+			expected: prometheus.Warnings{"partial data", "42", "true", "1.5"},
+		},
+		"objects": {
+			body:     `{"warnings":[{"store":"thanos-store-0","msg":"timeout"},["a","b"]]}`,
+			expected: prometheus.Warnings{`{"msg":"timeout","store":"thanos-store-0"}`, `["a","b"]`},
+		},
+		"null entries": {
+			body:     `{"warnings":[null,"partial data"]}`,
+			expected: prometheus.Warnings{"partial data"},
+		},
+		"single string": {
+			body:     `{"warnings":"partial data"}`,
+			expected: prometheus.Warnings{"partial data"},
+		},
+		"object": {
+			body:     `{"warnings":{"msg":"timeout"}}`,
+			expected: nil,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var result interface{}
+			if err := json.Unmarshal([]byte(test.body), &result); err != nil {
+				t.Fatalf("Failed to unmarshal test body: %s", err)
+			}
+
+			warnings := warningsFrom(result)
+			if !reflect.DeepEqual(warnings, test.expected) {
+				t.Fatalf("Warnings: exp (%q); act (%q)", test.expected, warnings)
+			}
+		})
+	}
+}
+
+func TestContextClose(t *testing.T) {
+	started := make(chan struct{}, 2)
+	client := promtest.NewClient().SetHandler(func(ctx context.Context, req *http.Request) *promtest.Response {
+		started <- struct{}{}
+		return hangingHandler(ctx, req)
+	})
+	ctx := NewContext(client)
+	named := ctx.WithName(AllocationContextName)
+
+	queryCh := ctx.Query("up")
+	rangeCh := named.QueryRange("up", time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	<-started
+	<-started
+
+	ctx.Close()
+
+	// results unread when the context closes may be discarded, which is also a cancelled error
+	for _, resCh := range []QueryResultsChan{queryCh, rangeCh} {
+		if _, err := resCh.Await(); !IsQueryCancelledError(err) {
+			t.Fatalf("Expected cancelled error, got: %v", err)
+		}
+	}
+
+	if !ctx.IsClosed() || !named.IsClosed() {
+		t.Fatalf("Expected context and copy to be closed")
+	}
+
+	// closed contexts cannot launch new queries
+	results := <-ctx.Query("up")
+	if !IsQueryCancelledError(results.Error) {
+		t.Fatalf("Expected cancelled error for query on closed context, got: %v", results.Error)
+	}
+	if len(client.Requests()) != 2 {
+		t.Fatalf("Expected no request to be sent by a closed context")
+	}
+
+	// closing a copy does not close the original
+	other := NewContext(client)
+	other.WithName("copy").Close()
+	if other.IsClosed() {
+		t.Fatalf("Expected original context to remain open after closing a copy")
+	}
+}
+
+func TestSyncQueryClose(t *testing.T) {
+	start, end := time.Now().Add(-4*time.Hour), time.Now()
+	syncQueries := map[string]func(ctx *Context) error{
+		"QuerySync": func(ctx *Context) error {
+			_, _, err := ctx.QuerySync("up")
+			return err
+		},
+		"QuerySyncCtx": func(ctx *Context) error {
+			_, _, err := ctx.QuerySyncCtx(context.Background(), "up")
+			return err
+		},
+		"QuerySyncPartial": func(ctx *Context) error {
+			_, _, _, err := ctx.QuerySyncPartial("up")
+			return err
+		},
+		"QueryScalarsByLabel": func(ctx *Context) error {
+			_, err := ctx.QueryScalarsByLabel("up", "job")
+			return err
+		},
+		"QueryRangeSync": func(ctx *Context) error {
+			_, _, err := ctx.QueryRangeSync("up", start, end, time.Minute)
+			return err
+		},
+		"QueryRangeChunked": func(ctx *Context) error {
+			_, _, err := ctx.QueryRangeChunked("up", start, end, time.Minute, time.Hour)
+			return err
+		},
+	}
+
+	for name, syncQuery := range syncQueries {
+		t.Run(name, func(t *testing.T) {
+			started := make(chan struct{}, 10)
+			client := promtest.NewClient().SetHandler(func(ctx context.Context, req *http.Request) *promtest.Response {
+				started <- struct{}{}
+				return hangingHandler(ctx, req)
+			})
+			ctx := NewContext(client)
+
+			// in-flight queries are cancelled by Close
+			errCh := make(chan error, 1)
+			go func() { errCh <- syncQuery(ctx) }()
+			<-started
+			ctx.Close()
+			if err := <-errCh; !IsQueryCancelledError(err) {
+				t.Fatalf("Expected cancelled error, got: %v", err)
+			}
+
+			// closed contexts do not send queries
+			requests := len(client.Requests())
+			if err := syncQuery(ctx); !IsQueryCancelledError(err) {
+				t.Fatalf("Expected cancelled error for closed context, got: %v", err)
+			}
+			if len(client.Requests()) != requests {
+				t.Fatalf("Expected no request to be sent by a closed context")
+			}
+		})
+	}
+}
+
+func TestSyncQueryRetry(t *testing.T) {
+	client := promtest.NewClient().SetHandler(flakyHandler(http.StatusServiceUnavailable))
+	ctx := NewContext(client).WithRetry(2, time.Millisecond)
+
+	if _, _, err := ctx.QuerySync("up"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if requests := len(client.Requests()); requests != 2 {
+		t.Fatalf("Requests: exp (2); act (%d)", requests)
+	}
+
+	client.SetHandler(flakyHandler(http.StatusServiceUnavailable))
+	if _, _, err := ctx.QueryRangeSync("up", time.Now().Add(-time.Hour), time.Now(), time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if requests := len(client.Requests()); requests != 4 {
+		t.Fatalf("Requests: exp (4); act (%d)", requests)
+	}
+}
+
+// recordingLogger is a Logger which records the formatted warnings and profile logs
+type recordingLogger struct {
+	lock     sync.Mutex
+	warnings []string
+	profiles []string
+}
+
+func (rl *recordingLogger) Warningf(format string, a ...interface{}) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	rl.warnings = append(rl.warnings, fmt.Sprintf(format, a...))
+}
+
+func (rl *recordingLogger) Infof(format string, a ...interface{}) {}
+
+func (rl *recordingLogger) Profilef(format string, a ...interface{}) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	rl.profiles = append(rl.profiles, fmt.Sprintf(format, a...))
+}
+
+func TestWithLogger(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]},"warnings":["partial data"]}`)})
+	logger := &recordingLogger{}
+
+	ctx := NewContext(client).WithLogger(logger)
+	<-ctx.ProfileQuery("up", "Profiled Query")
+
+	if len(logger.warnings) != 1 || !strings.Contains(logger.warnings[0], "partial data") {
+		t.Fatalf("Warnings: exp ([fetching query 'up': partial data]); act (%v)", logger.warnings)
+	}
+	expected := fmt.Sprintf(": [unnamed %s] Profiled Query", queryHash("up"))
+	if len(logger.profiles) != 1 || !strings.HasSuffix(logger.profiles[0], expected) {
+		t.Fatalf("Profiles: exp ([<elapsed>%s]); act (%v)", expected, logger.profiles)
+	}
+
+	named := &recordingLogger{}
+	<-NewNamedContext(client, AllocationContextName).WithLogger(named).ProfileQuery("up", "Profiled Query")
+	expected = fmt.Sprintf(": [%s %s] Profiled Query", AllocationContextName, queryHash("up"))
+	if len(named.profiles) != 1 || !strings.HasSuffix(named.profiles[0], expected) {
+		t.Fatalf("Profiles: exp ([<elapsed>%s]); act (%v)", expected, named.profiles)
+	}
+
+	if _, ok := NewContext(client).getLogger().(globalLogger); !ok {
+		t.Fatalf("Expected global logger by default")
+	}
+}
+
+func TestWithProfileAll(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	logger := &recordingLogger{}
+	ctx := NewContext(client).WithLogger(logger)
+
+	<-ctx.Query("up")
+	if len(logger.profiles) != 0 {
+		t.Fatalf("Expected no profiles by default, got %v", logger.profiles)
+	}
+
+	ctx.WithProfileAll(true)
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	<-ctx.Query("up")
+	<-ctx.QueryRange("up", start, start.Add(time.Hour), time.Minute)
+	<-ctx.ProfileQuery("up", "Profiled Query")
+
+	expected := []string{
+		fmt.Sprintf(": [unnamed %s] Query", queryHash("up")),
+		fmt.Sprintf(": [unnamed %s] QueryRange", queryHash("up")),
+		fmt.Sprintf(": [unnamed %s] Profiled Query", queryHash("up")),
+	}
+	if len(logger.profiles) != len(expected) {
+		t.Fatalf("Profiles: exp (%d); act (%v)", len(expected), logger.profiles)
+	}
+	for i, profile := range logger.profiles {
+		if !strings.HasSuffix(profile, expected[i]) {
+			t.Fatalf("Profiles: exp (<elapsed>%s); act (%s)", expected[i], profile)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[]},"warnings":["partial data"]}`
+
+	// a fixed id is sent with each request, and included in the logged warnings
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+	logger := &recordingLogger{}
+	ctx := NewContext(client).WithLogger(logger).WithRequestID("reconcile-1")
+	ctx.QuerySync("up")
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ctx.QueryRangeSync("up", start, start.Add(time.Hour), time.Minute)
+
+	for i, req := range client.Requests() {
+		if id := req.Header.Get(DefaultRequestIDHeader); id != "reconcile-1" {
+			t.Fatalf("request %d: exp (%s); act (%s)", i, "reconcile-1", id)
+		}
+	}
+	if len(logger.warnings) != 2 || !strings.Contains(logger.warnings[0], "(request id: reconcile-1)") {
+		t.Fatalf("Warnings: exp request id in warnings; act (%v)", logger.warnings)
+	}
+
+	// the id is included in the errors of unsuccessful responses
+	client = promtest.NewClient().
+		SetResponse("status", &promtest.Response{StatusCode: http.StatusServiceUnavailable, Body: []byte("Service Unavailable")}).
+		SetResponse("envelope", &promtest.Response{
+			StatusCode: http.StatusBadRequest,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       promtest.ErrorBody("bad_data", "parse error"),
+		}).
+		SetResponse("auth", &promtest.Response{StatusCode: http.StatusUnauthorized})
+	ctx = NewContext(client).WithRequestID("reconcile-1")
+	for _, query := range []string{"status", "envelope", "auth"} {
+		_, _, err := ctx.QuerySync(query)
+		if err == nil || !strings.Contains(err.Error(), "(request id: reconcile-1)") {
+			t.Fatalf("%s: exp request id in error; act (%v)", query, err)
+		}
+
+		_, _, err = ctx.QueryRangeSync(query, start, start.Add(time.Hour), time.Minute)
+		if err == nil || !strings.Contains(err.Error(), "(request id: reconcile-1)") {
+			t.Fatalf("%s range: exp request id in error; act (%v)", query, err)
+		}
+	}
+
+	// a unique id is generated for each query, using the configured header
+	client = promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+	ctx = NewContext(client).WithRequestIDHeader("X-Correlation-ID")
+	ctx.QuerySync("up")
+	ctx.QuerySync("up")
+
+	first := client.Requests()[0].Header.Get("X-Correlation-ID")
+	second := client.Requests()[1].Header.Get("X-Correlation-ID")
+	if first == "" || second == "" || first == second {
+		t.Fatalf("Expected unique generated ids; act (%s, %s)", first, second)
+	}
+	if id := client.Requests()[0].Header.Get(DefaultRequestIDHeader); id != "" {
+		t.Fatalf("Expected no %s header; act (%s)", DefaultRequestIDHeader, id)
+	}
+
+	// request ids are disabled by default
+	client = promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+	NewContext(client).QuerySync("up")
+	if id := client.Requests()[0].Header.Get(DefaultRequestIDHeader); id != "" {
+		t.Fatalf("Expected no request id by default; act (%s)", id)
+	}
+}
+
+func TestResultBuffer(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+
+	if c := cap(NewContext(client).Query("up")); c != 0 {
+		t.Fatalf("Default buffer: exp (%d); act (%d)", 0, c)
+	}
+
+	ctx := NewContext(client).WithResultBuffer(1)
+	queries := []string{"up", "down"}
+	resChs := ctx.QueryAll(queries...)
+
+	// the results are handed off to the buffer without a receiver waiting
+	deadline := time.Now().Add(5 * time.Second)
+	for _, resCh := range resChs {
+		for len(resCh) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for buffered results")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for i, resCh := range resChs {
+		if results := <-resCh; results.Query != queries[i] {
+			t.Fatalf("Query: exp (%s); act (%s)", queries[i], results.Query)
+		}
+	}
+
+	if c := cap(NewContext(client).WithResultBuffer(-1).Query("up")); c != 0 {
+		t.Fatalf("Negative buffer: exp (%d); act (%d)", 0, c)
+	}
+}
+
+func TestAPIPrefix(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+
+	ctx := NewContext(client).WithAPIPrefix("/prometheus/api/v1/")
+	ctx.QuerySync("up")
+
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	ctx.QueryRangeSync("up", start, start.Add(time.Hour), time.Minute)
+	ctx.BuildInfo()
+	ctx.Ping(context.Background())
+
+	expected := []string{
+		"/prometheus/api/v1/query",
+		"/prometheus/api/v1/query_range",
+		"/prometheus/api/v1/status/buildinfo",
+		"/-/ready",
+	}
+	for i, path := range expected {
+		if actual := client.Requests()[i].URL.Path; actual != path {
+			t.Fatalf("request %d: exp (%s); act (%s)", i, path, actual)
+		}
+	}
+
+	if path := NewContext(client).QueryURL().Path; path != epQuery {
+		t.Fatalf("default: exp (%s); act (%s)", epQuery, path)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+
+	// a body which is small when compressed, but large when decompressed
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(body))
+	gz.Write(bytes.Repeat([]byte(" "), 4096))
+	gz.Close()
+
+	testCases := map[string]struct {
+		header   http.Header
+		body     []byte
+		maxBytes int64
+		tooLarge bool
+	}{
+		"within limit": {
+			body:     []byte(body),
+			maxBytes: int64(len(body)),
+			tooLarge: false,
+		},
+		"exceeds limit": {
+			body:     []byte(body),
+			maxBytes: int64(len(body)) - 1,
+			tooLarge: true,
+		},
+		"decompressed exceeds limit": {
+			header:   http.Header{"Content-Encoding": []string{"gzip"}},
+			body:     buf.Bytes(),
+			maxBytes: 1024,
+			tooLarge: true,
+		},
+		"disabled": {
+			header:   http.Header{"Content-Encoding": []string{"gzip"}},
+			body:     buf.Bytes(),
+			maxBytes: 0,
+			tooLarge: false,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetDefault(&promtest.Response{Header: test.header, Body: test.body})
+
+			_, _, err := NewContext(client).WithMaxResponseBytes(test.maxBytes).QuerySync("up")
+			tooLarge := err != nil && strings.Contains(err.Error(), "exceeds the maximum size")
+			if tooLarge != test.tooLarge {
+				t.Fatalf("Too large: exp (%t); act (%t): %v", test.tooLarge, tooLarge, err)
+			}
+			if !test.tooLarge && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestUnixTimestamps(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+
+	start := time.Unix(1622505600, 500*int64(time.Millisecond))
+	end := start.Add(time.Hour)
+
+	ctx := NewContext(client)
+	<-ctx.QueryRange("up", start, end, time.Minute)
+
+	params := promtest.RequestParams(client.Requests()[0])
+	if params.Get("start") != start.Format(time.RFC3339Nano) || params.Get("end") != end.Format(time.RFC3339Nano) {
+		t.Fatalf("Expected RFC3339 timestamps by default; act (%s, %s)", params.Get("start"), params.Get("end"))
+	}
+
+	ctx = NewContext(client).WithUnixTimestamps(true)
+	<-ctx.QueryRange("up", start, end, time.Minute)
+	<-ctx.Query("up")
+
+	params = promtest.RequestParams(client.Requests()[1])
+	if params.Get("start") != "1622505600.5" || params.Get("end") != "1622509200.5" {
+		t.Fatalf("Range: exp (1622505600.5, 1622509200.5); act (%s, %s)", params.Get("start"), params.Get("end"))
+	}
+
+	params = promtest.RequestParams(client.Requests()[2])
+	ts, err := strconv.ParseInt(params.Get("time"), 10, 64)
+	if err != nil {
+		t.Fatalf("Expected whole second unix time for instant query; act (%s)", params.Get("time"))
+	}
+	if delay := time.Since(time.Unix(ts, 0)); delay < 0 || delay > time.Minute+promQueryOffset {
+		t.Fatalf("Unexpected instant query time: %s", params.Get("time"))
+	}
+}
+
+func TestQueryResultsWarnings(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","warnings":["receive series from Addr: 10.0.0.2:10901: EOF","query is slow"],"data":{"resultType":"vector","result":[]}}`)})
+	ctx := NewContext(client)
+
+	results := <-ctx.Query("up")
+	if results.Error != nil {
+		t.Fatalf("Unexpected error: %s", results.Error)
+	}
+
+	expected := []ResponseWarning{
+		{Type: WarningTypePartialResponse, Message: "receive series from Addr: 10.0.0.2:10901: EOF"},
+		{Type: WarningTypeUnknown, Message: "query is slow"},
+	}
+	if len(results.Warnings) != len(expected) {
+		t.Fatalf("Warnings: exp (%v); act (%v)", expected, results.Warnings)
+	}
+	for i, w := range results.Warnings {
+		if w != expected[i] {
+			t.Fatalf("warning %d: exp (%v); act (%v)", i, expected[i], w)
+		}
+	}
+}
+
+func TestDurationStep(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+	ctx := NewContext(client)
+
+	end := time.Now()
+	ctx.RawQueryRange("up", end.Add(-time.Hour), end, 90*time.Second)
+	ctx.WithDurationStep(true)
+	ctx.RawQueryRange("up", end.Add(-time.Hour), end, 90*time.Second)
+	ctx.RawQueryRange("up", end.Add(-time.Hour), end, 5*time.Minute)
+
+	for i, expected := range []string{"90.000", "90s", "5m"} {
+		if step := promtest.RequestParams(client.Requests()[i]).Get("step"); step != expected {
+			t.Fatalf("step %d: exp (%s); act (%s)", i, expected, step)
+		}
+	}
+}
+
+func TestQueryResultsChanDrain(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"1"]}]}}`)})
+	ctx := NewContext(client)
+
+	resCh := ctx.Query("up")
+	if err := resCh.Drain(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, ok := <-resCh; ok {
+		t.Fatalf("Expected channel to be closed once drained")
+	}
+
+	client.SetResponse("up(", &promtest.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       []byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`),
+	})
+	if err := ctx.Query("up(").Drain(); err == nil {
+		t.Fatalf("Expected error for failed query")
+	}
+}
+
+func TestReceiveTimeout(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	logger := &recordingLogger{}
+	ctx := NewContext(client).WithLogger(logger).WithReceiveTimeout(10 * time.Millisecond)
+
+	before := runtime.NumGoroutine()
+
+	// abandon the channels without reading the results
+	ctx.Query("up")
+	ctx.QueryRange("up", time.Now().Add(-time.Hour), time.Now(), time.Minute)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		logger.lock.Lock()
+		discarded := len(logger.warnings)
+		logger.lock.Unlock()
+
+		if discarded == 2 && runtime.NumGoroutine() <= before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected abandoned queries to exit, discarded (%d), goroutines (%d) before (%d)", discarded, runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// receivers reading within the timeout still receive the results
+	if _, err := ctx.Query("up").Await(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+func TestReceiveAfterClose(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	logger := &recordingLogger{}
+	ctx := NewContext(client).WithLogger(logger)
+
+	resCh := ctx.Query("up")
+
+	// wait for the query to complete, leaving its goroutine blocked on the unread channel
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if len(client.Requests()) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the query to be requested")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// closing the Context, without a receive timeout, releases the goroutine
+	ctx.Close()
+
+	_, err := resCh.Await()
+	if err == nil {
+		t.Fatalf("Expected an error reading discarded results")
+	}
+	if !IsQueryCancelledError(err) {
+		t.Fatalf("Expected a cancelled error; act (%s)", err)
+	}
+
+	logger.lock.Lock()
+	discarded := len(logger.warnings)
+	logger.lock.Unlock()
+	if discarded != 1 {
+		t.Fatalf("warnings: exp (1); act (%d)", discarded)
+	}
+}
+
+func TestResultsChanClose(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	ctx := NewContext(client)
+
+	if ctx.receiveTimeout != DefaultReceiveTimeout {
+		t.Fatalf("receive timeout: exp (%s); act (%s)", DefaultReceiveTimeout, ctx.receiveTimeout)
+	}
+
+	// the channel is closed by the Context once the results are received, so reading it again,
+	// whether directly or using Await, does not block or panic
+	for _, resCh := range []QueryResultsChan{ctx.Query("up"), ctx.WithResultBuffer(1).Query("up")} {
+		if results := <-resCh; results == nil || results.Error != nil {
+			t.Fatalf("Expected results, got: %+v", results)
+		}
+		if _, err := resCh.Await(); !errors.Is(err, ResultsDiscardedErr) {
+			t.Fatalf("Expected discarded error reading results twice, got: %v", err)
+		}
+		if _, ok := <-resCh; ok {
+			t.Fatalf("Expected channel to be closed")
+		}
+	}
+}
+
+func TestQuerySpecialValues(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"a"},"value":[1622505600,"NaN"]},{"metric":{"pod":"b"},"value":[1622505600,"-Inf"]}]}}`)})
+
+	results, _, err := NewContext(client).QuerySync("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 2 || results[0].Values[0].Value != 0 || results[1].Values[0].Value != 0 {
+		t.Fatalf("Expected special values to be replaced with 0 by default")
+	}
+
+	results, _, err = NewContext(client).WithSpecialValues(SpecialValuesSkipNaN).QuerySync("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results: exp (1); act (%d)", len(results))
+	}
+	if !math.IsInf(results[0].Values[0].Value, -1) {
+		t.Fatalf("Value: exp (%f); act (%f)", math.Inf(-1), results[0].Values[0].Value)
+	}
+}
@@ -0,0 +1,36 @@
+package prom
+
+import (
+	"strings"
+)
+
+// WithQueryComment enables prepending a PromQL comment identifying the Context and the purpose of
+// its queries to each query and query_range request, ie:
+//
+//	# kubecost context=allocation purpose=reconcile
+//	sum(container_memory_allocation_bytes) by (namespace)
+//
+// The comment is included in the query logged by Prometheus, ie: in the query log and the active
+// query tracker, which attributes expensive queries to the Kubecost component sending them. As
+// comments run to the end of the line, the query is evaluated unchanged. Line breaks in the purpose
+// are replaced with spaces, so the purpose cannot end the comment. Only the query sent is changed;
+// the query of the results, errors, and logs is not. An empty purpose disables the comment, which
+// is the default. Returns the Context to allow chaining.
+func (ctx *Context) WithQueryComment(purpose string) *Context {
+	ctx.queryComment = strings.NewReplacer("\r", " ", "\n", " ").Replace(purpose)
+	return ctx
+}
+
+// annotateQuery returns the query prefixed with the query comment of the Context, if enabled
+func (ctx *Context) annotateQuery(query string) string {
+	if ctx.queryComment == "" {
+		return query
+	}
+
+	name := ctx.name
+	if name == "" {
+		name = "unnamed"
+	}
+
+	return "# kubecost context=" + name + " purpose=" + ctx.queryComment + "\n" + query
+}
@@ -0,0 +1,38 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestQueryComment(t *testing.T) {
+	const query = `sum(container_memory_allocation_bytes{namespace="kubecost"}) by (pod)`
+
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+	ctx := NewNamedContext(client, AllocationContextName).WithQueryComment("reconcile\n# injected")
+
+	results := <-ctx.Query(query)
+	if results.Error != nil {
+		t.Fatalf("Unexpected error: %s", results.Error)
+	}
+	if results.Query != query {
+		t.Fatalf("Query: exp (%s); act (%s)", query, results.Query)
+	}
+
+	expected := "# kubecost context=" + AllocationContextName + " purpose=reconcile # injected\n" + query
+	sent := promtest.RequestParams(client.Requests()[0]).Get("query")
+	if sent != expected {
+		t.Fatalf("query: exp (%s); act (%s)", expected, sent)
+	}
+	if err := checkQueryStructure(sent); err != nil {
+		t.Fatalf("Unexpected validation error for commented query: %s", err)
+	}
+
+	ctx.WithQueryComment("")
+	ctx.Query(query).Await()
+	if sent := promtest.RequestParams(client.Requests()[1]).Get("query"); strings.HasPrefix(sent, "#") {
+		t.Fatalf("Expected no comment when disabled, got: %s", sent)
+	}
+}
@@ -0,0 +1,121 @@
+package prom
+
+import (
+	"sync"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+)
+
+// otherContextName is the context label used by the query metrics for contexts which are not named
+// with one of the known context names, which bounds the cardinality of the label.
+const otherContextName = "other"
+
+// knownContextNames contains the context names which are used as-is for the context label of the
+// query metrics
+var knownContextNames = map[string]bool{
+	"":                              true,
+	AllocationContextName:           true,
+	ClusterContextName:              true,
+	ClusterOptionalContextName:      true,
+	ComputeCostDataContextName:      true,
+	ComputeCostDataRangeContextName: true,
+	ClusterMapContextName:           true,
+	FrontendContextName:             true,
+	DiagnosticContextName:           true,
+}
+
+// Only allow the query metrics to be instantiated and registered once
+var queryMetricsInit sync.Once
+
+var (
+	queryWarningsCv       *promclient.CounterVec
+	queryRetriesCv        *promclient.CounterVec
+	queryRetryExhaustedCv *promclient.CounterVec
+	queryInflightGv       *promclient.GaugeVec
+	queryResultSeriesHv   *promclient.HistogramVec
+)
+
+// initQueryMetrics uses a sync.Once to ensure that the query metrics are only created and
+// registered once
+func initQueryMetrics() {
+	queryMetricsInit.Do(func() {
+		queryWarningsCv = promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "kubecost_prometheus_query_warnings_total",
+			Help: "kubecost_prometheus_query_warnings_total Number of warnings returned by prometheus queries",
+		}, []string{"context", "type"})
+
+		queryRetriesCv = promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "kubecost_query_retries_total",
+			Help: "kubecost_query_retries_total Number of prometheus query attempts which were retries of a failed attempt",
+		}, []string{"context"})
+
+		queryRetryExhaustedCv = promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "kubecost_query_retry_exhausted_total",
+			Help: "kubecost_query_retry_exhausted_total Number of prometheus queries which failed after exhausting all retry attempts",
+		}, []string{"context"})
+
+		queryInflightGv = promclient.NewGaugeVec(promclient.GaugeOpts{
+			Name: "kubecost_prometheus_queries_inflight",
+			Help: "kubecost_prometheus_queries_inflight Number of queries currently executing against prometheus",
+		}, []string{"context"})
+
+		queryResultSeriesHv = promclient.NewHistogramVec(promclient.HistogramOpts{
+			Name:    "kubecost_prometheus_query_result_series",
+			Help:    "kubecost_prometheus_query_result_series Number of series returned by prometheus queries",
+			Buckets: promclient.ExponentialBuckets(1, 4, 11),
+		}, []string{"context"})
+
+		promclient.MustRegister(queryWarningsCv, queryRetriesCv, queryRetryExhaustedCv, queryInflightGv, queryResultSeriesHv)
+	})
+}
+
+// recordQueryWarnings increments the warnings metric for each warning returned by a query. The
+// type label is the WarningType of the warning, which is a fixed set, bounding its cardinality.
+func (ctx *Context) recordQueryWarnings(warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	initQueryMetrics()
+	for _, w := range warnings {
+		queryWarningsCv.WithLabelValues(inflightContextName(ctx.name), string(ClassifyWarning(w))).Inc()
+	}
+}
+
+// recordQueryRetry increments the retries metric
+func (ctx *Context) recordQueryRetry() {
+	initQueryMetrics()
+	queryRetriesCv.WithLabelValues(inflightContextName(ctx.name)).Inc()
+}
+
+// recordQueryRetryExhausted increments the exhausted retries metric
+func (ctx *Context) recordQueryRetryExhausted() {
+	initQueryMetrics()
+	queryRetryExhaustedCv.WithLabelValues(inflightContextName(ctx.name)).Inc()
+}
+
+// inflightContextName returns the context label of the query metrics for the context name
+func inflightContextName(name string) string {
+	if knownContextNames[name] {
+		return name
+	}
+
+	return otherContextName
+}
+
+// trackInflight increments the in-flight metric, returning a func which decrements it once the
+// query has completed
+func (ctx *Context) trackInflight() func() {
+	initQueryMetrics()
+	gauge := queryInflightGv.WithLabelValues(inflightContextName(ctx.name))
+	gauge.Inc()
+
+	return gauge.Dec
+}
+
+// recordQueryResultSeries observes the number of series returned by a query in the result series
+// metric
+func (ctx *Context) recordQueryResultSeries(results *QueryResults) {
+	initQueryMetrics()
+	queryResultSeriesHv.WithLabelValues(inflightContextName(ctx.name)).Observe(float64(len(results.Results)))
+}
@@ -0,0 +1,106 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRecordQueryWarnings(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[]},"warnings":["partial data","No StoreAPIs matched for this query"]}`
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+
+	// the context name is unknown, so the warnings are recorded with the bounded other label
+	initQueryMetrics()
+	before := make(map[WarningType]float64)
+	for _, warnType := range []WarningType{WarningTypeNoStoreAPI, WarningTypeUnknown} {
+		before[warnType] = testutil.ToFloat64(queryWarningsCv.WithLabelValues(otherContextName, string(warnType)))
+	}
+
+	ctx := NewNamedContext(client, "warnings-test")
+	ctx.QuerySync("up")
+
+	cases := map[WarningType]float64{
+		WarningTypeNoStoreAPI: 1,
+		WarningTypeUnknown:    1,
+	}
+
+	for warnType, expected := range cases {
+		actual := testutil.ToFloat64(queryWarningsCv.WithLabelValues(otherContextName, string(warnType))) - before[warnType]
+		if actual != expected {
+			t.Fatalf("%s: exp (%f); act (%f)", warnType, expected, actual)
+		}
+	}
+}
+
+func TestQueryInflight(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	client := promtest.NewClient().SetHandler(gatedHandler(started, release, promtest.VectorBody(nil)))
+	ctx := NewNamedContext(client, "inflight-test")
+
+	resCh := ctx.Query("up")
+	<-started
+
+	gauge := queryInflightGv.WithLabelValues(otherContextName)
+	if actual := testutil.ToFloat64(gauge); actual != 1 {
+		t.Fatalf("in-flight: exp (%d); act (%f)", 1, actual)
+	}
+
+	close(release)
+	resCh.Await()
+
+	if actual := testutil.ToFloat64(gauge); actual != 0 {
+		t.Fatalf("in-flight: exp (%d); act (%f)", 0, actual)
+	}
+}
+
+func TestInflightContextName(t *testing.T) {
+	cases := map[string]string{
+		"":                    "",
+		AllocationContextName: AllocationContextName,
+		"custom":              otherContextName,
+	}
+
+	for name, expected := range cases {
+		if actual := inflightContextName(name); actual != expected {
+			t.Fatalf("%s: exp (%s); act (%s)", name, expected, actual)
+		}
+	}
+}
+
+// queryResultSeries returns the sample count and sum of the result series histogram for the context name
+func queryResultSeries(name string) (uint64, float64) {
+	metric := &dto.Metric{}
+	queryResultSeriesHv.WithLabelValues(name).(promclient.Histogram).Write(metric)
+
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func TestRecordQueryResultSeries(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[` +
+		`{"metric":{"pod":"a"},"value":[1622505600,"1"]},` +
+		`{"metric":{"pod":"b"},"value":[1622505600,"1"]},` +
+		`{"metric":{"pod":"c"},"value":[1622505600,"1"]}]}}`
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(body)})
+
+	// the histogram is shared by all tests in the package, so only the observations of this test
+	// are asserted
+	initQueryMetrics()
+	countBefore, sumBefore := queryResultSeries(DiagnosticContextName)
+
+	ctx := NewNamedContext(client, DiagnosticContextName)
+	ctx.QuerySync("up")
+	ctx.Query("up").Await()
+
+	countAfter, sumAfter := queryResultSeries(DiagnosticContextName)
+	if count := countAfter - countBefore; count != 2 {
+		t.Fatalf("count: exp (%d); act (%d)", 2, count)
+	}
+	if sum := sumAfter - sumBefore; sum != 6 {
+		t.Fatalf("sum: exp (%f); act (%f)", 6.0, sum)
+	}
+}
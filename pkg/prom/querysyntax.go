@@ -0,0 +1,89 @@
+package prom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithQueryValidation enables or disables checking the structure of each query with
+// checkQueryStructure before it is sent, which returns a validation error for queries with
+// unbalanced brackets or unterminated strings without making a request. This catches bugs in
+// programmatically built queries early, at the cost of a scan of each query. It is not a PromQL
+// parse, so other invalid queries are still sent and rejected by Prometheus. Disabled by default.
+// Returns the Context to allow chaining.
+func (ctx *Context) WithQueryValidation(enabled bool) *Context {
+	ctx.validateQueries = enabled
+	return ctx
+}
+
+// checkQueryStructure performs a structural check of the query, returning a QueryValidationError
+// describing the first problem found. It only verifies the query is non-empty, that string literals
+// are terminated, and that parentheses, braces, and brackets are balanced and properly nested. It is
+// not PromQL validation: queries such as `sum(` fail the check, but `sum()` or `rate(foo[5x])` pass
+// it, as function names, arguments, durations, operators, and types are left to Prometheus.
+func checkQueryStructure(query string) error {
+	if strings.TrimSpace(query) == "" {
+		return NewQueryValidationError(query, 0, "query is empty")
+	}
+
+	closers := map[byte]byte{'(': ')', '{': '}', '[': ']'}
+
+	type open struct {
+		char byte
+		pos  int
+	}
+	var stack []open
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch c {
+		case '#':
+			// comments run to the end of the line
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+
+		case '"', '\'', '`':
+			start := i
+			for i++; i < len(query) && query[i] != c; i++ {
+				if query[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+			if i >= len(query) {
+				return NewQueryValidationError(query, start+1, "unterminated string literal")
+			}
+
+		case '(', '{', '[':
+			stack = append(stack, open{char: c, pos: i})
+
+		case ')', '}', ']':
+			if len(stack) == 0 {
+				return NewQueryValidationError(query, i+1, fmt.Sprintf("unexpected '%c'", c))
+			}
+
+			last := stack[len(stack)-1]
+			if closers[last.char] != c {
+				return NewQueryValidationError(query, i+1, fmt.Sprintf("unexpected '%c', expected '%c'", c, closers[last.char]))
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if len(stack) > 0 {
+		last := stack[len(stack)-1]
+		return NewQueryValidationError(query, last.pos+1, fmt.Sprintf("unclosed '%c'", last.char))
+	}
+
+	return nil
+}
+
+// validateQuery checks the structure of the query if query validation is enabled for the Context
+func (ctx *Context) validateQuery(query string) error {
+	if !ctx.validateQueries {
+		return nil
+	}
+
+	return checkQueryStructure(query)
+}
@@ -0,0 +1,98 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestCheckQueryStructure(t *testing.T) {
+	cases := map[string]struct {
+		query    string
+		valid    bool
+		position int
+	}{
+		"simple": {
+			query: `up`,
+			valid: true,
+		},
+		"aggregation": {
+			query: `sum(rate(container_cpu_usage_seconds_total{container!="", namespace=~"kube-.*"}[5m])) by (namespace)`,
+			valid: true,
+		},
+		"delimiters in strings": {
+			query: `up{job="a)b", instance='{[', path=` + "`\\`" + `}`,
+			valid: true,
+		},
+		"escaped quote": {
+			query: `up{job="a\"b"}`,
+			valid: true,
+		},
+		"comment": {
+			query: "up # unbalanced ( in a comment\n",
+			valid: true,
+		},
+		"invalid promql with valid structure": {
+			query: `rate(foo[5x])`,
+			valid: true,
+		},
+		"empty": {
+			query: "  \n",
+		},
+		"unclosed paren": {
+			query:    `sum(rate(up[5m])`,
+			position: 4,
+		},
+		"unexpected closer": {
+			query:    `sum(up))`,
+			position: 8,
+		},
+		"mismatched": {
+			query:    `sum(up{job="a")}`,
+			position: 15,
+		},
+		"unterminated string": {
+			query:    `up{job="kubecost}`,
+			position: 8,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkQueryStructure(tc.query)
+			if tc.valid {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return
+			}
+
+			qve, ok := err.(QueryValidationError)
+			if !ok {
+				t.Fatalf("Expected QueryValidationError; act (%v)", err)
+			}
+			if qve.Position != tc.position {
+				t.Fatalf("Position: exp (%d); act (%d) %s", tc.position, qve.Position, err)
+			}
+		})
+	}
+}
+
+func TestQueryValidation(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)})
+
+	ctx := NewContext(client).WithQueryValidation(true).WithRetry(3, 0)
+	_, _, err := ctx.QuerySync(`sum(up`)
+	if !IsQueryValidationError(err) {
+		t.Fatalf("Expected QueryValidationError; act (%v)", err)
+	}
+	if len(client.Requests()) != 0 {
+		t.Fatalf("Expected no requests for an invalid query; act (%d)", len(client.Requests()))
+	}
+
+	// validation is disabled by default, so the query is sent
+	NewContext(client).QuerySync(`sum(up`)
+	if len(client.Requests()) != 1 {
+		t.Fatalf("Expected 1 request with validation disabled; act (%d)", len(client.Requests()))
+	}
+}
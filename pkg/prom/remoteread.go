@@ -0,0 +1,300 @@
+package prom
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/kubecost/cost-model/pkg/util"
+	"github.com/kubecost/cost-model/pkg/util/httputil"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const epRead = apiPrefix + "/read"
+
+// remoteReadVersion is the version of the remote read protocol sent with each request
+const remoteReadVersion = "0.1.0"
+
+// Remote read label matcher types, as defined by prompb.LabelMatcher_Type
+var remoteReadMatchTypes = map[MatchOp]uint64{
+	MatchEqual:     0,
+	MatchNotEqual:  1,
+	MatchRegexp:    2,
+	MatchNotRegexp: 3,
+}
+
+// WithRemoteRead enables or disables the remote read transport used by RemoteRead. Remote read is
+// disabled by default, as not all servers enable the endpoint. Returns the Context to allow chaining.
+func (ctx *Context) WithRemoteRead(enabled bool) *Context {
+	ctx.remoteRead = enabled
+	return ctx
+}
+
+// RemoteRead fetches the raw samples of all series matching the label matchers between start and
+// end using the Prometheus remote read protocol. This is significantly cheaper than query_range for
+// bulk extraction of historical data, as no PromQL evaluation occurs. Sample timestamps are returned
+// as-is, without being aligned to a step. Servers which do not implement the endpoint return an
+// UnsupportedEndpointError. If the Context is closed before the read completes, a cancelled error
+// is returned.
+func (ctx *Context) RemoteRead(matchers []LabelMatcher, start, end time.Time) ([]*QueryResult, error) {
+	if !ctx.remoteRead {
+		return nil, fmt.Errorf("Remote read is not enabled for this context")
+	}
+
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("Remote read requires at least one label matcher")
+	}
+
+	reqBody, err := encodeReadRequest(matchers, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	query := matcherString(matchers)
+
+	u := ctx.Client.URL(ctx.endpoint(epRead), nil)
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(snappy.Encode(nil, reqBody)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Set QueryContext name if non empty
+	if ctx.name != "" {
+		req = httputil.SetName(req, ctx.name)
+	}
+	req = httputil.SetQuery(req, query)
+	ctx.setUserAgent(req)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Read-Version", remoteReadVersion)
+
+	// the request is bound to the Context, so closing the Context cancels a read in progress
+	resp, body, _, err := ctx.Client.Do(ctx.baseContext(), req)
+	if err != nil {
+		if ctx.IsClosed() {
+			return nil, QueryCancelledErr(query)
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("remote read error: '%s' fetching series '%s'", err.Error(), query)
+		}
+
+		return nil, fmt.Errorf("remote read error %d: '%s' fetching series '%s'", resp.StatusCode, err.Error(), query)
+	}
+
+	statusCode := resp.StatusCode
+	if statusCode == http.StatusNotFound {
+		return nil, NewUnsupportedEndpointError(epRead)
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		return nil, NewCommResponseError(statusCode, body, query, "%d (%s) Headers: %s, Body: %s Query: %s", statusCode, http.StatusText(statusCode), httputil.HeaderString(resp.Header), body, query)
+	}
+
+	if ctx.maxResponseBytes > 0 {
+		if n, err := snappy.DecodedLen(body); err == nil && int64(n) > ctx.maxResponseBytes {
+			return nil, CommErrorf("%s, Query: %s", responseTooLargeErr(ctx.maxResponseBytes), query)
+		}
+	}
+
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, CommErrorf("Failed to decode snappy remote read response: %s, Query: %s", err, query)
+	}
+
+	results, err := decodeReadResponse(decoded, ctx.specialValues)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal Error: %s\nQuery: %s", err, query)
+	}
+
+	ctx.setDefaultResultLabels(results)
+
+	return results, nil
+}
+
+// matcherString returns the matchers in selector form, ie: {namespace="kubecost"}, which is used
+// in place of a query for errors and request labeling.
+func matcherString(matchers []LabelMatcher) string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range matchers {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(m.String())
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+//--------------------------------------------------------------------------
+//  Protobuf Encoding
+//--------------------------------------------------------------------------
+
+// The remote read messages are encoded by hand to avoid depending on the prometheus server
+// module for the generated prompb types. Field numbers match prompb/remote.proto and
+// prompb/types.proto.
+
+// encodeReadRequest encodes a prompb.ReadRequest containing a single query
+func encodeReadRequest(matchers []LabelMatcher, start, end time.Time) ([]byte, error) {
+	var query []byte
+	query = protowire.AppendTag(query, 1, protowire.VarintType)
+	query = protowire.AppendVarint(query, uint64(start.UnixNano()/int64(time.Millisecond)))
+	query = protowire.AppendTag(query, 2, protowire.VarintType)
+	query = protowire.AppendVarint(query, uint64(end.UnixNano()/int64(time.Millisecond)))
+
+	for _, m := range matchers {
+		matchType, ok := remoteReadMatchTypes[m.Op]
+		if !ok {
+			return nil, fmt.Errorf("Unsupported label matcher operator: '%s'", m.Op)
+		}
+
+		var matcher []byte
+		matcher = protowire.AppendTag(matcher, 1, protowire.VarintType)
+		matcher = protowire.AppendVarint(matcher, matchType)
+		matcher = protowire.AppendTag(matcher, 2, protowire.BytesType)
+		matcher = protowire.AppendString(matcher, m.Name)
+		matcher = protowire.AppendTag(matcher, 3, protowire.BytesType)
+		matcher = protowire.AppendString(matcher, m.Value)
+
+		query = protowire.AppendTag(query, 3, protowire.BytesType)
+		query = protowire.AppendBytes(query, matcher)
+	}
+
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, query)
+	return req, nil
+}
+
+// decodeReadResponse decodes a prompb.ReadResponse, returning the time series of all query
+// results. Special sample values are decoded according to the mode.
+func decodeReadResponse(b []byte, mode SpecialValueMode) ([]*QueryResult, error) {
+	var results []*QueryResult
+
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		// ReadResponse.results
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+
+		return consumeFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+			// QueryResult.timeseries
+			if num != 1 || typ != protowire.BytesType {
+				return nil
+			}
+
+			result, err := decodeTimeSeries(value, mode)
+			if err != nil {
+				return err
+			}
+
+			results = append(results, result)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// decodeTimeSeries decodes a prompb.TimeSeries into a QueryResult, decoding special sample values
+// according to the mode
+func decodeTimeSeries(b []byte, mode SpecialValueMode) (*QueryResult, error) {
+	result := &QueryResult{
+		Metric: make(map[string]interface{}),
+	}
+
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		// TimeSeries.labels
+		case 1:
+			var name, val string
+			err := consumeFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					name = string(value)
+				case num == 2 && typ == protowire.BytesType:
+					val = string(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			result.Metric[name] = val
+
+		// TimeSeries.samples
+		case 2:
+			sample := &util.Vector{}
+			err := consumeFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					v, _ := protowire.ConsumeFixed64(value)
+					sample.Value = math.Float64frombits(v)
+				case num == 2 && typ == protowire.VarintType:
+					v, _ := protowire.ConsumeVarint(value)
+					sample.Timestamp = float64(int64(v)) / 1000
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			var keep bool
+			sample.Value, _, keep = applySpecialValueMode(sample.Value, mode)
+			if keep {
+				result.Values = append(result.Values, sample)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(util.VectorSlice(result.Values))
+	return result, nil
+}
+
+// consumeFields iterates over each field of the encoded message, calling fn with the field
+// number, wire type, and value. Length delimited values are passed without their length prefix,
+// and all other values are passed in their encoded form.
+func consumeFields(b []byte, fn func(protowire.Number, protowire.Type, []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		value := b[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+
+		b = b[n:]
+	}
+
+	return nil
+}
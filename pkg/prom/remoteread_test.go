@@ -0,0 +1,136 @@
+package prom
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	"github.com/kubecost/cost-model/pkg/util"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// appendTestTimeSeries appends an encoded prompb.TimeSeries to the encoded QueryResult
+func appendTestTimeSeries(b []byte, labels map[string]string, samples []*util.Vector) []byte {
+	var ts []byte
+	for name, value := range labels {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, value)
+
+		ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, label)
+	}
+	for _, s := range samples {
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(int64(s.Timestamp*1000)))
+
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+	}
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
+
+func TestRemoteRead(t *testing.T) {
+	var result []byte
+	result = appendTestTimeSeries(result, map[string]string{"__name__": "up", "job": "kubecost"}, []*util.Vector{
+		{Timestamp: 1622505615.5, Value: 1},
+		{Timestamp: 1622505600, Value: math.NaN()},
+	})
+
+	var resp []byte
+	resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+	resp = protowire.AppendBytes(resp, result)
+
+	var reqBody []byte
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(func(req *http.Request) []byte {
+		body, _ := ioutil.ReadAll(req.Body)
+		reqBody, _ = snappy.Decode(nil, body)
+		return snappy.Encode(nil, resp)
+	}))
+
+	start := time.Unix(1622505600, 0)
+	end := start.Add(time.Hour)
+	matchers := []LabelMatcher{
+		{Name: "__name__", Op: MatchEqual, Value: "up"},
+		{Name: "job", Op: MatchRegexp, Value: "kube.*"},
+	}
+
+	_, err := NewContext(client).RemoteRead(matchers, start, end)
+	if err == nil {
+		t.Fatalf("Expected error when remote read is not enabled")
+	}
+
+	results, err := NewContext(client).WithRemoteRead(true).RemoteRead(matchers, start, end)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []*QueryResult{
+		{
+			Metric: map[string]interface{}{"__name__": "up", "job": "kubecost"},
+			Values: []*util.Vector{{Timestamp: 1622505600, Value: 0}, {Timestamp: 1622505615.5, Value: 1}},
+		},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("Results: exp (%+v); act (%+v)", expected, results)
+	}
+
+	req := client.Requests()[0]
+	if req.URL.Path != epRead || req.Header.Get("Content-Encoding") != "snappy" {
+		t.Fatalf("Unexpected request: %s %s", req.URL.Path, req.Header)
+	}
+
+	expectedReq, _ := encodeReadRequest(matchers, start, end)
+	if !reflect.DeepEqual(reqBody, expectedReq) {
+		t.Fatalf("Unexpected request body: %x", reqBody)
+	}
+}
+
+func TestRemoteReadUnsupported(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: http.StatusNotFound, Body: []byte("404 page not found")})
+
+	_, err := NewContext(client).WithRemoteRead(true).RemoteRead([]LabelMatcher{{Name: "__name__", Op: MatchEqual, Value: "up"}}, time.Now().Add(-time.Hour), time.Now())
+	if !IsUnsupportedEndpointError(err) {
+		t.Fatalf("Expected UnsupportedEndpointError, got: %v", err)
+	}
+}
+
+func TestRemoteReadClose(t *testing.T) {
+	started := make(chan struct{}, 1)
+	client := promtest.NewClient().SetHandler(func(ctx context.Context, req *http.Request) *promtest.Response {
+		started <- struct{}{}
+		return hangingHandler(ctx, req)
+	})
+	ctx := NewContext(client).WithRemoteRead(true)
+
+	errCh := make(chan error)
+	go func() {
+		_, err := ctx.RemoteRead([]LabelMatcher{{Name: "__name__", Op: MatchEqual, Value: "up"}}, time.Now().Add(-time.Hour), time.Now())
+		errCh <- err
+	}()
+
+	<-started
+	ctx.Close()
+
+	select {
+	case err := <-errCh:
+		if !IsQueryCancelledError(err) {
+			t.Fatalf("Expected cancelled error, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the remote read to be cancelled")
+	}
+}
@@ -0,0 +1,107 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// DefaultRequestIDHeader is the header used to send request ids when enabled using WithRequestID
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the request id of a query
+type requestIDKey struct{}
+
+// WithRequestID enables sending a correlation id with each query and query_range request, which
+// ties the logs of a Kubecost operation to the Prometheus access logs of its requests. If id is
+// non-empty, it is sent with every request made by the Context, ie: the id of a reconcile.
+// Otherwise, a unique id is generated for each query. The id is included in the warnings logged
+// for the query, and in request errors. The DefaultRequestIDHeader is used unless set using
+// WithRequestIDHeader. Returns the Context to allow chaining.
+func (ctx *Context) WithRequestID(id string) *Context {
+	ctx.requestIDs = true
+	ctx.requestID = id
+	if ctx.requestIDHeader == "" {
+		ctx.requestIDHeader = DefaultRequestIDHeader
+	}
+	return ctx
+}
+
+// WithRequestIDHeader sets the header used to send request ids, ie: X-Correlation-ID, and enables
+// request ids with a unique id generated for each query if not already enabled. An empty header
+// uses the DefaultRequestIDHeader. Returns the Context to allow chaining.
+func (ctx *Context) WithRequestIDHeader(header string) *Context {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+
+	ctx.requestIDs = true
+	ctx.requestIDHeader = header
+	return ctx
+}
+
+// withRequestID returns a copy of reqCtx carrying the request id of a query if request ids are
+// enabled and reqCtx does not carry one already.
+func (ctx *Context) withRequestID(reqCtx context.Context) context.Context {
+	if !ctx.requestIDs || requestIDFrom(reqCtx) != "" {
+		return reqCtx
+	}
+
+	id := ctx.requestID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	return context.WithValue(reqCtx, requestIDKey{}, id)
+}
+
+// requestIDFrom returns the request id carried by reqCtx, or an empty string if there is none
+func requestIDFrom(reqCtx context.Context) string {
+	id, _ := reqCtx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// setRequestID sets the request id header on the request if request ids are enabled, returning
+// the reqCtx carrying the request id sent.
+func (ctx *Context) setRequestID(reqCtx context.Context, req *http.Request) context.Context {
+	if !ctx.requestIDs {
+		return reqCtx
+	}
+
+	reqCtx = ctx.withRequestID(reqCtx)
+	req.Header.Set(ctx.requestIDHeader, requestIDFrom(reqCtx))
+	return reqCtx
+}
+
+// requestIDSuffix formats the request id carried by reqCtx for inclusion in logs and errors, or
+// returns an empty string if there is none
+func requestIDSuffix(reqCtx context.Context) string {
+	return formatRequestID(requestIDFrom(reqCtx))
+}
+
+// formatRequestID formats the request id for inclusion in logs and errors, or returns an empty
+// string if the id is empty
+func formatRequestID(id string) string {
+	if id == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (request id: %s)", id)
+}
+
+// withErrorRequestID sets the request id carried by reqCtx on a CommError or AuthError returned
+// for the response of a request. Other errors are returned as-is.
+func withErrorRequestID(reqCtx context.Context, err error) error {
+	switch e := err.(type) {
+	case CommError:
+		e.RequestID = requestIDFrom(reqCtx)
+		return e
+	case AuthError:
+		e.RequestID = requestIDFrom(reqCtx)
+		return e
+	default:
+		return err
+	}
+}
@@ -0,0 +1,300 @@
+package prom
+
+import (
+	"bytes"
+	gojson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kubecost/cost-model/pkg/log"
+	"github.com/kubecost/cost-model/pkg/util"
+	"github.com/kubecost/cost-model/pkg/util/json"
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// promResponse is the typed representation of a prometheus query API response. Decoding into
+// promResponse directly avoids building the intermediate map[string]interface{} representation,
+// which is significantly more expensive for large matrix results.
+type promResponse struct {
+	Status    string      `json:"status"`
+	Data      *promData   `json:"data"`
+	ErrorType string      `json:"errorType"`
+	Error     string      `json:"error"`
+	Warnings  interface{} `json:"warnings"`
+}
+
+// Prometheus query API result types
+const (
+	resultTypeMatrix = "matrix"
+	resultTypeVector = "vector"
+	resultTypeScalar = "scalar"
+	resultTypeString = "string"
+)
+
+// promData is the data field of a prometheus query API response. Scalar results are decoded into
+// a single series with no labels, and string results are retained in String.
+type promData struct {
+	ResultType string
+	Result     []*promSeries
+	String     *promSample
+	Stats      *QueryStats
+}
+
+// UnmarshalJSON decodes the result field of the response based on the resultType
+func (pd *promData) UnmarshalJSON(b []byte) error {
+	// gojson used here, as jsoniter UnmarshalJSON won't work with RawMessage
+	var raw struct {
+		ResultType string            `json:"resultType"`
+		Result     gojson.RawMessage `json:"result"`
+		Stats      *QueryStats       `json:"stats"`
+	}
+	if err := gojson.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	pd.ResultType = raw.ResultType
+	pd.Stats = raw.Stats
+	if len(raw.Result) == 0 || string(raw.Result) == "null" {
+		return nil
+	}
+
+	switch raw.ResultType {
+	// older versions of thanos and cortex omit the resultType, so default to series results
+	case resultTypeMatrix, resultTypeVector, "":
+		return gojson.Unmarshal(raw.Result, &pd.Result)
+
+	case resultTypeScalar:
+		var sample promSample
+		if err := gojson.Unmarshal(raw.Result, &sample); err != nil {
+			return err
+		}
+
+		pd.Result = []*promSeries{{Metric: map[string]interface{}{}, Value: &sample}}
+		return nil
+
+	case resultTypeString:
+		var sample promSample
+		if err := gojson.Unmarshal(raw.Result, &sample); err != nil {
+			return err
+		}
+
+		pd.String = &sample
+		return nil
+	}
+
+	return fmt.Errorf("Unknown result type: %s", raw.ResultType)
+}
+
+// promSeries is a single vector or matrix result of a prometheus query API response
+type promSeries struct {
+	Metric map[string]interface{} `json:"metric"`
+	Value  *promSample            `json:"value"`
+	Values []*promSample          `json:"values"`
+}
+
+// promSample is a single [<timestamp>, "<value>"] data point
+type promSample struct {
+	Timestamp float64
+	Value     string
+}
+
+// UnmarshalJSON decodes the [<timestamp>, "<value>"] tuple representation of a sample. The tuple
+// is parsed by hand, as it is decoded once per sample and dominates the cost of large matrices.
+func (ps *promSample) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) < 2 || b[0] != '[' || b[len(b)-1] != ']' {
+		return fmt.Errorf("invalid sample: %s", b)
+	}
+
+	b = b[1 : len(b)-1]
+	sep := bytes.IndexByte(b, ',')
+	if sep < 0 {
+		return fmt.Errorf("invalid sample: %s", b)
+	}
+
+	ts, err := strconv.ParseFloat(string(bytes.TrimSpace(b[:sep])), 64)
+	if err != nil {
+		return fmt.Errorf("invalid sample timestamp: %s", err)
+	}
+
+	val := bytes.TrimSpace(b[sep+1:])
+	if len(val) < 2 || val[0] != '"' || val[len(val)-1] != '"' {
+		return fmt.Errorf("invalid sample value: %s", val)
+	}
+
+	ps.Timestamp = ts
+	ps.Value = string(val[1 : len(val)-1])
+	return nil
+}
+
+// decodeResponse decodes the prometheus response from the reader directly into QueryResults,
+// returning any warnings found in the response. Special sample values are decoded according to
+// the mode.
+func decodeResponse(query string, r io.Reader, mode SpecialValueMode) (*QueryResults, prometheus.Warnings, error) {
+	var resp promResponse
+
+	err := json.NewDecoder(r).Decode(&resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newQueryResultsFromResponse(query, &resp, mode), parseWarnings(resp.Warnings), nil
+}
+
+// isTruncatedJSON returns true if the body is the prefix of a json value which ended before the
+// value was complete, which occurs when the connection is closed mid-response. An empty body is
+// also considered truncated.
+func isTruncatedJSON(body []byte) bool {
+	var raw gojson.RawMessage
+	err := gojson.NewDecoder(bytes.NewReader(body)).Decode(&raw)
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// maxBodySnippet is the maximum number of bytes of a response body included in error messages
+const maxBodySnippet = 256
+
+// checkContentType returns a CommError if the Content-Type of a successful query response is not
+// JSON, ie: the HTML error page of a misrouted reverse proxy served with a 200. Responses without a
+// Content-Type are assumed to be JSON. Protobuf responses are checked separately.
+func checkContentType(query string, contentType string, body []byte) error {
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	return NewCommResponseError(http.StatusOK, body, query, "Unexpected Content-Type '%s' for a successful response, which may have been returned by a misconfigured proxy: %s, Query: %s", contentType, bodySnippet(body), query)
+}
+
+// bodySnippet returns the start of the response body for inclusion in error messages
+func bodySnippet(body []byte) string {
+	if len(body) <= maxBodySnippet {
+		return strings.TrimSpace(string(body))
+	}
+
+	return strings.TrimSpace(string(body[:maxBodySnippet])) + "..."
+}
+
+// newQueryResultsFromResponse creates QueryResults from a typed prometheus response.
+func newQueryResultsFromResponse(query string, resp *promResponse, mode SpecialValueMode) *QueryResults {
+	qrs := &QueryResults{Query: query}
+
+	if resp.Data == nil {
+		// some gateways respond with {"status":"success","data":null}, which must not be mistaken
+		// for an empty result
+		if resp.Error == "" && resp.Status == "success" {
+			qrs.Error = DataFieldNullErr(query)
+			return qrs
+		}
+
+		if resp.Error == "" {
+			qrs.Error = PromUnexpectedResponseErr(query)
+			return qrs
+		}
+
+		qrs.Error = fmt.Errorf("'%s' parsing query '%s'", resp.Error, query)
+		return qrs
+	}
+
+	qrs.Stats = resp.Data.Stats
+
+	if resp.Data.ResultType == resultTypeString {
+		qrs.Error = ResultTypeUnsupportedErr(query, resultTypeString)
+		return qrs
+	}
+
+	if resp.Data.Result == nil {
+		qrs.Error = ResultFieldDoesNotExistErr(query)
+		return qrs
+	}
+
+	results := make([]*QueryResult, 0, len(resp.Data.Result))
+
+	// malformed series are skipped, with the first error set on the results, so the series which
+	// parsed are still available to callers which accept partial results (see QuerySyncPartial)
+	fail := func(err error) {
+		if qrs.Error == nil {
+			qrs.Error = err
+		}
+	}
+
+series:
+	for _, series := range resp.Data.Result {
+		if series == nil {
+			fail(ResultFormatErr(query))
+			continue
+		}
+		if series.Metric == nil {
+			fail(MetricFieldDoesNotExistErr(query))
+			continue
+		}
+
+		// Define label string for values to ensure that we only run labelsForMetric once
+		// if we receive multiple warnings.
+		var labelString string = ""
+
+		var vectors []*util.Vector
+		if series.Values == nil {
+			if series.Value == nil {
+				fail(ValueFieldDoesNotExistErr(query))
+				continue
+			}
+
+			v, warn, err := newVector(series.Value.Timestamp, series.Value.Value, mode)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			if v == nil {
+				continue
+			}
+			if warn != nil {
+				log.DedupedWarningf(5, "%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelsForMetric(series.Metric))
+			}
+
+			vectors = append(vectors, v)
+		} else {
+			vectors = make([]*util.Vector, 0, len(series.Values))
+
+			for _, sample := range series.Values {
+				if sample == nil {
+					fail(DataPointFormatErr(query))
+					continue series
+				}
+
+				v, warn, err := newVector(sample.Timestamp, sample.Value, mode)
+				if err != nil {
+					fail(err)
+					continue series
+				}
+				if v == nil {
+					continue
+				}
+				if warn != nil {
+					if labelString == "" {
+						labelString = labelsForMetric(series.Metric)
+					}
+					log.DedupedWarningf(5, "%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelString)
+				}
+
+				vectors = append(vectors, v)
+			}
+		}
+
+		results = append(results, &QueryResult{
+			Metric: series.Metric,
+			Values: vectors,
+		})
+	}
+
+	qrs.Results = results
+	return qrs
+}
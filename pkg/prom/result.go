@@ -1,15 +1,26 @@
 package prom
 
 import (
+	"context"
+	gojson "encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kubecost/cost-model/pkg/log"
 	"github.com/kubecost/cost-model/pkg/util"
 )
 
+// labelSeparator is used to separate label names and values when computing a fingerprint,
+// and is an invalid utf-8 byte, so it cannot appear within a label.
+const labelSeparator byte = 0xff
+
 var (
 	// Static Warnings for data point parsing
 	InfWarning warning = newWarning("Found Inf value parsing vector data point for metric")
@@ -20,6 +31,10 @@ func DataFieldFormatErr(query string) error {
 	return fmt.Errorf("Data field improperly formatted in prometheus repsonse fetching query '%s'", query)
 }
 
+func DataFieldNullErr(query string) error {
+	return fmt.Errorf("Data field is null or missing in successful prometheus response fetching query '%s', which usually indicates a misconfigured proxy or query frontend", query)
+}
+
 func DataPointFormatErr(query string) error {
 	return fmt.Errorf("Improperly formatted datapoint from Prometheus fetching query '%s'", query)
 }
@@ -40,6 +55,21 @@ func PromUnexpectedResponseErr(query string) error {
 	return fmt.Errorf("Unexpected response from Prometheus fetching query '%s'", query)
 }
 
+func QueryCancelledErr(query string) error {
+	return fmt.Errorf("Query cancelled, as the context was closed, fetching query '%s': %w", query, context.Canceled)
+}
+
+// IsQueryCancelledError returns true if the error is, or wraps, a context cancellation, such as a
+// QueryCancelledErr returned by a closed Context
+func IsQueryCancelledError(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// ResultsDiscardedErr is returned when reading a QueryResultsChan the results of which were
+// discarded, as they were not read before the Context was closed or the receive timeout elapsed
+// (see WithReceiveTimeout). It wraps context.Canceled, so IsQueryCancelledError returns true.
+var ResultsDiscardedErr = fmt.Errorf("Query results discarded, as they were not read before the context was closed or the receive timeout elapsed: %w", context.Canceled)
+
 func QueryResultNilErr(query string) error {
 	return NewCommError(query)
 }
@@ -56,6 +86,10 @@ func ResultFormatErr(query string) error {
 	return fmt.Errorf("Result is improperly formatted fetching query '%s'", query)
 }
 
+func ResultTypeUnsupportedErr(query string, resultType string) error {
+	return fmt.Errorf("Result type '%s' is not supported fetching query '%s'", resultType, query)
+}
+
 func ValueFieldDoesNotExistErr(query string) error {
 	return fmt.Errorf("Value field does not exist in data result vector fetching query '%s'", query)
 }
@@ -64,15 +98,15 @@ func ValueFieldFormatErr(query string) error {
 	return fmt.Errorf("Values field is improperly formatted fetching query '%s'", query)
 }
 
+// MissingLabelGroup is the group key used by GroupByLabel for results without the label
+const MissingLabelGroup = "__missing__"
+
 // QueryResultsChan is a channel of query results
 type QueryResultsChan chan *QueryResults
 
-// Await returns query results, blocking until they are made available, and
-// deferring the closure of the underlying channel
+// Await returns query results, blocking until they are made available
 func (qrc QueryResultsChan) Await() ([]*QueryResult, error) {
-	defer close(qrc)
-
-	results := <-qrc
+	results := qrc.read()
 	if results.Error != nil {
 		return nil, results.Error
 	}
@@ -80,139 +114,271 @@ func (qrc QueryResultsChan) Await() ([]*QueryResult, error) {
 	return results.Results, nil
 }
 
+// Drain awaits the results and discards them, returning only the error of the query. This is useful for queries run only for their side effects, ie: warming
+// caches, which must still be read to release the goroutine executing the query. Warnings are
+// logged and recorded by the Context regardless.
+func (qrc QueryResultsChan) Drain() error {
+	return qrc.read().Error
+}
+
+// read receives the results. The channel is closed by the Context once the results are sent, so if
+// it is closed without results, as the results were discarded or already read, results with a
+// ResultsDiscardedErr are returned.
+func (qrc QueryResultsChan) read() *QueryResults {
+	results, ok := <-qrc
+	if !ok {
+		return &QueryResults{Error: ResultsDiscardedErr}
+	}
+
+	return results
+}
+
+// ReadAll awaits the results of each channel, returning the QueryResults in the same order the
+// channels were provided. Query errors are returned on the QueryResults of the respective query.
+// Nil channels produce a nil entry, which allows callers to skip optional queries while
+// maintaining indexing.
+func ReadAll(chs []QueryResultsChan) []*QueryResults {
+	results := make([]*QueryResults, len(chs))
+
+	for i, ch := range chs {
+		if ch == nil {
+			continue
+		}
+
+		results[i] = ch.read()
+	}
+
+	return results
+}
+
 // QueryResults contains all of the query results and the source query string.
 type QueryResults struct {
 	Query   string
 	Error   error
 	Results []*QueryResult
-}
 
-// QueryResult contains a single result from a prometheus query. It's common
-// to refer to query results as a slice of QueryResult
-type QueryResult struct {
-	Metric map[string]interface{} `json:"metric"`
-	Values []*util.Vector         `json:"values"`
+	// ContextName is the name of the Context which executed the query, or empty for unnamed
+	// Contexts. This is useful for attributing results and errors when results from multiple
+	// Contexts flow into a shared handler.
+	ContextName string
+
+	// Stats contains the query statistics reported by the server, which are only requested when
+	// enabled on the Context using WithQueryStats.
+	Stats *QueryStats
+
+	// RawBody is a copy of the decoded response body, which is only retained when enabled on the
+	// Context using WithRawBody. This is intended for diagnostics only.
+	RawBody []byte
+
+	// Warnings are the classified warnings returned by the server alongside the results
+	Warnings []ResponseWarning
 }
 
-// NewQueryResults accepts the raw prometheus query result and returns an array of
-// QueryResult objects
-func NewQueryResults(query string, queryResult interface{}) *QueryResults {
-	qrs := &QueryResults{Query: query}
+// IsEmpty returns true if the query returned no results
+func (qrs *QueryResults) IsEmpty() bool {
+	return qrs.Len() == 0
+}
 
-	if queryResult == nil {
-		qrs.Error = QueryResultNilErr(query)
-		return qrs
+// clone returns a deep copy of the results, so the copy may be modified without affecting the
+// original. The raw body and warnings are shared, as they are never modified.
+func (qrs *QueryResults) clone() *QueryResults {
+	c := *qrs
+	if qrs.Results == nil {
+		return &c
 	}
 
-	data, ok := queryResult.(map[string]interface{})["data"]
-	if !ok {
-		e, err := wrapPrometheusError(query, queryResult)
-		if err != nil {
-			qrs.Error = err
-			return qrs
+	c.Results = make([]*QueryResult, len(qrs.Results))
+	for i, qr := range qrs.Results {
+		var metric map[string]interface{}
+		if qr.Metric != nil {
+			metric = make(map[string]interface{}, len(qr.Metric))
+			for k, v := range qr.Metric {
+				metric[k] = v
+			}
+		}
+
+		var values []*util.Vector
+		if qr.Values != nil {
+			values = make([]*util.Vector, len(qr.Values))
+			for j, v := range qr.Values {
+				if v != nil {
+					vector := *v
+					values[j] = &vector
+				}
+			}
 		}
-		qrs.Error = fmt.Errorf(e)
-		return qrs
+
+		c.Results[i] = &QueryResult{Metric: metric, Values: values}
 	}
 
-	// Deep Check for proper formatting
-	d, ok := data.(map[string]interface{})
-	if !ok {
-		qrs.Error = DataFieldFormatErr(query)
-		return qrs
+	return &c
+}
+
+// Len returns the number of results (series) returned by the query
+func (qrs *QueryResults) Len() int {
+	if qrs == nil {
+		return 0
 	}
-	resultData, ok := d["result"]
-	if !ok {
-		qrs.Error = ResultFieldDoesNotExistErr(query)
-		return qrs
+
+	return len(qrs.Results)
+}
+
+// SingleValue returns the value of the query result if exactly one series containing a single
+// sample was returned, ie: count(up). A NoDataError is returned if there are no results, and
+// an error is returned if there are multiple series or samples.
+func (qrs *QueryResults) SingleValue() (float64, error) {
+	if qrs.Error != nil {
+		return 0, qrs.Error
 	}
-	resultsData, ok := resultData.([]interface{})
-	if !ok {
-		qrs.Error = ResultFieldFormatErr(query)
-		return qrs
+
+	switch n := qrs.Len(); {
+	case n == 0:
+		return 0, NoDataErr(qrs.Query)
+	case n > 1:
+		return 0, fmt.Errorf("Expected a single result, but found %d series fetching query '%s'", n, qrs.Query)
 	}
 
-	// Result vectors from the query
-	var results []*QueryResult
+	values := qrs.Results[0].Values
+	if len(values) != 1 {
+		return 0, fmt.Errorf("Expected a single value, but found %d values fetching query '%s'", len(values), qrs.Query)
+	}
 
-	// Parse raw results and into QueryResults
-	for _, val := range resultsData {
-		resultInterface, ok := val.(map[string]interface{})
-		if !ok {
-			qrs.Error = ResultFormatErr(query)
-			return qrs
-		}
+	return values[0].Value, nil
+}
 
-		metricInterface, ok := resultInterface["metric"]
-		if !ok {
-			qrs.Error = MetricFieldDoesNotExistErr(query)
-			return qrs
+// ScalarsByLabel returns the value of each result keyed by the value of the label with the provided
+// name, ie: ScalarsByLabel("job") of up returns the value of up for each job. The last value of each
+// result is used, which is the only value for instant queries, and results without values are
+// skipped. Results without the label are keyed under MissingLabelGroup. If multiple results have the
+// same key, their values are summed if sumDuplicates is set, and an error is returned otherwise.
+func (qrs *QueryResults) ScalarsByLabel(name string, sumDuplicates bool) (map[string]float64, error) {
+	if qrs.Error != nil {
+		return nil, qrs.Error
+	}
+
+	scalars := make(map[string]float64, qrs.Len())
+	for i := 0; i < qrs.Len(); i++ {
+		qr := qrs.Results[i]
+		if len(qr.Values) == 0 {
+			continue
 		}
-		metricMap, ok := metricInterface.(map[string]interface{})
-		if !ok {
-			qrs.Error = MetricFieldFormatErr(query)
-			return qrs
+
+		key := qr.GetLabelOr(name, MissingLabelGroup)
+		value := qr.Values[len(qr.Values)-1].Value
+		if _, ok := scalars[key]; ok {
+			if !sumDuplicates {
+				return nil, fmt.Errorf("Duplicate value '%s' of label '%s' fetching query '%s'", key, name, qrs.Query)
+			}
+
+			scalars[key] += value
+			continue
 		}
 
-		// Define label string for values to ensure that we only run labelsForMetric once
-		// if we receive multiple warnings.
-		var labelString string = ""
+		scalars[key] = value
+	}
 
-		// Determine if the result is a ranged data set or single value
-		_, isRange := resultInterface["values"]
+	return scalars, nil
+}
 
-		var vectors []*util.Vector
-		if !isRange {
-			dataPoint, ok := resultInterface["value"]
-			if !ok {
-				qrs.Error = ValueFieldDoesNotExistErr(query)
-				return qrs
-			}
+// FilterByLabel returns the results which have the label with the provided name set to value.
+// Unlike PromQL matchers, results without the label do not match an empty value.
+func (qrs *QueryResults) FilterByLabel(name, value string) []*QueryResult {
+	return qrs.filter(func(qr *QueryResult) bool {
+		v, ok := qr.GetLabel(name)
+		return ok && v == value
+	})
+}
 
-			// Append new data point, log warnings
-			v, warn, err := parseDataPoint(query, dataPoint)
-			if err != nil {
-				qrs.Error = err
-				return qrs
-			}
-			if warn != nil {
-				log.DedupedWarningf(5, "%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelsForMetric(metricMap))
-			}
+// FilterByLabelRegex returns the results which have the label with the provided name set to a
+// value matching the regular expression. Unlike PromQL matchers, the expression is not anchored,
+// so it should be wrapped with ^ and $ to match the full value.
+func (qrs *QueryResults) FilterByLabelRegex(name string, re *regexp.Regexp) []*QueryResult {
+	return qrs.filter(func(qr *QueryResult) bool {
+		v, ok := qr.GetLabel(name)
+		return ok && re.MatchString(v)
+	})
+}
 
-			vectors = append(vectors, v)
-		} else {
-			values, ok := resultInterface["values"].([]interface{})
-			if !ok {
-				qrs.Error = fmt.Errorf("Values field is improperly formatted")
-				return qrs
-			}
+// GroupByLabel returns the results bucketed by the value of the label with the provided name.
+// Results without the label are bucketed under MissingLabelGroup.
+func (qrs *QueryResults) GroupByLabel(name string) map[string][]*QueryResult {
+	return qrs.GroupByLabels(name)
+}
 
-			// Append new data points, log warnings
-			for _, value := range values {
-				v, warn, err := parseDataPoint(query, value)
-				if err != nil {
-					qrs.Error = err
-					return qrs
-				}
-				if warn != nil {
-					if labelString == "" {
-						labelString = labelsForMetric(metricMap)
-					}
-					log.DedupedWarningf(5, "%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelString)
-				}
+// GroupByLabels returns the results bucketed by the values of the labels with the provided names,
+// joined with "/" in the order provided, ie: GroupByLabels("namespace", "pod") buckets a result
+// under "kubecost/cost-model-0". Missing labels use MissingLabelGroup in place of the value.
+func (qrs *QueryResults) GroupByLabels(names ...string) map[string][]*QueryResult {
+	groups := make(map[string][]*QueryResult)
+
+	values := make([]string, len(names))
+	for i := 0; i < qrs.Len(); i++ {
+		qr := qrs.Results[i]
+		for j, name := range names {
+			values[j] = qr.GetLabelOr(name, MissingLabelGroup)
+		}
 
-				vectors = append(vectors, v)
-			}
+		key := strings.Join(values, "/")
+		groups[key] = append(groups[key], qr)
+	}
+
+	return groups
+}
+
+// ByFingerprint returns the results keyed by the Fingerprint of each series. If multiple results
+// have the same fingerprint, the last result is kept.
+func (qrs *QueryResults) ByFingerprint() map[uint64]*QueryResult {
+	byFingerprint := make(map[uint64]*QueryResult, qrs.Len())
+	if qrs == nil {
+		return byFingerprint
+	}
+
+	for _, result := range qrs.Results {
+		byFingerprint[result.Fingerprint()] = result
+	}
+
+	return byFingerprint
+}
+
+// filter returns the results for which the predicate returns true
+func (qrs *QueryResults) filter(pred func(*QueryResult) bool) []*QueryResult {
+	var filtered []*QueryResult
+	for i := 0; i < qrs.Len(); i++ {
+		if qr := qrs.Results[i]; pred(qr) {
+			filtered = append(filtered, qr)
 		}
+	}
+	return filtered
+}
+
+// QueryResult contains a single result from a prometheus query. It's common
+// to refer to query results as a slice of QueryResult
+type QueryResult struct {
+	Metric map[string]interface{} `json:"metric"`
+	Values []*util.Vector         `json:"values"`
+}
 
-		results = append(results, &QueryResult{
-			Metric: metricMap,
-			Values: vectors,
-		})
+// NewQueryResults accepts the raw prometheus query result and returns an array of
+// QueryResult objects. The raw result is converted into the typed response structs, so callers
+// holding the response body should decode it directly instead.
+func NewQueryResults(query string, queryResult interface{}) *QueryResults {
+	if queryResult == nil {
+		return &QueryResults{Query: query, Error: QueryResultNilErr(query)}
+	}
+
+	// gojson used here, as the raw result is decoded using the standard library decoder
+	b, err := gojson.Marshal(queryResult)
+	if err != nil {
+		return &QueryResults{Query: query, Error: ResultFormatErr(query)}
 	}
 
-	qrs.Results = results
-	return qrs
+	var resp promResponse
+	err = gojson.Unmarshal(b, &resp)
+	if err != nil {
+		return &QueryResults{Query: query, Error: fmt.Errorf("Unmarshal Error: %s\nQuery: %s", err, query)}
+	}
+
+	return newQueryResultsFromResponse(query, &resp, SpecialValuesZero)
 }
 
 // GetString returns the requested field, or an error if it does not exist
@@ -251,6 +417,59 @@ func (qr *QueryResult) GetStrings(fields ...string) (map[string]string, error) {
 	return values, nil
 }
 
+// GetLabel returns the string value of the metric label with the provided name, and whether the
+// label exists. Labels which are present but not strings are treated as missing.
+func (qr *QueryResult) GetLabel(name string) (string, bool) {
+	if qr == nil {
+		return "", false
+	}
+
+	value, ok := qr.Metric[name].(string)
+	return value, ok
+}
+
+// GetLabelOr returns the string value of the metric label with the provided name, or def if the
+// label does not exist. Labels which exist with an empty value return the empty value.
+func (qr *QueryResult) GetLabelOr(name, def string) string {
+	if value, ok := qr.GetLabel(name); ok {
+		return value
+	}
+
+	return def
+}
+
+// GetFloatLabel returns the value of the metric label with the provided name parsed as a float64,
+// or an error if the label does not exist or is not numeric.
+func (qr *QueryResult) GetFloatLabel(name string) (float64, error) {
+	value, err := qr.GetString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' field value '%s' cannot be converted to float", name, value)
+	}
+
+	return f, nil
+}
+
+// GetIntLabel returns the value of the metric label with the provided name parsed as an int64,
+// or an error if the label does not exist or is not an integer.
+func (qr *QueryResult) GetIntLabel(name string) (int64, error) {
+	value, err := qr.GetString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' field value '%s' cannot be converted to int", name, value)
+	}
+
+	return i, nil
+}
+
 // GetLabels returns all labels and their values from the query result
 func (qr *QueryResult) GetLabels() map[string]string {
 	result := make(map[string]string)
@@ -297,37 +516,155 @@ func (qr *QueryResult) GetAnnotations() map[string]string {
 	return result
 }
 
-// parseDataPoint parses a data point from raw prometheus query results and returns
-// a new Vector instance containing the parsed data along with any warnings or errors.
-func parseDataPoint(query string, dataPoint interface{}) (*util.Vector, warning, error) {
-	var w warning = nil
+// Fingerprint returns a hash over the sorted metric labels of the result, which identifies the
+// series independently of label order, ie: when joining or deduplicating the results of multiple
+// queries. The 64-bit FNV-1a hash separates names and values with an invalid utf-8 byte, so
+// collisions are negligible at typical series cardinalities, but are possible.
+func (qr *QueryResult) Fingerprint() uint64 {
+	return metricFingerprint(qr.Metric)
+}
 
-	value, ok := dataPoint.([]interface{})
-	if !ok || len(value) != 2 {
-		return nil, w, DataPointFormatErr(query)
+// SumValues returns the sum of the sample values of the result, or 0 if there are no samples
+func (qr *QueryResult) SumValues() float64 {
+	sum := 0.0
+	for _, v := range qr.Values {
+		sum += v.Value
 	}
+	return sum
+}
 
-	strVal := value[1].(string)
+// AverageValues returns the mean of the sample values of the result, or 0 if there are no samples.
+// Each sample is weighted equally regardless of spacing; see TimeWeightedAverage.
+func (qr *QueryResult) AverageValues() float64 {
+	if len(qr.Values) == 0 {
+		return 0
+	}
+
+	return qr.SumValues() / float64(len(qr.Values))
+}
+
+// TimeWeightedAverage returns the average of the sample values of the result, with each sample
+// weighted by the time until the next sample, capped at step. The last sample is weighted by step.
+// Capping at the step prevents gaps in the series, ie: a pod which was not running, from being
+// attributed to the sample preceding the gap. Samples are expected to be sorted by timestamp. A
+// step <= 0 falls back to AverageValues, and a result with no samples returns 0.
+func (qr *QueryResult) TimeWeightedAverage(step time.Duration) float64 {
+	if step <= 0 {
+		return qr.AverageValues()
+	}
+
+	stepSecs := step.Seconds()
+	weightedSum, totalWeight := 0.0, 0.0
+	for i, v := range qr.Values {
+		weight := stepSecs
+		if i+1 < len(qr.Values) {
+			weight = math.Min(qr.Values[i+1].Timestamp-v.Timestamp, stepSecs)
+		}
+
+		weightedSum += v.Value * weight
+		totalWeight += weight
+	}
+
+	if totalWeight <= 0 {
+		return 0
+	}
+
+	return weightedSum / totalWeight
+}
+
+// newVector creates a vector from a sample timestamp and string value. Timestamps are rounded to
+// the nearest 10 seconds, and the "NaN", "+Inf" and "-Inf" values are decoded according to the
+// mode. A nil vector with a nil error is returned for samples skipped by the mode.
+func newVector(timestamp float64, strVal string, mode SpecialValueMode) (*util.Vector, warning, error) {
 	v, err := strconv.ParseFloat(strVal, 64)
 	if err != nil {
-		return nil, w, err
+		return nil, nil, err
 	}
 
-	// Test for +Inf and -Inf (sign: 0), Test for NaN
-	if math.IsInf(v, 0) {
-		w = InfWarning
-		v = 0.0
-	} else if math.IsNaN(v) {
-		w = NaNWarning
-		v = 0.0
+	v, w, keep := applySpecialValueMode(v, mode)
+	if !keep {
+		return nil, nil, nil
 	}
 
 	return &util.Vector{
-		Timestamp: math.Round(value[0].(float64)/10) * 10,
+		Timestamp: math.Round(timestamp/10) * 10,
 		Value:     v,
 	}, w, nil
 }
 
+// metricFingerprint computes a hash over the sorted labels of a metric map, which can be used
+// to identify the same series across query results.
+func metricFingerprint(metric map[string]interface{}) uint64 {
+	keys := make([]string, 0, len(metric))
+	for k := range metric {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{labelSeparator})
+		h.Write([]byte(fmt.Sprintf("%v", metric[k])))
+		h.Write([]byte{labelSeparator})
+	}
+
+	return h.Sum64()
+}
+
+// MergeQueryResults merges the results of multiple queries, ie: a query split by namespace or by
+// time range, combining the values of results with the same series fingerprint. Combined values
+// are sorted by timestamp, and duplicate timestamps are removed, keeping the first value
+// encountered. Results are returned in the order their series are first encountered. The input
+// results are not modified, but the merged results share their metrics and values.
+func MergeQueryResults(sets ...[]*QueryResult) []*QueryResult {
+	var merged []*QueryResult
+	bySeries := make(map[uint64]*QueryResult)
+
+	for _, set := range sets {
+		for _, result := range set {
+			fp := metricFingerprint(result.Metric)
+
+			existing, ok := bySeries[fp]
+			if !ok {
+				existing = &QueryResult{
+					Metric: result.Metric,
+				}
+				bySeries[fp] = existing
+				merged = append(merged, existing)
+			}
+
+			existing.Values = append(existing.Values, result.Values...)
+		}
+	}
+
+	for _, result := range merged {
+		result.Values = dedupSortedValues(result.Values)
+	}
+
+	return merged
+}
+
+// dedupSortedValues sorts the vectors by timestamp and removes any duplicate timestamps, keeping
+// the first value encountered for a timestamp.
+func dedupSortedValues(values []*util.Vector) []*util.Vector {
+	if len(values) < 2 {
+		return values
+	}
+
+	sort.Stable(util.VectorSlice(values))
+
+	deduped := values[:1]
+	for _, v := range values[1:] {
+		if v.Timestamp == deduped[len(deduped)-1].Timestamp {
+			continue
+		}
+		deduped = append(deduped, v)
+	}
+
+	return deduped
+}
+
 func labelsForMetric(metricMap map[string]interface{}) string {
 	var pairs []string
 	for k, v := range metricMap {
@@ -336,12 +673,3 @@ func labelsForMetric(metricMap map[string]interface{}) string {
 
 	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
 }
-
-func wrapPrometheusError(query string, qr interface{}) (string, error) {
-	e, ok := qr.(map[string]interface{})["error"]
-	if !ok {
-		return "", PromUnexpectedResponseErr(query)
-	}
-	eStr, ok := e.(string)
-	return fmt.Sprintf("'%s' parsing query '%s'", eStr, query), nil
-}
@@ -0,0 +1,556 @@
+package prom
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/util"
+	"github.com/kubecost/cost-model/pkg/util/json"
+)
+
+// newTestQueryResults parses the provided json prometheus response into QueryResults
+func newTestQueryResults(t *testing.T, query string, body string) *QueryResults {
+	var raw interface{}
+	err := json.Unmarshal([]byte(body), &raw)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal test body: %s", err)
+	}
+
+	return NewQueryResults(query, raw)
+}
+
+func TestNewQueryResults(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		expected []*QueryResult
+	}{
+		"vector": {
+			body: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"namespace":"kubecost"},"value":[1622505603,"1.5"]},{"metric":{"namespace":"default"},"value":[1622505603,"NaN"]}]}}`,
+			expected: []*QueryResult{
+				{
+					Metric: map[string]interface{}{"namespace": "kubecost"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 1.5}},
+				},
+				{
+					Metric: map[string]interface{}{"namespace": "default"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 0}},
+				},
+			},
+		},
+		"matrix": {
+			body: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"namespace":"kubecost"},"values":[[1622505600,"1"],[1622505660,"2"]]}]}}`,
+			expected: []*QueryResult{
+				{
+					Metric: map[string]interface{}{"namespace": "kubecost"},
+					Values: []*util.Vector{{Timestamp: 1622505600, Value: 1}, {Timestamp: 1622505660, Value: 2}},
+				},
+			},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			qrs := newTestQueryResults(t, "up", test.body)
+			if qrs.Error != nil {
+				t.Fatalf("Unexpected error: %s", qrs.Error)
+			}
+			if !reflect.DeepEqual(qrs.Results, test.expected) {
+				t.Fatalf("Results: exp (%+v); act (%+v)", test.expected, qrs.Results)
+			}
+		})
+	}
+}
+
+func TestNewQueryResultsErrors(t *testing.T) {
+	if qrs := NewQueryResults("up", nil); !IsCommError(qrs.Error) {
+		t.Fatalf("Expected CommError for nil result, got: %v", qrs.Error)
+	}
+
+	qrs := newTestQueryResults(t, "up", `{"status":"error","errorType":"bad_data","error":"invalid parameter"}`)
+	if qrs.Error == nil || qrs.Error.Error() != "'invalid parameter' parsing query 'up'" {
+		t.Fatalf("Unexpected error: %v", qrs.Error)
+	}
+}
+
+func TestQueryResultsSingleValue(t *testing.T) {
+	testCases := map[string]struct {
+		body         string
+		expected     float64
+		expectError  bool
+		expectNoData bool
+	}{
+		"single value": {
+			body:     `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"12"]}]}}`,
+			expected: 12,
+		},
+		"no results": {
+			body:         `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			expectError:  true,
+			expectNoData: true,
+		},
+		"multiple series": {
+			body:        `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":[1622505600,"1"]},{"metric":{"job":"b"},"value":[1622505600,"2"]}]}}`,
+			expectError: true,
+		},
+		"multiple samples": {
+			body:        `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1622505600,"1"],[1622505660,"2"]]}]}}`,
+			expectError: true,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			qrs := newTestQueryResults(t, "count(up)", test.body)
+
+			value, err := qrs.SingleValue()
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("Expected error, got value %f", value)
+				}
+				if IsNoDataError(err) != test.expectNoData {
+					t.Fatalf("Unexpected NoDataError classification for error: %s", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if value != test.expected {
+				t.Fatalf("SingleValue(): exp (%f); act (%f)", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestQueryResultGetLabel(t *testing.T) {
+	qr := &QueryResult{
+		Metric: map[string]interface{}{
+			"namespace": "kubecost",
+			"pod":       "",
+			"count":     3.0,
+		},
+	}
+
+	testCases := map[string]struct {
+		label         string
+		expected      string
+		expectedOk    bool
+		expectedOrDef string
+	}{
+		"present": {
+			label:         "namespace",
+			expected:      "kubecost",
+			expectedOk:    true,
+			expectedOrDef: "kubecost",
+		},
+		"absent": {
+			label:         "container",
+			expected:      "",
+			expectedOk:    false,
+			expectedOrDef: "default",
+		},
+		"empty value": {
+			label:         "pod",
+			expected:      "",
+			expectedOk:    true,
+			expectedOrDef: "",
+		},
+		"non-string value": {
+			label:         "count",
+			expected:      "",
+			expectedOk:    false,
+			expectedOrDef: "default",
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			value, ok := qr.GetLabel(test.label)
+			if value != test.expected || ok != test.expectedOk {
+				t.Fatalf("GetLabel(%s): exp (%s, %t); act (%s, %t)", test.label, test.expected, test.expectedOk, value, ok)
+			}
+
+			orDef := qr.GetLabelOr(test.label, "default")
+			if orDef != test.expectedOrDef {
+				t.Fatalf("GetLabelOr(%s): exp (%s); act (%s)", test.label, test.expectedOrDef, orDef)
+			}
+		})
+	}
+
+	var nilResult *QueryResult
+	if _, ok := nilResult.GetLabel("namespace"); ok {
+		t.Fatalf("GetLabel on nil QueryResult: exp (false); act (true)")
+	}
+}
+
+func TestQueryResultGetNumericLabel(t *testing.T) {
+	qr := &QueryResult{
+		Metric: map[string]interface{}{
+			"cpu":    "3",
+			"ratio":  "0.25",
+			"device": "eth0",
+		},
+	}
+
+	testCases := map[string]struct {
+		label         string
+		expectedFloat float64
+		expectedInt   int64
+		floatError    bool
+		intError      bool
+	}{
+		"integer": {
+			label:         "cpu",
+			expectedFloat: 3,
+			expectedInt:   3,
+		},
+		"float": {
+			label:         "ratio",
+			expectedFloat: 0.25,
+			intError:      true,
+		},
+		"non-numeric": {
+			label:      "device",
+			floatError: true,
+			intError:   true,
+		},
+		"missing": {
+			label:      "port",
+			floatError: true,
+			intError:   true,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			f, err := qr.GetFloatLabel(test.label)
+			if (err != nil) != test.floatError {
+				t.Fatalf("GetFloatLabel(%s): unexpected error result: %v", test.label, err)
+			}
+			if f != test.expectedFloat {
+				t.Fatalf("GetFloatLabel(%s): exp (%f); act (%f)", test.label, test.expectedFloat, f)
+			}
+
+			i, err := qr.GetIntLabel(test.label)
+			if (err != nil) != test.intError {
+				t.Fatalf("GetIntLabel(%s): unexpected error result: %v", test.label, err)
+			}
+			if i != test.expectedInt {
+				t.Fatalf("GetIntLabel(%s): exp (%d); act (%d)", test.label, test.expectedInt, i)
+			}
+		})
+	}
+}
+
+func TestQueryResultsFilterByLabel(t *testing.T) {
+	kubecost := &QueryResult{Metric: map[string]interface{}{"namespace": "kubecost", "pod": "cost-model-0"}}
+	kubeSystem := &QueryResult{Metric: map[string]interface{}{"namespace": "kube-system", "pod": "coredns-0"}}
+	unlabeled := &QueryResult{Metric: map[string]interface{}{"pod": "orphan"}}
+
+	qrs := &QueryResults{Results: []*QueryResult{kubecost, kubeSystem, unlabeled}}
+
+	testCases := map[string]struct {
+		actual   []*QueryResult
+		expected []*QueryResult
+	}{
+		"equal match": {
+			actual:   qrs.FilterByLabel("namespace", "kubecost"),
+			expected: []*QueryResult{kubecost},
+		},
+		"no match": {
+			actual:   qrs.FilterByLabel("namespace", "default"),
+			expected: nil,
+		},
+		"empty value does not match missing label": {
+			actual:   qrs.FilterByLabel("namespace", ""),
+			expected: nil,
+		},
+		"regex match": {
+			actual:   qrs.FilterByLabelRegex("namespace", regexp.MustCompile(`^kube`)),
+			expected: []*QueryResult{kubecost, kubeSystem},
+		},
+		"anchored regex match": {
+			actual:   qrs.FilterByLabelRegex("pod", regexp.MustCompile(`^.*-0$`)),
+			expected: []*QueryResult{kubecost, kubeSystem},
+		},
+		"nil results": {
+			actual:   (*QueryResults)(nil).FilterByLabel("namespace", "kubecost"),
+			expected: nil,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.actual, test.expected) {
+				t.Fatalf("exp (%+v); act (%+v)", test.expected, test.actual)
+			}
+		})
+	}
+}
+
+func TestQueryResultsGroupByLabel(t *testing.T) {
+	a := &QueryResult{Metric: map[string]interface{}{"namespace": "kubecost", "node": "node-1"}}
+	b := &QueryResult{Metric: map[string]interface{}{"namespace": "kubecost", "node": "node-2"}}
+	c := &QueryResult{Metric: map[string]interface{}{"namespace": "kube-system", "node": "node-1"}}
+	d := &QueryResult{Metric: map[string]interface{}{"node": "node-1"}}
+
+	qrs := &QueryResults{Results: []*QueryResult{a, b, c, d}}
+
+	testCases := map[string]struct {
+		actual   map[string][]*QueryResult
+		expected map[string][]*QueryResult
+	}{
+		"single label": {
+			actual: qrs.GroupByLabel("namespace"),
+			expected: map[string][]*QueryResult{
+				"kubecost":        {a, b},
+				"kube-system":     {c},
+				MissingLabelGroup: {d},
+			},
+		},
+		"multiple labels": {
+			actual: qrs.GroupByLabels("namespace", "node"),
+			expected: map[string][]*QueryResult{
+				"kubecost/node-1":             {a},
+				"kubecost/node-2":             {b},
+				"kube-system/node-1":          {c},
+				MissingLabelGroup + "/node-1": {d},
+			},
+		},
+		"no labels": {
+			actual: qrs.GroupByLabels(),
+			expected: map[string][]*QueryResult{
+				"": {a, b, c, d},
+			},
+		},
+		"nil results": {
+			actual:   (*QueryResults)(nil).GroupByLabel("namespace"),
+			expected: map[string][]*QueryResult{},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.actual, test.expected) {
+				t.Fatalf("exp (%+v); act (%+v)", test.expected, test.actual)
+			}
+		})
+	}
+}
+
+func TestQueryResultsScalarsByLabel(t *testing.T) {
+	result := func(job string, value float64) *QueryResult {
+		metric := map[string]interface{}{}
+		if job != "" {
+			metric["job"] = job
+		}
+		return &QueryResult{Metric: metric, Values: []*util.Vector{{Timestamp: 1622505600, Value: value}}}
+	}
+
+	testCases := map[string]struct {
+		results       []*QueryResult
+		sumDuplicates bool
+		expected      map[string]float64
+		expectErr     bool
+	}{
+		"unique keys": {
+			results:  []*QueryResult{result("prometheus", 1), result("kubecost", 0)},
+			expected: map[string]float64{"prometheus": 1, "kubecost": 0},
+		},
+		"missing label": {
+			results:  []*QueryResult{result("prometheus", 1), result("", 1)},
+			expected: map[string]float64{"prometheus": 1, MissingLabelGroup: 1},
+		},
+		"no values": {
+			results:  []*QueryResult{result("prometheus", 1), {Metric: map[string]interface{}{"job": "kubecost"}}},
+			expected: map[string]float64{"prometheus": 1},
+		},
+		"duplicate keys": {
+			results:   []*QueryResult{result("node-exporter", 1), result("node-exporter", 1)},
+			expectErr: true,
+		},
+		"summed duplicate keys": {
+			results:       []*QueryResult{result("node-exporter", 1), result("node-exporter", 1), result("kubecost", 1)},
+			sumDuplicates: true,
+			expected:      map[string]float64{"node-exporter": 2, "kubecost": 1},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			qrs := &QueryResults{Query: "up", Results: test.results}
+
+			scalars, err := qrs.ScalarsByLabel("job", test.sumDuplicates)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("Expected error for duplicate keys")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(scalars, test.expected) {
+				t.Fatalf("exp (%+v); act (%+v)", test.expected, scalars)
+			}
+		})
+	}
+}
+
+func TestQueryResultAggregateValues(t *testing.T) {
+	testCases := map[string]struct {
+		values               []*util.Vector
+		step                 time.Duration
+		expectedSum          float64
+		expectedAverage      float64
+		expectedTimeWeighted float64
+	}{
+		"empty": {
+			values:               nil,
+			step:                 time.Minute,
+			expectedSum:          0,
+			expectedAverage:      0,
+			expectedTimeWeighted: 0,
+		},
+		"single sample": {
+			values:               []*util.Vector{{Timestamp: 0, Value: 4}},
+			step:                 time.Minute,
+			expectedSum:          4,
+			expectedAverage:      4,
+			expectedTimeWeighted: 4,
+		},
+		"evenly spaced": {
+			values:               []*util.Vector{{Timestamp: 0, Value: 1}, {Timestamp: 60, Value: 2}, {Timestamp: 120, Value: 3}},
+			step:                 time.Minute,
+			expectedSum:          6,
+			expectedAverage:      2,
+			expectedTimeWeighted: 2,
+		},
+		"gap capped at step": {
+			// the 1 sample is weighted by a single step rather than the 9 minute gap
+			values:               []*util.Vector{{Timestamp: 0, Value: 1}, {Timestamp: 540, Value: 3}},
+			step:                 time.Minute,
+			expectedSum:          4,
+			expectedAverage:      2,
+			expectedTimeWeighted: 2,
+		},
+		"uneven spacing": {
+			// 2 is weighted by 30s, 4 by 60s, and the last sample by a step of 60s
+			values:               []*util.Vector{{Timestamp: 0, Value: 2}, {Timestamp: 30, Value: 4}, {Timestamp: 90, Value: 4}},
+			step:                 time.Minute,
+			expectedSum:          10,
+			expectedAverage:      10.0 / 3.0,
+			expectedTimeWeighted: 3.6,
+		},
+		"non-positive step": {
+			values:               []*util.Vector{{Timestamp: 0, Value: 2}, {Timestamp: 30, Value: 4}, {Timestamp: 90, Value: 6}},
+			step:                 0,
+			expectedSum:          12,
+			expectedAverage:      4,
+			expectedTimeWeighted: 4,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			qr := &QueryResult{Values: test.values}
+
+			if sum := qr.SumValues(); !util.IsApproximately(sum, test.expectedSum) {
+				t.Fatalf("SumValues: exp (%f); act (%f)", test.expectedSum, sum)
+			}
+			if avg := qr.AverageValues(); !util.IsApproximately(avg, test.expectedAverage) {
+				t.Fatalf("AverageValues: exp (%f); act (%f)", test.expectedAverage, avg)
+			}
+			if twa := qr.TimeWeightedAverage(test.step); !util.IsApproximately(twa, test.expectedTimeWeighted) {
+				t.Fatalf("TimeWeightedAverage: exp (%f); act (%f)", test.expectedTimeWeighted, twa)
+			}
+		})
+	}
+}
+
+func TestQueryResultFingerprint(t *testing.T) {
+	a := &QueryResult{Metric: map[string]interface{}{"namespace": "kubecost", "pod": "cost-model"}}
+	b := &QueryResult{Metric: map[string]interface{}{"pod": "cost-model", "namespace": "kubecost"}}
+	c := &QueryResult{Metric: map[string]interface{}{"namespace": "kubecost", "pod": "prometheus"}}
+
+	// label names and values are separated, so shifting characters between them differs
+	d := &QueryResult{Metric: map[string]interface{}{"namespacek": "ubecost", "pod": "cost-model"}}
+
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Fatalf("Expected equal fingerprints for the same labels in a different order")
+	}
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Fatalf("Expected different fingerprints for different label values")
+	}
+	if a.Fingerprint() == d.Fingerprint() {
+		t.Fatalf("Expected different fingerprints for shifted label names and values")
+	}
+
+	qrs := &QueryResults{Results: []*QueryResult{a, c}}
+	byFingerprint := qrs.ByFingerprint()
+	if len(byFingerprint) != 2 {
+		t.Fatalf("ByFingerprint: exp (%d); act (%d)", 2, len(byFingerprint))
+	}
+	if byFingerprint[b.Fingerprint()] != a {
+		t.Fatalf("Expected lookup by an equivalent series to return the result")
+	}
+
+	var empty *QueryResults
+	if len(empty.ByFingerprint()) != 0 {
+		t.Fatalf("Expected empty map for nil results")
+	}
+}
+
+func TestMergeQueryResults(t *testing.T) {
+	kubecost := map[string]interface{}{"namespace": "kubecost"}
+	system := map[string]interface{}{"namespace": "kube-system"}
+
+	first := []*QueryResult{
+		{Metric: kubecost, Values: []*util.Vector{{Timestamp: 60, Value: 2}, {Timestamp: 120, Value: 3}}},
+		{Metric: system, Values: []*util.Vector{{Timestamp: 60, Value: 5}}},
+	}
+	// overlaps the first set at 120, the value of which is dropped in favor of the first set
+	second := []*QueryResult{
+		{Metric: map[string]interface{}{"namespace": "kubecost"}, Values: []*util.Vector{{Timestamp: 180, Value: 4}, {Timestamp: 120, Value: 9}, {Timestamp: 0, Value: 1}}},
+		{Metric: map[string]interface{}{"namespace": "default"}, Values: []*util.Vector{{Timestamp: 0, Value: 7}}},
+	}
+
+	merged := MergeQueryResults(first, nil, second)
+	if len(merged) != 3 {
+		t.Fatalf("results: exp (3); act (%d)", len(merged))
+	}
+
+	expected := []struct {
+		namespace string
+		values    []util.Vector
+	}{
+		{"kubecost", []util.Vector{{Timestamp: 0, Value: 1}, {Timestamp: 60, Value: 2}, {Timestamp: 120, Value: 3}, {Timestamp: 180, Value: 4}}},
+		{"kube-system", []util.Vector{{Timestamp: 60, Value: 5}}},
+		{"default", []util.Vector{{Timestamp: 0, Value: 7}}},
+	}
+	for i, exp := range expected {
+		if ns, _ := merged[i].GetString("namespace"); ns != exp.namespace {
+			t.Fatalf("result %d namespace: exp (%s); act (%s)", i, exp.namespace, ns)
+		}
+
+		var values []util.Vector
+		for _, v := range merged[i].Values {
+			values = append(values, *v)
+		}
+		if !reflect.DeepEqual(values, exp.values) {
+			t.Fatalf("result %d values: exp (%v); act (%v)", i, exp.values, values)
+		}
+	}
+
+	// the input values are left in their original order
+	if second[0].Values[0].Timestamp != 180 {
+		t.Fatalf("Expected input values to be unmodified")
+	}
+
+	if merged := MergeQueryResults(); len(merged) != 0 {
+		t.Fatalf("Expected no results when merging no sets")
+	}
+}
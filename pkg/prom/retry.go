@@ -0,0 +1,74 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/util/retry"
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// WithRetry retries failed Query and QueryRange requests up to a total of attempts, waiting delay
+// (plus jitter) between attempts. Client errors, ie: an invalid query, are not retried, with the
+// exception of 429 Too Many Requests, and neither are query validation errors. An attempts value
+// <= 1 disables retries, which is the default. Returns the Context to allow chaining.
+func (ctx *Context) WithRetry(attempts uint, delay time.Duration) *Context {
+	ctx.retryAttempts = attempts
+	ctx.retryDelay = delay
+	return ctx
+}
+
+// isRetryableQueryError returns true if the query error may succeed on a subsequent attempt
+func isRetryableQueryError(err error) bool {
+	if IsQueryValidationError(err) || IsAuthError(err) {
+		return false
+	}
+
+	var ce CommError
+	if errors.As(err, &ce) && ce.IsClientError() {
+		return ce.StatusCode == http.StatusTooManyRequests
+	}
+
+	return err != nil
+}
+
+// retryQuery runs the query, retrying retryable errors if enabled for the context. Retries and
+// exhausted retries are recorded in the query retry metrics.
+func (ctx *Context) retryQuery(reqCtx context.Context, query func(context.Context) (*QueryResults, prometheus.Warnings, error)) (*QueryResults, prometheus.Warnings, error) {
+	if ctx.retryAttempts <= 1 {
+		return query(reqCtx)
+	}
+
+	var results *QueryResults
+	var warnings prometheus.Warnings
+	var err error
+
+	var attempt uint
+	_, retryErr := retry.Retry(reqCtx, func() (interface{}, error) {
+		if attempt > 0 {
+			ctx.recordQueryRetry()
+		}
+		attempt++
+
+		results, warnings, err = query(reqCtx)
+
+		// errors which are not retryable end the retries, and are returned as-is
+		if isRetryableQueryError(err) {
+			return nil, err
+		}
+		return nil, nil
+	}, ctx.retryAttempts, ctx.retryDelay)
+
+	// cancelled before the first attempt completed
+	if results == nil && err == nil {
+		return nil, nil, retryErr
+	}
+
+	if isRetryableQueryError(err) && attempt == ctx.retryAttempts {
+		ctx.recordQueryRetryExhausted()
+	}
+
+	return results, warnings, err
+}
@@ -0,0 +1,132 @@
+package prom
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// flakyHandler returns a promtest.Handler which responds with each of the statuses in order, and 200
+// once exhausted
+func flakyHandler(statuses ...int) promtest.Handler {
+	var lock sync.Mutex
+	calls := 0
+
+	return func(ctx context.Context, req *http.Request) *promtest.Response {
+		lock.Lock()
+		defer lock.Unlock()
+
+		status := http.StatusOK
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+
+		if status != http.StatusOK {
+			return &promtest.Response{StatusCode: status, Body: []byte(http.StatusText(status))}
+		}
+		return &promtest.Response{StatusCode: status, Body: promtest.VectorBody(nil)}
+	}
+}
+
+func TestQueryRetry(t *testing.T) {
+	testCases := map[string]struct {
+		statuses          []int
+		attempts          uint
+		expectedCalls     int
+		expectedError     bool
+		expectedRetries   float64
+		expectedExhausted float64
+	}{
+		"recovers": {
+			statuses:          []int{http.StatusServiceUnavailable, http.StatusBadGateway},
+			attempts:          3,
+			expectedCalls:     3,
+			expectedError:     false,
+			expectedRetries:   2,
+			expectedExhausted: 0,
+		},
+		"exhausted": {
+			statuses:          []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			attempts:          3,
+			expectedCalls:     3,
+			expectedError:     true,
+			expectedRetries:   2,
+			expectedExhausted: 1,
+		},
+		"client error not retried": {
+			statuses:          []int{http.StatusBadRequest},
+			attempts:          3,
+			expectedCalls:     1,
+			expectedError:     true,
+			expectedRetries:   0,
+			expectedExhausted: 0,
+		},
+		"too many requests retried": {
+			statuses:          []int{http.StatusTooManyRequests},
+			attempts:          3,
+			expectedCalls:     2,
+			expectedError:     false,
+			expectedRetries:   1,
+			expectedExhausted: 0,
+		},
+		"disabled": {
+			statuses:          []int{http.StatusServiceUnavailable},
+			attempts:          0,
+			expectedCalls:     1,
+			expectedError:     true,
+			expectedRetries:   0,
+			expectedExhausted: 0,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetHandler(flakyHandler(test.statuses...))
+			ctx := NewNamedContext(client, "retry-test-"+name).WithRetry(test.attempts, time.Millisecond)
+
+			// the context name is unknown, so the metrics are recorded with the bounded other label
+			initQueryMetrics()
+			retriesBefore := testutil.ToFloat64(queryRetriesCv.WithLabelValues(otherContextName))
+			exhaustedBefore := testutil.ToFloat64(queryRetryExhaustedCv.WithLabelValues(otherContextName))
+
+			results := <-ctx.Query("up")
+			if calls := len(client.Requests()); calls != test.expectedCalls {
+				t.Fatalf("Calls: exp (%d); act (%d)", test.expectedCalls, calls)
+			}
+			if ctx.HasErrors() != test.expectedError {
+				t.Fatalf("Error: exp (%t); act (%t): %v", test.expectedError, ctx.HasErrors(), results.Error)
+			}
+
+			if retries := testutil.ToFloat64(queryRetriesCv.WithLabelValues(otherContextName)) - retriesBefore; retries != test.expectedRetries {
+				t.Fatalf("Retries: exp (%f); act (%f)", test.expectedRetries, retries)
+			}
+			if exhausted := testutil.ToFloat64(queryRetryExhaustedCv.WithLabelValues(otherContextName)) - exhaustedBefore; exhausted != test.expectedExhausted {
+				t.Fatalf("Exhausted: exp (%f); act (%f)", test.expectedExhausted, exhausted)
+			}
+		})
+	}
+}
+
+func TestQueryRetryContextLabel(t *testing.T) {
+	client := promtest.NewClient().SetHandler(flakyHandler(http.StatusServiceUnavailable))
+	ctx := NewNamedContext(client, AllocationContextName).WithRetry(2, time.Millisecond)
+
+	initQueryMetrics()
+	before := testutil.ToFloat64(queryRetriesCv.WithLabelValues(AllocationContextName))
+
+	ctx.Query("up").Await()
+
+	// known context names are used as-is
+	if retries := testutil.ToFloat64(queryRetriesCv.WithLabelValues(AllocationContextName)) - before; retries != 1 {
+		t.Fatalf("Retries: exp (1); act (%f)", retries)
+	}
+	if label := inflightContextName("caller-chosen-name"); label != otherContextName {
+		t.Fatalf("label: exp (%s); act (%s)", otherContextName, label)
+	}
+}
@@ -0,0 +1,86 @@
+package prom
+
+import (
+	"time"
+)
+
+const (
+	epAlerts = apiPrefix + "/alerts"
+	epRules  = apiPrefix + "/rules"
+)
+
+// Rule types returned by the rules endpoint
+const (
+	RuleTypeAlerting  = "alerting"
+	RuleTypeRecording = "recording"
+)
+
+// Alert is an active (pending or firing) alert of an alerting rule
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	State       string            `json:"state"`
+	ActiveAt    *time.Time        `json:"activeAt,omitempty"`
+	Value       string            `json:"value"`
+}
+
+// Rule is an alerting or recording rule, differentiated by Type. The Duration, Annotations,
+// Alerts, and State fields are only set for alerting rules.
+type Rule struct {
+	Type           string            `json:"type"`
+	Name           string            `json:"name"`
+	Query          string            `json:"query"`
+	Labels         map[string]string `json:"labels"`
+	Health         string            `json:"health"`
+	LastError      string            `json:"lastError"`
+	EvaluationTime float64           `json:"evaluationTime"`
+	LastEvaluation time.Time         `json:"lastEvaluation"`
+
+	Duration    float64           `json:"duration"`
+	Annotations map[string]string `json:"annotations"`
+	Alerts      []*Alert          `json:"alerts"`
+	State       string            `json:"state"`
+}
+
+// RuleGroup is a group of rules which are evaluated together at the group interval
+type RuleGroup struct {
+	Name           string    `json:"name"`
+	File           string    `json:"file"`
+	Interval       float64   `json:"interval"`
+	EvaluationTime float64   `json:"evaluationTime"`
+	LastEvaluation time.Time `json:"lastEvaluation"`
+	Rules          []*Rule   `json:"rules"`
+}
+
+// alertsData is the data field of the alerts endpoint response
+type alertsData struct {
+	Alerts []*Alert `json:"alerts"`
+}
+
+// rulesData is the data field of the rules endpoint response
+type rulesData struct {
+	Groups []*RuleGroup `json:"groups"`
+}
+
+// Alerts returns the active alerts of the Prometheus server. Servers which do not evaluate rules
+// (ie: Thanos Query without a rule component) may return an UnsupportedEndpointError.
+func (ctx *Context) Alerts() ([]*Alert, error) {
+	var data alertsData
+	if err := ctx.apiGet(epAlerts, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Alerts, nil
+}
+
+// Rules returns the alerting and recording rule groups loaded by the Prometheus server, including
+// the health and last evaluation of each rule. Servers which do not evaluate rules may return an
+// UnsupportedEndpointError.
+func (ctx *Context) Rules() ([]*RuleGroup, error) {
+	var data rulesData
+	if err := ctx.apiGet(epRules, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Groups, nil
+}
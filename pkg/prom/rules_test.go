@@ -0,0 +1,76 @@
+package prom
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestAlerts(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"alerts":[{"labels":{"alertname":"CostAnomaly","namespace":"kubecost"},"annotations":{"summary":"Cost increased"},"state":"firing","activeAt":"2021-06-01T00:00:00.000Z","value":"1.5e+00"}]}}`)})
+	ctx := NewContext(client)
+
+	alerts, err := ctx.Alerts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Alerts: exp (%d); act (%d)", 1, len(alerts))
+	}
+
+	alert := alerts[0]
+	if alert.Labels["alertname"] != "CostAnomaly" || alert.State != "firing" || alert.Value != "1.5e+00" {
+		t.Errorf("Unexpected alert: %+v", alert)
+	}
+	if alert.ActiveAt == nil || alert.ActiveAt.Unix() != 1622505600 {
+		t.Errorf("Unexpected active at: %v", alert.ActiveAt)
+	}
+
+	req := client.Requests()[0]
+	if req.Method != http.MethodGet || req.URL.Path != epAlerts {
+		t.Errorf("Unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+}
+
+func TestRules(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"groups":[{"name":"kubecost","file":"/etc/prometheus/rules.yml","interval":60,"rules":[` +
+		`{"type":"recording","name":"kubecost_cluster_memory_working_set_bytes","query":"sum(container_memory_working_set_bytes)","health":"ok","lastError":"","evaluationTime":0.002,"lastEvaluation":"2021-06-01T00:00:00Z"},` +
+		`{"type":"alerting","name":"CostAnomaly","query":"kubecost_cost > 100","duration":300,"labels":{"severity":"warning"},"annotations":{"summary":"Cost increased"},"alerts":[{"labels":{"alertname":"CostAnomaly"},"state":"pending","value":"101"}],"state":"pending","health":"ok","lastEvaluation":"2021-06-01T00:00:00Z"}` +
+		`]}]}}`)})
+	ctx := NewContext(client)
+
+	groups, err := ctx.Rules()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(groups) != 1 || len(groups[0].Rules) != 2 {
+		t.Fatalf("Unexpected groups: %+v", groups)
+	}
+	if groups[0].Name != "kubecost" || groups[0].Interval != 60 {
+		t.Errorf("Unexpected group: %+v", groups[0])
+	}
+
+	recording := groups[0].Rules[0]
+	if recording.Type != RuleTypeRecording || recording.Health != "ok" || recording.LastEvaluation.Unix() != 1622505600 {
+		t.Errorf("Unexpected recording rule: %+v", recording)
+	}
+
+	alerting := groups[0].Rules[1]
+	if alerting.Type != RuleTypeAlerting || alerting.Duration != 300 || alerting.State != "pending" || len(alerting.Alerts) != 1 {
+		t.Errorf("Unexpected alerting rule: %+v", alerting)
+	}
+
+	if req := client.Requests()[0]; req.URL.Path != epRules {
+		t.Errorf("Unexpected request: %s", req.URL.Path)
+	}
+}
+
+func TestRulesUnsupported(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: http.StatusNotFound, Body: []byte("404 page not found")})
+
+	_, err := NewContext(client).Rules()
+	if !IsUnsupportedEndpointError(err) {
+		t.Fatalf("Expected UnsupportedEndpointError, got: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// QueryPriority is the priority of the queries made by a Context when a priority scheduler is
+// enabled using WithPriorityScheduler. Higher priority queries are sent before lower priority
+// queries waiting for a worker.
+type QueryPriority int
+
+const (
+	// PriorityLow is used for background queries which may be delayed, ie: allocation batches
+	PriorityLow QueryPriority = -1
+
+	// PriorityNormal is the default priority of queries
+	PriorityNormal QueryPriority = 0
+
+	// PriorityHigh is used for interactive queries, ie: health checks and UI requests
+	PriorityHigh QueryPriority = 1
+)
+
+// String returns the name of the priority
+func (p QueryPriority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+
+	return fmt.Sprintf("priority(%d)", int(p))
+}
+
+// WithPriorityScheduler limits the number of requests the Context sends to Prometheus at a time to
+// the number of workers, queueing the remaining requests by priority. When a worker is free, the
+// highest priority request waiting is sent next, and requests of the same priority are sent in the
+// order they were queued. This prevents large background workloads from starving interactive
+// queries when Prometheus is busy. Copies of the Context made with WithName or WithPriority share
+// the scheduler with the original. A value <= 0 disables the scheduler, which is the default.
+// Returns the Context to allow chaining.
+func (ctx *Context) WithPriorityScheduler(workers int) *Context {
+	if workers <= 0 {
+		ctx.scheduler = nil
+	} else {
+		ctx.scheduler = newQueryScheduler(workers)
+	}
+	return ctx
+}
+
+// WithPriority returns a copy of the Context which queries at the provided priority, so a single
+// Context may be used for both background and interactive queries. The copy shares the client,
+// error collector, and all options of the original. The priority has no effect unless a priority
+// scheduler is enabled using WithPriorityScheduler.
+func (ctx *Context) WithPriority(priority QueryPriority) *Context {
+	clone := *ctx
+	clone.priority = priority
+	return &clone
+}
+
+// Priority returns the priority of the queries made by the Context
+func (ctx *Context) Priority() QueryPriority {
+	return ctx.priority
+}
+
+// schedule blocks until the scheduler of the Context has a worker available for the query, and
+// returns a function which must be called to release the worker once the request completes. If
+// reqCtx is done before a worker is available, an error is returned instead. Contexts without a
+// scheduler return immediately.
+func (ctx *Context) schedule(reqCtx context.Context, query string) (func(), error) {
+	if ctx.scheduler == nil {
+		return func() {}, nil
+	}
+
+	if err := ctx.scheduler.acquire(reqCtx, ctx.priority); err != nil {
+		return nil, fmt.Errorf("query error: '%s' scheduling query '%s'%s", err.Error(), query, requestIDSuffix(reqCtx))
+	}
+
+	return ctx.scheduler.release, nil
+}
+
+// queryWaiter is a request waiting for a worker of the queryScheduler
+type queryWaiter struct {
+	priority QueryPriority
+	ready    chan struct{}
+}
+
+// queryScheduler limits the number of concurrent requests to a fixed number of workers, and hands
+// each worker released to the highest priority waiter, oldest first.
+type queryScheduler struct {
+	lock    sync.Mutex
+	workers int
+	running int
+
+	// waiting is in the order requests were queued
+	waiting []*queryWaiter
+}
+
+// newQueryScheduler creates a new queryScheduler with the provided number of workers
+func newQueryScheduler(workers int) *queryScheduler {
+	return &queryScheduler{
+		workers: workers,
+	}
+}
+
+// acquire blocks until a worker is available for a request at the provided priority, or returns
+// the error of reqCtx if it is done first.
+func (qs *queryScheduler) acquire(reqCtx context.Context, priority QueryPriority) error {
+	qs.lock.Lock()
+	if qs.running < qs.workers && len(qs.waiting) == 0 {
+		qs.running++
+		qs.lock.Unlock()
+		return nil
+	}
+
+	w := &queryWaiter{
+		priority: priority,
+		ready:    make(chan struct{}),
+	}
+	qs.waiting = append(qs.waiting, w)
+	qs.lock.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-reqCtx.Done():
+	}
+
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+
+	// the worker may have been handed off after reqCtx was done, in which case it is passed on
+	if !qs.remove(w) {
+		qs.handoff()
+	}
+
+	return reqCtx.Err()
+}
+
+// release frees the worker of a completed request
+func (qs *queryScheduler) release() {
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+
+	qs.handoff()
+}
+
+// handoff passes a released worker to the next waiter, if any. Must be called with the lock held.
+func (qs *queryScheduler) handoff() {
+	if len(qs.waiting) == 0 {
+		qs.running--
+		return
+	}
+
+	next := 0
+	for i, w := range qs.waiting {
+		if w.priority > qs.waiting[next].priority {
+			next = i
+		}
+	}
+
+	w := qs.waiting[next]
+	qs.waiting = append(qs.waiting[:next], qs.waiting[next+1:]...)
+	close(w.ready)
+}
+
+// remove removes the waiter from the queue, returning false if it was not waiting. Must be called
+// with the lock held.
+func (qs *queryScheduler) remove(w *queryWaiter) bool {
+	for i, waiting := range qs.waiting {
+		if waiting == w {
+			qs.waiting = append(qs.waiting[:i], qs.waiting[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// queued returns the number of requests waiting for a worker
+func (qs *queryScheduler) queued() int {
+	qs.lock.Lock()
+	defer qs.lock.Unlock()
+
+	return len(qs.waiting)
+}
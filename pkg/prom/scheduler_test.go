@@ -0,0 +1,89 @@
+package prom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestPriorityScheduler(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	client := promtest.NewClient().SetHandler(gatedHandler(started, release, promtest.VectorBody(nil)))
+	ctx := NewContext(client).WithPriorityScheduler(1)
+
+	// occupy the only worker
+	first := ctx.WithPriority(PriorityLow).Query("first")
+	<-started
+
+	low := ctx.WithPriority(PriorityLow).Query("low")
+	waitQueued(t, ctx.scheduler, 1)
+	normal := ctx.Query("normal")
+	waitQueued(t, ctx.scheduler, 2)
+	high := ctx.WithPriority(PriorityHigh).Query("high")
+	waitQueued(t, ctx.scheduler, 3)
+
+	for _, resCh := range []QueryResultsChan{first, high, normal, low} {
+		release <- struct{}{}
+		if _, err := resCh.Await(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	expected := []string{"first", "high", "normal", "low"}
+	for i, query := range client.Queries() {
+		if query != expected[i] {
+			t.Fatalf("query %d: exp (%s); act (%s)", i, expected[i], query)
+		}
+	}
+}
+
+func TestPrioritySchedulerCancelled(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	client := promtest.NewClient().SetHandler(gatedHandler(started, release, promtest.VectorBody(nil)))
+	ctx := NewContext(client).WithPriorityScheduler(1)
+
+	first := ctx.Query("first")
+	<-started
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, _, err := ctx.rawQuery(reqCtx, "cancelled", ctx.queryTime(), "")
+		errCh <- err
+	}()
+	waitQueued(t, ctx.scheduler, 1)
+
+	cancel()
+	if err := <-errCh; err == nil {
+		t.Fatalf("Expected error for query cancelled while queued")
+	}
+	if queued := ctx.scheduler.queued(); queued != 0 {
+		t.Fatalf("queued: exp (0); act (%d)", queued)
+	}
+
+	release <- struct{}{}
+	first.Await()
+
+	// the worker is released, so a new query runs immediately
+	second := ctx.Query("second")
+	<-started
+	release <- struct{}{}
+	if _, err := second.Await(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+// waitQueued waits for the number of requests waiting for a worker of the scheduler to reach n
+func waitQueued(t *testing.T, qs *queryScheduler, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for qs.queued() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued: exp (%d); act (%d)", n, qs.queued())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
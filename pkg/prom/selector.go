@@ -0,0 +1,172 @@
+package prom
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/util/timeutil"
+)
+
+// MatchOp is a PromQL label matching operator
+type MatchOp string
+
+const (
+	MatchEqual     MatchOp = "="
+	MatchNotEqual  MatchOp = "!="
+	MatchRegexp    MatchOp = "=~"
+	MatchNotRegexp MatchOp = "!~"
+)
+
+// labelValueReplacer escapes characters which are not permitted unescaped in a double
+// quoted PromQL string literal.
+var labelValueReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+// EscapeLabelValue escapes the backslashes, double quotes, and newlines of a label value so it
+// may be safely interpolated into a double quoted PromQL string, ie: namespace="%s".
+func EscapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+// EscapeRegexValue escapes all regular expression metacharacters in the value, so a regex matcher
+// (=~ or !~) matches it literally, ie: a pod named "nginx-1.2" becomes nginx-1\.2. The result is a
+// regex rather than a PromQL string, so EscapeLabelValue must still be applied when interpolating
+// it into a query by hand. LabelMatcher.String() applies EscapeLabelValue to all values.
+func EscapeRegexValue(v string) string {
+	return regexp.QuoteMeta(v)
+}
+
+// LabelMatcher is a single label matcher within a vector selector, ie: namespace="kubecost"
+type LabelMatcher struct {
+	Name  string
+	Op    MatchOp
+	Value string
+}
+
+// String returns the PromQL representation of the label matcher with the value quoted
+// and escaped.
+func (lm *LabelMatcher) String() string {
+	return fmt.Sprintf(`%s%s"%s"`, lm.Name, lm.Op, EscapeLabelValue(lm.Value))
+}
+
+// VectorSelector is used to build PromQL vector selectors without hand-formatting the
+// label matchers, ie:
+//
+//	NewVectorSelector("kube_pod_labels").WithLabel("namespace", "=", ns).Offset(time.Hour).String()
+//
+// Label values are escaped, so values containing quotes or backslashes cannot
+// break out of the matcher.
+type VectorSelector struct {
+	metric   string
+	matchers []*LabelMatcher
+	rng      time.Duration
+	offset   time.Duration
+	at       string
+}
+
+// NewVectorSelector creates a new VectorSelector for the provided metric name.
+func NewVectorSelector(metric string) *VectorSelector {
+	return &VectorSelector{
+		metric: metric,
+	}
+}
+
+// WithLabel appends a label matcher using the provided operator to the selector.
+func (vs *VectorSelector) WithLabel(name string, op MatchOp, value string) *VectorSelector {
+	vs.matchers = append(vs.matchers, &LabelMatcher{
+		Name:  name,
+		Op:    op,
+		Value: value,
+	})
+	return vs
+}
+
+// Range converts the selector into a range vector selector over the provided duration,
+// ie: container_cpu_usage_seconds_total[5m]
+func (vs *VectorSelector) Range(d time.Duration) *VectorSelector {
+	vs.rng = d
+	return vs
+}
+
+// Offset sets the offset modifier for the selector. A zero or negative offset is omitted.
+func (vs *VectorSelector) Offset(d time.Duration) *VectorSelector {
+	vs.offset = d
+	return vs
+}
+
+// At anchors the selector evaluation to the provided time using the @ modifier, ie:
+// up @ 1609746000. Requires Prometheus v2.25+ with the @ modifier enabled.
+func (vs *VectorSelector) At(t time.Time) *VectorSelector {
+	vs.at = strconv.FormatInt(t.Unix(), 10)
+	return vs
+}
+
+// AtStart anchors the selector evaluation to the start of the range query using the
+// @ start() modifier. For instant queries, start() and end() both resolve to the evaluation time.
+func (vs *VectorSelector) AtStart() *VectorSelector {
+	vs.at = "start()"
+	return vs
+}
+
+// AtEnd anchors the selector evaluation to the end of the range query using the
+// @ end() modifier. For instant queries, start() and end() both resolve to the evaluation time.
+func (vs *VectorSelector) AtEnd() *VectorSelector {
+	vs.at = "end()"
+	return vs
+}
+
+// String returns the PromQL representation of the selector.
+func (vs *VectorSelector) String() string {
+	var sb strings.Builder
+	sb.WriteString(vs.metric)
+
+	if len(vs.matchers) > 0 {
+		sb.WriteString("{")
+		for i, m := range vs.matchers {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(m.String())
+		}
+		sb.WriteString("}")
+	}
+
+	if rng := timeutil.DurationString(vs.rng); rng != "" {
+		sb.WriteString(fmt.Sprintf("[%s]", rng))
+	}
+
+	if vs.at != "" {
+		sb.WriteString(" @ ")
+		sb.WriteString(vs.at)
+	}
+
+	if offset := timeutil.DurationToPromOffsetString(vs.offset); offset != "" {
+		sb.WriteString(" ")
+		sb.WriteString(offset)
+	}
+
+	return sb.String()
+}
+
+// Subquery wraps the provided instant query expression in a subquery over the range with
+// the given resolution, ie: Subquery("rate(up[5m])", time.Hour, time.Minute) returns
+// (rate(up[5m]))[1h:1m]. A zero resolution omits the step, ie: (rate(up[5m]))[1h:], so Prometheus
+// uses the global evaluation interval. Subqueries can be passed directly to QueryRange, the query
+// string is sent as-is. An error is returned if the range is less than 1s, as a subquery requires
+// a range, or if a non-zero resolution is less than 1s, as durations are truncated to seconds.
+func Subquery(expr string, rng time.Duration, resolution time.Duration) (string, error) {
+	if rng < time.Second {
+		return "", fmt.Errorf("Subquery range must be at least 1s: %s", rng)
+	}
+	if resolution != 0 && resolution < time.Second {
+		return "", fmt.Errorf("Subquery resolution must be zero or at least 1s: %s", resolution)
+	}
+
+	return fmt.Sprintf("(%s)[%s:%s]", expr, timeutil.DurationString(rng), timeutil.DurationString(resolution)), nil
+}
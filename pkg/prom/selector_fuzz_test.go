@@ -0,0 +1,44 @@
+//go:build go1.18
+// +build go1.18
+
+package prom
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzEscapeLabelValue(f *testing.F) {
+	for _, seed := range []string{"", "kubecost", `say "hi"`, `C:\temp`, "a\nb", `\"`, "nginx-1.2", "a|b(c)*"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		if !utf8.ValidString(value) {
+			t.Skip()
+		}
+
+		// PromQL double quoted strings use go escaping rules, so the escaped value must
+		// unquote to the original value
+		escaped := EscapeLabelValue(value)
+		unquoted, err := strconv.Unquote(`"` + escaped + `"`)
+		if err != nil {
+			t.Fatalf("Failed to unquote escaped value %q: %s", escaped, err)
+		}
+		if unquoted != value {
+			t.Fatalf("Unquoted value: exp (%q); act (%q)", value, unquoted)
+		}
+
+		// the escaped regex must match exactly the original value, as prometheus anchors
+		// regex matchers
+		re, err := regexp.Compile("^(?:" + EscapeRegexValue(value) + ")$")
+		if err != nil {
+			t.Fatalf("Failed to compile escaped regex for %q: %s", value, err)
+		}
+		if !re.MatchString(value) {
+			t.Fatalf("Escaped regex %q does not match %q", EscapeRegexValue(value), value)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package prom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVectorSelectorString(t *testing.T) {
+	testCases := map[string]struct {
+		selector *VectorSelector
+		expected string
+	}{
+		"metric only": {
+			selector: NewVectorSelector("up"),
+			expected: `up`,
+		},
+		"single label": {
+			selector: NewVectorSelector("kube_pod_labels").WithLabel("namespace", "=", "kubecost"),
+			expected: `kube_pod_labels{namespace="kubecost"}`,
+		},
+		"multiple labels with offset": {
+			selector: NewVectorSelector("kube_pod_labels").
+				WithLabel("namespace", MatchEqual, "kubecost").
+				WithLabel("pod", MatchNotEqual, "").
+				Offset(time.Hour),
+			expected: `kube_pod_labels{namespace="kubecost", pod!=""} offset 1h`,
+		},
+		"range": {
+			selector: NewVectorSelector("container_cpu_usage_seconds_total").WithLabel("container", "!=", "POD").Range(5 * time.Minute),
+			expected: `container_cpu_usage_seconds_total{container!="POD"}[5m]`,
+		},
+		"double quote": {
+			selector: NewVectorSelector("kube_namespace_labels").WithLabel("namespace", "=", `te"st`),
+			expected: `kube_namespace_labels{namespace="te\"st"}`,
+		},
+		"quote breakout": {
+			selector: NewVectorSelector("kube_namespace_labels").WithLabel("namespace", "=", `a"} or up{job="b`),
+			expected: `kube_namespace_labels{namespace="a\"} or up{job=\"b"}`,
+		},
+		"backslash": {
+			selector: NewVectorSelector("kube_namespace_labels").WithLabel("namespace", "=", `a\b`),
+			expected: `kube_namespace_labels{namespace="a\\b"}`,
+		},
+		"newline": {
+			selector: NewVectorSelector("kube_namespace_labels").WithLabel("namespace", "=", "a\nb"),
+			expected: `kube_namespace_labels{namespace="a\nb"}`,
+		},
+		"regex metacharacters": {
+			selector: NewVectorSelector("kube_pod_labels").WithLabel("pod", MatchRegexp, `web-.*|api-[0-9]+`),
+			expected: `kube_pod_labels{pod=~"web-.*|api-[0-9]+"}`,
+		},
+		"escaped regex metacharacter": {
+			selector: NewVectorSelector("kube_pod_labels").WithLabel("pod", MatchNotRegexp, `web\.kubecost`),
+			expected: `kube_pod_labels{pod!~"web\\.kubecost"}`,
+		},
+		"at timestamp": {
+			selector: NewVectorSelector("up").At(time.Unix(1609746000, 0)),
+			expected: `up @ 1609746000`,
+		},
+		"at end with range and offset": {
+			selector: NewVectorSelector("container_memory_working_set_bytes").Range(time.Hour).AtEnd().Offset(5 * time.Minute),
+			expected: `container_memory_working_set_bytes[1h] @ end() offset 5m`,
+		},
+		"at start": {
+			selector: NewVectorSelector("up").WithLabel("job", "=", "kubecost").AtStart(),
+			expected: `up{job="kubecost"} @ start()`,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual := test.selector.String()
+			if actual != test.expected {
+				t.Fatalf("VectorSelector.String(): exp (%s); act (%s)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSubquery(t *testing.T) {
+	testCases := map[string]struct {
+		expr        string
+		rng         time.Duration
+		resolution  time.Duration
+		expected    string
+		expectError bool
+	}{
+		"with resolution": {
+			expr:       `rate(container_cpu_usage_seconds_total[5m])`,
+			rng:        time.Hour,
+			resolution: time.Minute,
+			expected:   `(rate(container_cpu_usage_seconds_total[5m]))[1h:1m]`,
+		},
+		"default resolution": {
+			expr:       `max(kube_pod_container_resource_requests)`,
+			rng:        24 * time.Hour,
+			resolution: 0,
+			expected:   `(max(kube_pod_container_resource_requests))[1d:]`,
+		},
+		"with selector": {
+			expr:       NewVectorSelector("up").AtEnd().String(),
+			rng:        30 * time.Minute,
+			resolution: 30 * time.Second,
+			expected:   `(up @ end())[30m:30s]`,
+		},
+		"zero range": {
+			expr:        `up`,
+			rng:         0,
+			resolution:  time.Minute,
+			expectError: true,
+		},
+		"sub-second range": {
+			expr:        `up`,
+			rng:         500 * time.Millisecond,
+			resolution:  0,
+			expectError: true,
+		},
+		"sub-second resolution": {
+			expr:        `up`,
+			rng:         time.Hour,
+			resolution:  500 * time.Millisecond,
+			expectError: true,
+		},
+		"negative resolution": {
+			expr:        `up`,
+			rng:         time.Hour,
+			resolution:  -time.Minute,
+			expectError: true,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Subquery(test.expr, test.rng, test.resolution)
+			if test.expectError {
+				if err == nil {
+					t.Fatalf("Subquery(): expected error; act (%s)", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if actual != test.expected {
+				t.Fatalf("Subquery(): exp (%s); act (%s)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected string
+	}{
+		"plain":        {value: "kubecost", expected: `kubecost`},
+		"double quote": {value: `say "hi"`, expected: `say \"hi\"`},
+		"backslash":    {value: `C:\temp`, expected: `C:\\temp`},
+		"newline":      {value: "a\nb", expected: `a\nb`},
+		"combined":     {value: "\\\"\n", expected: `\\\"\n`},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual := EscapeLabelValue(test.value)
+			if actual != test.expected {
+				t.Fatalf("EscapeLabelValue: exp (%s); act (%s)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEscapeRegexValue(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected string
+	}{
+		"plain":          {value: "kubecost", expected: `kubecost`},
+		"dot":            {value: "nginx-1.2", expected: `nginx-1\.2`},
+		"metacharacters": {value: "a|b(c)*", expected: `a\|b\(c\)\*`},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual := EscapeRegexValue(test.value)
+			if actual != test.expected {
+				t.Fatalf("EscapeRegexValue: exp (%s); act (%s)", test.expected, actual)
+			}
+		})
+	}
+
+	matcher := LabelMatcher{Name: "pod", Op: MatchRegexp, Value: EscapeRegexValue("nginx-1.2")}
+	if actual := matcher.String(); actual != `pod=~"nginx-1\\.2"` {
+		t.Fatalf("LabelMatcher: exp (%s); act (%s)", `pod=~"nginx-1\\.2"`, actual)
+	}
+}
@@ -0,0 +1,60 @@
+package prom
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kubecost/cost-model/pkg/errors"
+)
+
+// queryShardedConcurrency is the maximum number of concurrent requests made by QuerySharded
+const queryShardedConcurrency = 8
+
+// QuerySharded splits a query spanning many values of a label, ie: all namespaces of a large
+// cluster, into one instant query per value, which individually succeed where the full query would
+// time out. The template must contain a {{label}} placeholder, ie: {{namespace}}, which is replaced
+// by each of the shard values, escaped for a double quoted PromQL string:
+//
+//	sum(container_memory_working_set_bytes{namespace="{{namespace}}"}) by (pod)
+//
+// Up to 8 queries are run concurrently and their results are merged using MergeQueryResults, in the
+// order of the shard values. If any query fails, nil results and a *QueryErrorCollector reporting
+// every failed shard are returned.
+func (ctx *Context) QuerySharded(template string, shardValues []string, label string) ([]*QueryResult, error) {
+	placeholder := "{{" + label + "}}"
+	if !strings.Contains(template, placeholder) {
+		return nil, fmt.Errorf("Sharded query template does not contain the placeholder %s: %s", placeholder, template)
+	}
+
+	sets := make([][]*QueryResult, len(shardValues))
+	ec := &QueryErrorCollector{}
+
+	sem := make(chan struct{}, queryShardedConcurrency)
+	var wg sync.WaitGroup
+	for i, value := range shardValues {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, query string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer errors.HandlePanic()
+
+			results, _, err := ctx.QuerySync(query)
+			if err != nil {
+				ec.Report(query, nil, err, nil)
+				return
+			}
+
+			sets[i] = results
+		}(i, strings.ReplaceAll(template, placeholder, EscapeLabelValue(value)))
+	}
+	wg.Wait()
+
+	if ec.IsError() {
+		return nil, ec
+	}
+
+	return MergeQueryResults(sets...), nil
+}
@@ -0,0 +1,55 @@
+package prom
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestQuerySharded(t *testing.T) {
+	namespaceRegex := regexp.MustCompile(`namespace="([^"]*)"`)
+
+	// respond with a series per pod of the namespace, failing the namespaces prefixed with "fail"
+	client := promtest.NewClient().SetHandler(promtest.BodyHandler(func(req *http.Request) []byte {
+		namespace := namespaceRegex.FindStringSubmatch(promtest.RequestParams(req).Get("query"))[1]
+		if len(namespace) >= 4 && namespace[:4] == "fail" {
+			return []byte(`{"status":"error","errorType":"execution","error":"query timed out"}`)
+		}
+
+		return []byte(fmt.Sprintf(`{"status":"success","data":{"resultType":"vector","result":[`+
+			`{"metric":{"namespace":"%s","pod":"a"},"value":[1622505600,"1"]},`+
+			`{"metric":{"namespace":"%s","pod":"b"},"value":[1622505600,"2"]}]}}`, namespace, namespace))
+	}))
+	ctx := NewContext(client)
+
+	const template = `sum(kube_pod_info{namespace="{{namespace}}"}) by (namespace, pod)`
+
+	namespaces := []string{"kubecost", "kube-system", "default"}
+	results, err := ctx.QuerySharded(template, namespaces, "namespace")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("results: exp (6); act (%d)", len(results))
+	}
+	for i, result := range results {
+		if ns, _ := result.GetString("namespace"); ns != namespaces[i/2] {
+			t.Fatalf("results[%d] namespace: exp (%s); act (%s)", i, namespaces[i/2], ns)
+		}
+	}
+
+	_, err = ctx.QuerySharded(template, []string{"kubecost", "fail-a", "default", "fail-b"}, "namespace")
+	if !IsErrorCollection(err) {
+		t.Fatalf("Expected error collection for failed shards, got: %v", err)
+	}
+	if failed := err.(*QueryErrorCollector).FailedQueries(); len(failed) != 2 {
+		t.Fatalf("failed queries: exp (2); act (%d)", len(failed))
+	}
+
+	if _, err := ctx.QuerySharded(template, namespaces, "pod"); err == nil {
+		t.Fatalf("Expected error for template without placeholder")
+	}
+}
@@ -0,0 +1,68 @@
+package prom
+
+import "math"
+
+// SpecialValueMode determines how the NaN, +Inf and -Inf sample values returned by Prometheus are
+// decoded, see WithSpecialValues
+type SpecialValueMode int
+
+const (
+	// SpecialValuesZero replaces NaN, +Inf and -Inf values with 0, logging a warning, which keeps
+	// special values out of cost calculations. This is the default.
+	SpecialValuesZero SpecialValueMode = iota
+
+	// SpecialValuesKeep decodes NaN, +Inf and -Inf into the corresponding float64 values
+	SpecialValuesKeep
+
+	// SpecialValuesSkipNaN drops NaN samples, and decodes +Inf and -Inf into the corresponding
+	// float64 values. Instant query series with a NaN value are dropped entirely.
+	SpecialValuesSkipNaN
+)
+
+// String returns the name of the mode
+func (m SpecialValueMode) String() string {
+	switch m {
+	case SpecialValuesZero:
+		return "zero"
+	case SpecialValuesKeep:
+		return "keep"
+	case SpecialValuesSkipNaN:
+		return "skip-nan"
+	default:
+		return "unknown"
+	}
+}
+
+// WithSpecialValues sets how the NaN, +Inf and -Inf sample values of query, query_range and remote
+// read responses are decoded. Prometheus encodes these as the strings "NaN", "+Inf" and "-Inf",
+// ie: a ratio dividing by zero, or the le="+Inf" bucket of a histogram. By default they are
+// replaced with 0 (SpecialValuesZero). Returns the Context to allow chaining.
+func (ctx *Context) WithSpecialValues(mode SpecialValueMode) *Context {
+	ctx.specialValues = mode
+	return ctx
+}
+
+// applySpecialValueMode returns the sample value to use for the decoded value v under the mode,
+// and whether the sample should be kept. A warning is returned when a special value is replaced.
+func applySpecialValueMode(v float64, mode SpecialValueMode) (float64, warning, bool) {
+	switch {
+	case math.IsNaN(v):
+		switch mode {
+		case SpecialValuesKeep:
+			return v, nil, true
+		case SpecialValuesSkipNaN:
+			return v, nil, false
+		default:
+			return 0, NaNWarning, true
+		}
+
+	// Test for +Inf and -Inf (sign: 0)
+	case math.IsInf(v, 0):
+		if mode == SpecialValuesKeep || mode == SpecialValuesSkipNaN {
+			return v, nil, true
+		}
+		return 0, InfWarning, true
+	}
+
+	return v, nil, true
+}
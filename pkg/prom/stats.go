@@ -0,0 +1,56 @@
+package prom
+
+import (
+	"net/url"
+)
+
+// QueryStats contains the statistics reported by Prometheus for a query when requested with
+// stats=all. Supported by Prometheus 2.26+ as well as Cortex and Mimir.
+type QueryStats struct {
+	Timings QueryTimings `json:"timings"`
+	Samples QuerySamples `json:"samples"`
+}
+
+// QueryTimings contains the time spent in each phase of query execution, in seconds
+type QueryTimings struct {
+	EvalTotalTime        float64 `json:"evalTotalTime"`
+	ResultSortTime       float64 `json:"resultSortTime"`
+	QueryPreparationTime float64 `json:"queryPreparationTime"`
+	InnerEvalTime        float64 `json:"innerEvalTime"`
+	ExecQueueTime        float64 `json:"execQueueTime"`
+	ExecTotalTime        float64 `json:"execTotalTime"`
+}
+
+// QuerySamples contains the number of samples touched by a query
+type QuerySamples struct {
+	TotalQueryableSamples int64 `json:"totalQueryableSamples"`
+	PeakSamples           int64 `json:"peakSamples"`
+}
+
+// WithQueryStats enables requesting query statistics, which are exposed on QueryResults.Stats. If
+// samplesThreshold is greater than 0, a warning is logged for each query touching more samples than
+// the threshold. Stats collection is disabled by default. Returns the Context to allow chaining.
+func (ctx *Context) WithQueryStats(enabled bool, samplesThreshold int64) *Context {
+	ctx.queryStats = enabled
+	ctx.statsSamplesThreshold = samplesThreshold
+	return ctx
+}
+
+// setStatsParam adds the stats parameter to the query parameters if stats collection is enabled
+func (ctx *Context) setStatsParam(q url.Values) {
+	if ctx.queryStats {
+		q.Set("stats", "all")
+	}
+}
+
+// checkQueryStats logs a warning if the query touched more samples than the configured threshold
+func (ctx *Context) checkQueryStats(results *QueryResults) {
+	if results == nil || results.Stats == nil || ctx.statsSamplesThreshold <= 0 {
+		return
+	}
+
+	samples := results.Stats.Samples.TotalQueryableSamples
+	if samples > ctx.statsSamplesThreshold {
+		ctx.getLogger().Warningf("Query touched %d samples, exceeding threshold of %d (eval time: %.3fs): %s", samples, ctx.statsSamplesThreshold, results.Stats.Timings.EvalTotalTime, results.Query)
+	}
+}
@@ -0,0 +1,271 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/util/httputil"
+	"github.com/kubecost/cost-model/pkg/util/json"
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+const (
+	epBuildInfo   = apiPrefix + "/status/buildinfo"
+	epRuntimeInfo = apiPrefix + "/status/runtimeinfo"
+	epTSDB        = apiPrefix + "/status/tsdb"
+	epReady       = "/-/ready"
+	epHealthy     = "/-/healthy"
+	epMetricNames = apiPrefix + "/label/__name__/values"
+)
+
+// DefaultMetricPresenceLookback is the window QuerySyncMetric searches for series of a metric when
+// a query returns no results.
+const DefaultMetricPresenceLookback = time.Hour
+
+// BuildInfo contains the build information of the Prometheus server
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"buildUser"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// RuntimeInfo contains the runtime properties of the Prometheus server
+type RuntimeInfo struct {
+	StartTime           time.Time `json:"startTime"`
+	CWD                 string    `json:"CWD"`
+	ReloadConfigSuccess bool      `json:"reloadConfigSuccess"`
+	LastConfigTime      time.Time `json:"lastConfigTime"`
+	CorruptionCount     int64     `json:"corruptionCount"`
+	GoroutineCount      int       `json:"goroutineCount"`
+	GOMAXPROCS          int       `json:"GOMAXPROCS"`
+	GOGC                string    `json:"GOGC"`
+	GODEBUG             string    `json:"GODEBUG"`
+	StorageRetention    string    `json:"storageRetention"`
+}
+
+// TSDBHeadStats contains the statistics for the Prometheus TSDB head block
+type TSDBHeadStats struct {
+	NumSeries     uint64 `json:"numSeries"`
+	NumLabelPairs int    `json:"numLabelPairs"`
+	ChunkCount    int64  `json:"chunkCount"`
+	MinTime       int64  `json:"minTime"`
+	MaxTime       int64  `json:"maxTime"`
+}
+
+// TSDBStat is a single name and count pair from the TSDB cardinality statistics
+type TSDBStat struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// TSDBStatus contains the cardinality statistics of the Prometheus TSDB. Each of the
+// TSDBStat slices are ordered by value, and contain the top entries only.
+type TSDBStatus struct {
+	HeadStats                   TSDBHeadStats `json:"headStats"`
+	SeriesCountByMetricName     []TSDBStat    `json:"seriesCountByMetricName"`
+	LabelValueCountByLabelName  []TSDBStat    `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []TSDBStat    `json:"memoryInBytesByLabelName"`
+	SeriesCountByLabelValuePair []TSDBStat    `json:"seriesCountByLabelValuePair"`
+}
+
+// BuildInfo returns the build information of the Prometheus server. Servers which do not
+// implement the endpoint (ie: older Thanos or Cortex) return an UnsupportedEndpointError.
+func (ctx *Context) BuildInfo() (*BuildInfo, error) {
+	var bi BuildInfo
+	if err := ctx.apiGet(epBuildInfo, nil, &bi); err != nil {
+		return nil, err
+	}
+
+	return &bi, nil
+}
+
+// RuntimeInfo returns the runtime properties of the Prometheus server. Servers which do not
+// implement the endpoint (ie: older Thanos or Cortex) return an UnsupportedEndpointError.
+func (ctx *Context) RuntimeInfo() (*RuntimeInfo, error) {
+	var ri RuntimeInfo
+	if err := ctx.apiGet(epRuntimeInfo, nil, &ri); err != nil {
+		return nil, err
+	}
+
+	return &ri, nil
+}
+
+// TSDBStatus returns the cardinality statistics of the Prometheus TSDB, which is useful for
+// determining which metrics (ie: kube_pod_labels) are contributing the most series. Servers which
+// do not implement the endpoint (ie: Thanos or Cortex) return an UnsupportedEndpointError.
+func (ctx *Context) TSDBStatus() (*TSDBStatus, error) {
+	var ts TSDBStatus
+	if err := ctx.apiGet(epTSDB, nil, &ts); err != nil {
+		return nil, err
+	}
+
+	return &ts, nil
+}
+
+// Ping confirms the Prometheus server is reachable and ready to serve queries using the /-/ready
+// endpoint, falling back to /-/healthy for servers which do not implement it. Returns nil if the
+// server responds with a 200, and a descriptive error otherwise. The request is bound to reqCtx,
+// so a deadline may be used to limit how long the check waits.
+func (ctx *Context) Ping(reqCtx context.Context) error {
+	err := ctx.probe(reqCtx, epReady)
+	if IsUnsupportedEndpointError(err) {
+		return ctx.probe(reqCtx, epHealthy)
+	}
+
+	return err
+}
+
+// probe executes a GET request against a management endpoint, returning an error if the server
+// does not respond with a 200.
+func (ctx *Context) probe(reqCtx context.Context, ep string) error {
+	u := ctx.Client.URL(ep, nil)
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Set QueryContext name if non empty
+	if ctx.name != "" {
+		req = httputil.SetName(req, ctx.name)
+	}
+	req = httputil.SetQuery(req, ep)
+	ctx.setUserAgent(req)
+
+	resp, body, _, err := ctx.Client.Do(reqCtx, req)
+	if err != nil {
+		if resp == nil {
+			return fmt.Errorf("Prometheus is unreachable at '%s': %s", u, err)
+		}
+
+		return fmt.Errorf("Prometheus is unreachable at '%s': %d (%s) %s", u, resp.StatusCode, http.StatusText(resp.StatusCode), err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return NewUnsupportedEndpointError(ep)
+	}
+
+	return NewCommResponseError(resp.StatusCode, body, ep, "Prometheus is not ready at '%s': %d (%s) %s", u, resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(body)))
+}
+
+// MetricExists returns true if any series of the metric have been written within the lookback
+// window. Servers which do not support filtering label values by match[] (Prometheus < 2.24)
+// return all metric names, which are searched instead.
+func (ctx *Context) MetricExists(metric string, lookback time.Duration) (bool, error) {
+	end := ctx.now()
+	params := url.Values{}
+	params.Set("match[]", metric)
+	params.Set("start", ctx.formatTime(end.Add(-lookback), time.RFC3339))
+	params.Set("end", ctx.formatTime(end, time.RFC3339))
+
+	var names []string
+	if err := ctx.apiGet(epMetricNames, params, &names); err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if name == metric {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// QuerySyncMetric runs QuerySync for a query over the provided metric name. If the query returns
+// no results, the metric is checked for series within DefaultMetricPresenceLookback, and a
+// MetricAbsentError is returned if none exist. Otherwise, empty results are returned with a nil
+// error, meaning the metric exists but currently has no series matching the query. This allows
+// health checks to differentiate a missing exporter from legitimately empty data.
+func (ctx *Context) QuerySyncMetric(query string, metric string) ([]*QueryResult, prometheus.Warnings, error) {
+	results, warnings, err := ctx.QuerySync(query)
+	if err != nil || len(results) > 0 {
+		return results, warnings, err
+	}
+
+	exists, err := ctx.MetricExists(metric, DefaultMetricPresenceLookback)
+	if err != nil {
+		return results, warnings, err
+	}
+	if !exists {
+		return nil, warnings, NewMetricAbsentError(metric, query)
+	}
+
+	return results, warnings, nil
+}
+
+// apiResponse is the json envelope returned by the Prometheus HTTP API. The Data field should
+// be set to a pointer of the expected type prior to decoding.
+type apiResponse struct {
+	Status   string      `json:"status"`
+	Data     interface{} `json:"data"`
+	Warnings []string    `json:"warnings"`
+}
+
+// apiGet executes a GET request against a non-query Prometheus API endpoint and decodes the
+// data field of the response into the provided data pointer. Warnings are logged and reported
+// to the error collector using the endpoint in place of a query.
+func (ctx *Context) apiGet(ep string, params url.Values, data interface{}) error {
+	u := ctx.Client.URL(ctx.endpoint(ep), nil)
+	if params != nil {
+		u.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Set QueryContext name if non empty
+	if ctx.name != "" {
+		req = httputil.SetName(req, ctx.name)
+	}
+	req = httputil.SetQuery(req, ep)
+	ctx.setUserAgent(req)
+
+	resp, body, _, err := ctx.Client.Do(context.Background(), req)
+	if err != nil {
+		if resp == nil {
+			return fmt.Errorf("request error: '%s' fetching endpoint '%s'", err.Error(), ep)
+		}
+
+		return fmt.Errorf("request error %d: '%s' fetching endpoint '%s'", resp.StatusCode, err.Error(), ep)
+	}
+
+	statusCode := resp.StatusCode
+	if statusCode == http.StatusNotFound {
+		return NewUnsupportedEndpointError(ep)
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		if envelope, ok := DecodeErrorEnvelope(resp.Header.Get("Content-Type"), body); ok {
+			return NewCommEnvelopeError(statusCode, body, ep, envelope)
+		}
+
+		return NewCommResponseError(statusCode, body, ep, "%d (%s) Headers: %s, Body: %s Endpoint: %s", statusCode, http.StatusText(statusCode), httputil.HeaderString(resp.Header), body, ep)
+	}
+
+	apiResp := apiResponse{Data: data}
+	err = json.Unmarshal(body, &apiResp)
+	if err != nil {
+		return fmt.Errorf("Unmarshal Error: %s\nEndpoint: %s", err, ep)
+	}
+
+	if len(apiResp.Warnings) > 0 {
+		ctx.errorCollector.Report(ep, apiResp.Warnings, nil, nil)
+		for _, w := range apiResp.Warnings {
+			ctx.getLogger().Warningf("fetching endpoint '%s': %s", ep, w)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package prom
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestBuildInfo(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"version":"2.26.0","revision":"3cafc58827d1ebd1a67749f88be4218f0bab3d8d","branch":"HEAD","buildUser":"root@a67cafebe6d0","buildDate":"20210331-11:56:23","goVersion":"go1.16.2"}}`)})
+	ctx := NewContext(client)
+
+	bi, err := ctx.BuildInfo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if bi.Version != "2.26.0" {
+		t.Errorf("Unexpected version: %s", bi.Version)
+	}
+	if bi.GoVersion != "go1.16.2" {
+		t.Errorf("Unexpected go version: %s", bi.GoVersion)
+	}
+
+	req := client.Requests()[0]
+	if req.Method != http.MethodGet || req.URL.Path != epBuildInfo {
+		t.Errorf("Unexpected request: %s %s", req.Method, req.URL.Path)
+	}
+}
+
+func TestRuntimeInfoUnsupported(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{StatusCode: http.StatusNotFound, Body: []byte("404 page not found")})
+	ctx := NewContext(client)
+
+	_, err := ctx.RuntimeInfo()
+	if !IsUnsupportedEndpointError(err) {
+		t.Fatalf("Expected UnsupportedEndpointError, got: %v", err)
+	}
+}
+
+func TestTSDBStatus(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"headStats":{"numSeries":508,"numLabelPairs":1234,"chunkCount":937,"minTime":1591516800000,"maxTime":1598896800143},"seriesCountByMetricName":[{"name":"kube_pod_labels","value":311},{"name":"up","value":12}],"labelValueCountByLabelName":[{"name":"__name__","value":502}],"memoryInBytesByLabelName":[{"name":"__name__","value":1990}],"seriesCountByLabelValuePair":[{"name":"job=kubecost","value":302}]}}`)})
+	ctx := NewContext(client)
+
+	ts, err := ctx.TSDBStatus()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if ts.HeadStats.NumSeries != 508 {
+		t.Errorf("Unexpected series count: %d", ts.HeadStats.NumSeries)
+	}
+	if len(ts.SeriesCountByMetricName) != 2 || ts.SeriesCountByMetricName[0].Name != "kube_pod_labels" || ts.SeriesCountByMetricName[0].Value != 311 {
+		t.Errorf("Unexpected series count by metric name: %+v", ts.SeriesCountByMetricName)
+	}
+}
+
+func TestPing(t *testing.T) {
+	testCases := map[string]struct {
+		responses     map[string]*promtest.Response
+		expectedPaths []string
+		expectError   bool
+	}{
+		"ready": {
+			responses: map[string]*promtest.Response{
+				epReady: {StatusCode: http.StatusOK, Body: []byte("Prometheus is Ready.")},
+			},
+			expectedPaths: []string{epReady},
+		},
+		"not ready": {
+			responses: map[string]*promtest.Response{
+				epReady: {StatusCode: http.StatusServiceUnavailable, Body: []byte("Service Unavailable")},
+			},
+			expectedPaths: []string{epReady},
+			expectError:   true,
+		},
+		"healthy fallback": {
+			responses: map[string]*promtest.Response{
+				epReady:   {StatusCode: http.StatusNotFound, Body: []byte("404 page not found")},
+				epHealthy: {StatusCode: http.StatusOK, Body: []byte("OK")},
+			},
+			expectedPaths: []string{epReady, epHealthy},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient()
+			for path, resp := range test.responses {
+				client.SetResponse(path, resp)
+			}
+
+			err := NewContext(client).Ping(context.Background())
+			if (err != nil) != test.expectError {
+				t.Fatalf("Unexpected error result: %v", err)
+			}
+
+			var paths []string
+			for _, req := range client.Requests() {
+				paths = append(paths, req.URL.Path)
+			}
+			if !reflect.DeepEqual(paths, test.expectedPaths) {
+				t.Fatalf("Paths: exp (%v); act (%v)", test.expectedPaths, paths)
+			}
+		})
+	}
+}
+
+func TestQuerySyncMetric(t *testing.T) {
+	const emptyVector = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+	const nonEmptyVector = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"1"]}]}}`
+
+	testCases := map[string]struct {
+		queryBody      string
+		names          string
+		expectedLen    int
+		expectAbsent   bool
+		expectRequests int
+	}{
+		"present with results": {
+			queryBody:      nonEmptyVector,
+			expectedLen:    1,
+			expectRequests: 1,
+		},
+		"present with zero series": {
+			queryBody:      emptyVector,
+			names:          `{"status":"success","data":["kube_pod_labels"]}`,
+			expectRequests: 2,
+		},
+		"absent": {
+			queryBody:      emptyVector,
+			names:          `{"status":"success","data":[]}`,
+			expectAbsent:   true,
+			expectRequests: 2,
+		},
+		"absent without match support": {
+			queryBody:      emptyVector,
+			names:          `{"status":"success","data":["up","node_cpu_seconds_total"]}`,
+			expectAbsent:   true,
+			expectRequests: 2,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client := promtest.NewClient().SetHandler(promtest.BodyHandler(func(req *http.Request) []byte {
+				if req.URL.Path == epMetricNames {
+					return []byte(test.names)
+				}
+				return []byte(test.queryBody)
+			}))
+
+			results, _, err := NewContext(client).QuerySyncMetric(`kube_pod_labels{namespace="kubecost"}`, "kube_pod_labels")
+			if test.expectAbsent {
+				if !IsMetricAbsentError(err) {
+					t.Fatalf("Expected MetricAbsentError, got: %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			if len(results) != test.expectedLen {
+				t.Fatalf("Results: exp (%d); act (%d)", test.expectedLen, len(results))
+			}
+			if len(client.Requests()) != test.expectRequests {
+				t.Fatalf("Requests: exp (%d); act (%d)", test.expectRequests, len(client.Requests()))
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package prom
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// minAdaptiveTimeoutSamples is the number of observations of a query required before its adaptive
+// timeout is derived from its latency rather than the floor
+const minAdaptiveTimeoutSamples = 5
+
+// WithAdaptiveTimeout bounds each query and query_range request by a timeout derived from the
+// observed latency of the query, which cuts off pathologically slow queries without affecting
+// queries completing in their usual time. The timeout is the p95 latency of the recent observations
+// of the query multiplied by the multiplier, clamped between floor and ceiling. Until the query has
+// enough history, or if latency tracking is disabled (see WithLatencyTracking), the floor is used,
+// so a floor of 0 leaves those queries without a timeout. Requests cut off by the timeout are
+// observed at the time they were cut off, so the timeout of a query which slows down grows with its
+// latency until its requests complete again. A ceiling <= 0 leaves the timeout unbounded above. A
+// multiplier <= 0 disables adaptive timeouts, which is the default. Returns the Context to allow
+// chaining.
+func (ctx *Context) WithAdaptiveTimeout(multiplier float64, floor, ceiling time.Duration) *Context {
+	if ceiling > 0 && ceiling < floor {
+		ceiling = floor
+	}
+
+	ctx.timeoutMultiplier = multiplier
+	ctx.timeoutFloor = floor
+	ctx.timeoutCeiling = ceiling
+	return ctx
+}
+
+// adaptiveTimeout returns the timeout of a request for the query, or 0 if adaptive timeouts are
+// disabled
+func (ctx *Context) adaptiveTimeout(query string) time.Duration {
+	if ctx.timeoutMultiplier <= 0 {
+		return 0
+	}
+
+	timeout := ctx.timeoutFloor
+	if ctx.latencies != nil {
+		latency, ok := ctx.latencies.get(queryHash(normalizeQuery(query)))
+		if ok && latency.Count >= minAdaptiveTimeoutSamples {
+			timeout = time.Duration(float64(latency.P95) * ctx.timeoutMultiplier)
+		}
+	}
+
+	if timeout < ctx.timeoutFloor {
+		timeout = ctx.timeoutFloor
+	}
+	if ctx.timeoutCeiling > 0 && timeout > ctx.timeoutCeiling {
+		timeout = ctx.timeoutCeiling
+	}
+
+	return timeout
+}
+
+// withAdaptiveTimeout returns a copy of reqCtx bounded by the adaptive timeout of the query, and the
+// function releasing its resources, which must be called once the request completes
+func (ctx *Context) withAdaptiveTimeout(reqCtx context.Context, query string) (context.Context, context.CancelFunc) {
+	timeout := ctx.adaptiveTimeout(query)
+	if timeout <= 0 {
+		return reqCtx, func() {}
+	}
+
+	return context.WithTimeout(reqCtx, timeout)
+}
+
+// WithServerTimeout sets the timeout parameter of each query and query_range request, which bounds
+// the evaluation of the query by Prometheus. Queries exceeding the timeout are cancelled by the
+// server, which frees its resources and responds with a timeout error, unlike a client-side timeout
+// (ie: WithAdaptiveTimeout), which abandons the request while the server continues evaluating the
+// query. Prometheus caps the timeout at its -query.timeout flag. A value <= 0 leaves the parameter
+// unset, which is the default. Returns the Context to allow chaining.
+func (ctx *Context) WithServerTimeout(timeout time.Duration) *Context {
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	ctx.serverTimeout = timeout
+	return ctx
+}
+
+// setTimeoutParam sets the timeout parameter of the request if a server timeout is configured
+func (ctx *Context) setTimeoutParam(q url.Values) {
+	if ctx.serverTimeout > 0 {
+		q.Set("timeout", strconv.FormatFloat(ctx.serverTimeout.Seconds(), 'f', 3, 64))
+	}
+}
@@ -0,0 +1,210 @@
+package prom
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	const query = "sum(up)"
+
+	testCases := map[string]struct {
+		observed   []time.Duration
+		multiplier float64
+		floor      time.Duration
+		ceiling    time.Duration
+		expected   time.Duration
+	}{
+		"disabled": {
+			observed:   []time.Duration{time.Second, time.Second, time.Second, time.Second, time.Second},
+			multiplier: 0,
+			floor:      time.Second,
+			expected:   0,
+		},
+		"insufficient history": {
+			observed:   []time.Duration{time.Second, time.Second},
+			multiplier: 3,
+			floor:      5 * time.Second,
+			ceiling:    time.Minute,
+			expected:   5 * time.Second,
+		},
+		"p95": {
+			observed:   []time.Duration{time.Second, time.Second, time.Second, 2 * time.Second, 4 * time.Second},
+			multiplier: 3,
+			floor:      time.Second,
+			ceiling:    time.Minute,
+			expected:   12 * time.Second,
+		},
+		"floor": {
+			observed:   []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond},
+			multiplier: 3,
+			floor:      time.Second,
+			ceiling:    time.Minute,
+			expected:   time.Second,
+		},
+		"ceiling": {
+			observed:   []time.Duration{time.Minute, time.Minute, time.Minute, time.Minute, time.Minute},
+			multiplier: 3,
+			floor:      time.Second,
+			ceiling:    2 * time.Minute,
+			expected:   2 * time.Minute,
+		},
+		"unbounded": {
+			observed:   []time.Duration{time.Minute, time.Minute, time.Minute, time.Minute, time.Minute},
+			multiplier: 3,
+			floor:      time.Second,
+			expected:   3 * time.Minute,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := NewContext(promtest.NewClient().SetHandler(hangingHandler)).WithAdaptiveTimeout(test.multiplier, test.floor, test.ceiling)
+
+			normalized := normalizeQuery(query)
+			for _, latency := range test.observed {
+				ctx.latencies.observe(queryHash(normalized), normalized, latency, time.Now())
+			}
+
+			if timeout := ctx.adaptiveTimeout(query); timeout != test.expected {
+				t.Fatalf("timeout: exp (%s); act (%s)", test.expected, timeout)
+			}
+		})
+	}
+}
+
+func TestAdaptiveTimeoutRequest(t *testing.T) {
+	ctx := NewContext(promtest.NewClient().SetHandler(hangingHandler)).WithAdaptiveTimeout(3, 50*time.Millisecond, time.Second)
+
+	start := time.Now()
+	_, _, err := ctx.QuerySync("up")
+	if err == nil {
+		t.Fatalf("Expected error for request exceeding the adaptive timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected request to be cut off at the floor, took %s", elapsed)
+	}
+}
+
+func TestServerTimeout(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)})
+	ctx := NewContext(client)
+
+	ctx.RawQuery("up")
+	if _, ok := promtest.RequestParams(client.Requests()[0])["timeout"]; ok {
+		t.Fatalf("Expected no timeout parameter by default")
+	}
+
+	ctx.WithServerTimeout(90 * time.Second)
+	ctx.RawQuery("up")
+	ctx.RawQueryRange("up", time.Now().Add(-time.Hour), time.Now(), time.Minute)
+
+	for _, req := range client.Requests()[1:] {
+		if timeout := promtest.RequestParams(req).Get("timeout"); timeout != "90.000" {
+			t.Fatalf("timeout: exp (%s); act (%s)", "90.000", timeout)
+		}
+	}
+}
+
+func TestQuerySyncCtx(t *testing.T) {
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1622505600,"1"]}]}}`)})
+	results, _, err := NewContext(client).QuerySyncCtx(context.Background(), "up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results: exp (1); act (%d)", len(results))
+	}
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err = NewContext(promtest.NewClient().SetHandler(hangingHandler)).QuerySyncCtx(reqCtx, "up")
+	if err != context.Canceled {
+		t.Fatalf("error: exp (%s); act (%v)", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected query to return once cancelled, took %s", elapsed)
+	}
+}
+
+func TestAdaptiveTimeoutRecovers(t *testing.T) {
+	// responds after the delay, or returns early once the request context is done
+	var lock sync.Mutex
+	var delay time.Duration
+	client := promtest.NewClient().SetHandler(func(ctx context.Context, req *http.Request) *promtest.Response {
+		lock.Lock()
+		d := delay
+		lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return &promtest.Response{Err: ctx.Err()}
+		case <-time.After(d):
+			return nil
+		}
+	})
+	ctx := NewContext(client).WithAdaptiveTimeout(2, 20*time.Millisecond, 0)
+
+	for i := 0; i < minAdaptiveTimeoutSamples; i++ {
+		if _, _, err := ctx.QuerySync("up"); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	// the latency shifts well beyond the timeout derived from the history
+	lock.Lock()
+	delay = 100 * time.Millisecond
+	lock.Unlock()
+
+	if _, _, err := ctx.QuerySync("up"); err == nil {
+		t.Fatalf("Expected the first query after the latency shift to be cut off")
+	}
+
+	// each cut off request is observed at the timeout, which grows the timeout until the query
+	// completes again
+	for attempt := 2; ; attempt++ {
+		if _, _, err := ctx.QuerySync("up"); err == nil {
+			break
+		}
+		if attempt >= 10 {
+			t.Fatalf("Expected the query to recover after its latency shifted, timeout (%s)", ctx.adaptiveTimeout("up"))
+		}
+	}
+
+	if timeout := ctx.adaptiveTimeout("up"); timeout < 100*time.Millisecond {
+		t.Fatalf("Expected timeout to exceed the new latency, got %s", timeout)
+	}
+}
+
+func TestObserveAttempt(t *testing.T) {
+	const query = "up"
+	fingerprint := queryHash(normalizeQuery(query))
+	start := time.Now().Add(-time.Second)
+
+	ctx := NewContext(promtest.NewClient().SetHandler(hangingHandler))
+
+	// failed requests are not observed
+	ctx.observeAttempt(context.Background(), query, start, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+	ctx.observeAttempt(context.Background(), query, start, nil, context.Canceled)
+	if _, ok := ctx.latencies.get(fingerprint); ok {
+		t.Fatalf("Expected failed requests not to be observed")
+	}
+
+	// timed out requests are observed
+	reqCtx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-reqCtx.Done()
+	ctx.observeAttempt(reqCtx, query, start, nil, reqCtx.Err())
+
+	ctx.observeAttempt(context.Background(), query, start, &http.Response{StatusCode: http.StatusOK}, nil)
+	if latency, _ := ctx.latencies.get(fingerprint); latency.Count != 2 {
+		t.Fatalf("Count: exp (2); act (%d)", latency.Count)
+	}
+}
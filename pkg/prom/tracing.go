@@ -0,0 +1,75 @@
+package prom
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name used for prometheus query spans
+const tracerName = "github.com/kubecost/cost-model/pkg/prom"
+
+// tracer is the package tracer used to create query spans. Until a TracerProvider is registered
+// via otel.SetTracerProvider(), the global tracer is a no-op.
+var tracer = otel.Tracer(tracerName)
+
+// Span attribute keys for prometheus queries
+const (
+	attrContextName  = attribute.Key("prom.context")
+	attrQuery        = attribute.Key("prom.query")
+	attrStatusCode   = attribute.Key("http.status_code")
+	attrResultCount  = attribute.Key("prom.result_count")
+	attrDurationSecs = attribute.Key("prom.duration_seconds")
+)
+
+// startQuerySpan starts a new client span for the query as a child of any span in the provided
+// context.
+func startQuerySpan(reqCtx context.Context, spanName string, ctx *Context, query string) (context.Context, trace.Span) {
+	reqCtx, span := tracer.Start(reqCtx, spanName, trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		contextName := ctx.name
+		if contextName == "" {
+			contextName = "<none>"
+		}
+
+		span.SetAttributes(
+			attrContextName.String(contextName),
+			attrQuery.String(query),
+		)
+	}
+
+	return reqCtx, span
+}
+
+// endQuerySpan records the outcome of the query on the span and ends it. A nil results
+// omits the result count.
+func endQuerySpan(span trace.Span, start time.Time, statusCode int, results *QueryResults, err error) {
+	if span.IsRecording() {
+		span.SetAttributes(attrDurationSecs.Float64(time.Since(start).Seconds()))
+
+		if statusCode != 0 {
+			span.SetAttributes(attrStatusCode.Int(statusCode))
+		}
+		if results != nil {
+			span.SetAttributes(attrResultCount.Int(len(results.Results)))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
+
+// injectTraceHeaders propagates the trace context into the outbound request headers using the
+// globally registered propagator.
+func injectTraceHeaders(reqCtx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(reqCtx, propagation.HeaderCarrier(req.Header))
+}
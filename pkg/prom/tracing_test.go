@@ -0,0 +1,135 @@
+package prom
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// withSpanRecorder replaces the package tracer and global propagator with a tracer recording to the
+// returned recorder and the W3C trace context propagator, restoring both once the test completes
+func withSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	prevTracer, prevPropagator := tracer, otel.GetTextMapPropagator()
+	tracer = provider.Tracer(tracerName)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		tracer = prevTracer
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	return recorder
+}
+
+// endedSpan returns the ended span with the name, failing the test if there is none
+func endedSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+
+	t.Fatalf("Expected span %s to be ended", name)
+	return nil
+}
+
+// spanAttribute returns the value of the attribute of the span with the key
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestQueryTracing(t *testing.T) {
+	recorder := withSpanRecorder(t)
+
+	client := promtest.NewClient().SetBody("up", promtest.VectorBody([]promtest.Series{
+		{Metric: map[string]string{"pod": "a"}, Points: []promtest.Point{{Value: 1}}},
+		{Metric: map[string]string{"pod": "b"}, Points: []promtest.Point{{Value: 1}}},
+	}))
+
+	if _, err := NewContext(client).WithName(AllocationContextName).Query("up").Await(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	querySpan := endedSpan(t, recorder, "prom.Query")
+	rawSpan := endedSpan(t, recorder, "prom.RawQuery")
+
+	if v, _ := spanAttribute(querySpan, attrQuery); v.AsString() != "up" {
+		t.Fatalf("%s: exp (up); act (%s)", attrQuery, v.AsString())
+	}
+	if v, _ := spanAttribute(querySpan, attrContextName); v.AsString() != AllocationContextName {
+		t.Fatalf("%s: exp (%s); act (%s)", attrContextName, AllocationContextName, v.AsString())
+	}
+	if v, _ := spanAttribute(querySpan, attrResultCount); v.AsInt64() != 2 {
+		t.Fatalf("%s: exp (2); act (%d)", attrResultCount, v.AsInt64())
+	}
+	if v, _ := spanAttribute(rawSpan, attrStatusCode); v.AsInt64() != http.StatusOK {
+		t.Fatalf("%s: exp (%d); act (%d)", attrStatusCode, http.StatusOK, v.AsInt64())
+	}
+	if rawSpan.Parent().SpanID() != querySpan.SpanContext().SpanID() {
+		t.Fatalf("Expected %s to be a child of %s", rawSpan.Name(), querySpan.Name())
+	}
+
+	// the trace context of the request span is propagated to Prometheus
+	traceparent := client.Requests()[0].Header.Get("traceparent")
+	expected := "00-" + rawSpan.SpanContext().TraceID().String() + "-" + rawSpan.SpanContext().SpanID().String() + "-01"
+	if traceparent != expected {
+		t.Fatalf("traceparent: exp (%s); act (%s)", expected, traceparent)
+	}
+}
+
+func TestQueryTracingError(t *testing.T) {
+	recorder := withSpanRecorder(t)
+
+	client := promtest.NewClient().SetResponse("up", &promtest.Response{StatusCode: http.StatusServiceUnavailable})
+
+	if _, err := NewContext(client).Query("up").Await(); err == nil {
+		t.Fatalf("Expected error for failed query")
+	}
+
+	querySpan := endedSpan(t, recorder, "prom.Query")
+	rawSpan := endedSpan(t, recorder, "prom.RawQuery")
+
+	if v, _ := spanAttribute(rawSpan, attrStatusCode); v.AsInt64() != http.StatusServiceUnavailable {
+		t.Fatalf("%s: exp (%d); act (%d)", attrStatusCode, http.StatusServiceUnavailable, v.AsInt64())
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{querySpan, rawSpan} {
+		if span.Status().Code != codes.Error {
+			t.Fatalf("%s status: exp (%s); act (%s)", span.Name(), codes.Error, span.Status().Code)
+		}
+	}
+}
+
+func TestQueryTracingNoProvider(t *testing.T) {
+	client := promtest.NewClient()
+
+	// without a registered provider and propagator, queries are not traced
+	if _, err := NewContext(client).Query("up").Await(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if traceparent := client.Requests()[0].Header.Get("traceparent"); traceparent != "" {
+		t.Fatalf("Expected no traceparent header; act (%s)", traceparent)
+	}
+
+	_, span := startQuerySpan(context.Background(), "prom.Query", NewContext(client), "up")
+	defer span.End()
+	if span.IsRecording() || span.SpanContext().IsValid() {
+		t.Fatalf("Expected a no-op span without a provider")
+	}
+}
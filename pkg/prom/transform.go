@@ -0,0 +1,39 @@
+package prom
+
+import (
+	"fmt"
+)
+
+// ResponseTransformer transforms the raw body of a query or query_range response before it is
+// decoded, ie: to strip a byte order mark, or unwrap the envelope added by a gateway.
+type ResponseTransformer func(body []byte) ([]byte, error)
+
+// WithResponseTransformer adds a transformer which is applied to the raw body of each query and
+// query_range response before it is decoded. Multiple transformers are applied in the order they
+// were added, each receiving the output of the previous. An error returned by a transformer fails
+// the query. The raw query methods (RawQuery and RawQueryRange) return the body untransformed. No
+// transformers are applied by default. Returns the Context to allow chaining.
+func (ctx *Context) WithResponseTransformer(transformer ResponseTransformer) *Context {
+	if transformer == nil {
+		return ctx
+	}
+
+	// copy, so transformers added to copies of the Context made with WithName are not shared
+	transformers := make([]ResponseTransformer, 0, len(ctx.responseTransformers)+1)
+	transformers = append(transformers, ctx.responseTransformers...)
+	ctx.responseTransformers = append(transformers, transformer)
+	return ctx
+}
+
+// transformResponse applies the response transformers of the Context to the body in order
+func (ctx *Context) transformResponse(query string, body []byte) ([]byte, error) {
+	for _, transformer := range ctx.responseTransformers {
+		var err error
+		body, err = transformer(body)
+		if err != nil {
+			return nil, fmt.Errorf("Response Transformer Error: %s\nQuery: %s", err, query)
+		}
+	}
+
+	return body, nil
+}
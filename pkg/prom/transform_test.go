@@ -0,0 +1,58 @@
+package prom
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/prom/promtest"
+)
+
+func TestResponseTransformer(t *testing.T) {
+	const body = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"namespace":"kubecost"},"value":[1622505600,"1"]}]}}`
+
+	// a gateway wrapping the response in an envelope, prefixed with a byte order mark
+	client := promtest.NewClient().SetDefault(&promtest.Response{Body: []byte("\xef\xbb\xbf" + `{"response":` + body + `}`)})
+
+	var order []string
+	stripBOM := func(b []byte) ([]byte, error) {
+		order = append(order, "bom")
+		return bytes.TrimPrefix(b, []byte("\xef\xbb\xbf")), nil
+	}
+	unwrap := func(b []byte) ([]byte, error) {
+		order = append(order, "unwrap")
+		if !bytes.HasPrefix(b, []byte(`{"response":`)) {
+			return nil, fmt.Errorf("missing envelope")
+		}
+		return bytes.TrimSuffix(bytes.TrimPrefix(b, []byte(`{"response":`)), []byte("}")), nil
+	}
+
+	ctx := NewContext(client).WithResponseTransformer(stripBOM).WithResponseTransformer(unwrap)
+
+	results, _, err := ctx.QuerySync("up")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results: exp (1); act (%d)", len(results))
+	}
+	if order[0] != "bom" || order[1] != "unwrap" {
+		t.Fatalf("Expected transformers to be applied in order, got %v", order)
+	}
+
+	_, err = ctx.QueryRange("up", time.Now().Add(-time.Hour), time.Now(), time.Minute).Await()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// in reverse order, the envelope is not found
+	ctx = NewContext(client).WithResponseTransformer(unwrap).WithResponseTransformer(stripBOM)
+	if _, _, err := ctx.QuerySync("up"); err == nil {
+		t.Fatalf("Expected transformer error")
+	}
+
+	if _, _, err := NewContext(client).QuerySync("up"); err == nil {
+		t.Fatalf("Expected error decoding untransformed response")
+	}
+}
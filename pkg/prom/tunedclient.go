@@ -0,0 +1,200 @@
+package prom
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/env"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// Recommended transport defaults for Kubecost's query patterns. Queries are issued in bursts of
+// up to MAX_QUERY_CONCURRENCY concurrent requests against a single host, and some range queries
+// run for minutes. The net/http default of 2 idle connections per host causes most connections of
+// a burst to be closed and re-dialed (including a TLS handshake) on the next burst, so idle
+// connections per host should be at least the query concurrency.
+const (
+	DefaultTunedDialTimeout         = 120 * time.Second
+	DefaultTunedKeepAlive           = 120 * time.Second
+	DefaultTunedMaxIdleConns        = 100
+	DefaultTunedMaxIdleConnsPerHost = 20
+	DefaultTunedIdleConnTimeout     = 90 * time.Second
+	DefaultTunedTLSHandshakeTimeout = 10 * time.Second
+)
+
+// tunedClientOptions contains the transport settings used by NewTunedClient
+type tunedClientOptions struct {
+	dialTimeout         time.Duration
+	keepAlive           time.Duration
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	disableKeepAlives   bool
+	tlsConfig           *tls.Config
+}
+
+// TunedClientOption sets a transport setting used by NewTunedClient
+type TunedClientOption func(*tunedClientOptions)
+
+// WithDialTimeout sets the maximum amount of time a dial will wait for a connect to complete
+func WithDialTimeout(timeout time.Duration) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.dialTimeout = timeout
+	}
+}
+
+// WithKeepAlive sets the interval between TCP keep-alive probes of active connections
+func WithKeepAlive(keepAlive time.Duration) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.keepAlive = keepAlive
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections across all hosts
+func WithMaxIdleConns(n int) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.maxIdleConns = n
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the maximum number of idle connections kept per host. This should
+// be at least the maximum query concurrency.
+func WithMaxIdleConnsPerHost(n int) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.maxIdleConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout sets the maximum amount of time an idle connection remains open
+func WithIdleConnTimeout(timeout time.Duration) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.idleConnTimeout = timeout
+	}
+}
+
+// WithDisableKeepAlives disables HTTP keep-alives, using each connection for a single request
+func WithDisableKeepAlives(disable bool) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.disableKeepAlives = disable
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for connections. By default, InsecureSkipVerify is
+// set from the environment.
+func WithTLSConfig(tlsConfig *tls.Config) TunedClientOption {
+	return func(opts *tunedClientOptions) {
+		opts.tlsConfig = tlsConfig
+	}
+}
+
+// NewTunedClient creates a prometheus client for the address using a transport tuned for high query
+// volume. Unset options use the DefaultTuned* values. The returned client is not rate limited. For
+// a rate limited client, pass a prometheus.Config using NewTunedTransport as its RoundTripper to
+// NewRateLimitedClient instead.
+func NewTunedClient(address string, opts ...TunedClientOption) (prometheus.Client, error) {
+	return prometheus.NewClient(prometheus.Config{
+		Address:      address,
+		RoundTripper: NewTunedTransport(opts...),
+	})
+}
+
+// NewClientWithTLS creates a prometheus client for the address using the tuned transport, which
+// verifies the server certificate against the PEM encoded CA certificates in caFile, ie: for a
+// Prometheus serving a self-signed certificate. If caFile is empty, the system roots are used.
+//
+// Setting insecureSkipVerify disables verification of the server certificate chain and host name
+// entirely, and caFile is ignored. The connection is still encrypted, but it is not authenticated,
+// so any server able to intercept the connection may impersonate Prometheus, and read or modify
+// queries, results, and any credentials sent with them. It should only be used for testing, and
+// providing the CA with caFile is preferred.
+//
+// The returned client is not rate limited. For a rate limited client, pass a prometheus.Config
+// using NewTLSTransport as its RoundTripper to NewRateLimitedClient instead.
+func NewClientWithTLS(address string, caFile string, insecureSkipVerify bool) (prometheus.Client, error) {
+	transport, err := NewTLSTransport(caFile, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	return prometheus.NewClient(prometheus.Config{
+		Address:      address,
+		RoundTripper: transport,
+	})
+}
+
+// NewTLSTransport creates the transport used by NewClientWithTLS, which is the tuned transport
+// verifying the server certificate as described by NewClientWithTLS. Additional options are applied
+// after the TLS configuration.
+func NewTLSTransport(caFile string, insecureSkipVerify bool, opts ...TunedClientOption) (http.RoundTripper, error) {
+	tlsConfig, err := newTLSConfig(caFile, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTunedTransport(append([]TunedClientOption{WithTLSConfig(tlsConfig)}, opts...)...), nil
+}
+
+// newTLSConfig creates the TLS configuration trusting the CA certificates in caFile, if provided
+func newTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	if insecureSkipVerify || caFile == "" {
+		return &tls.Config{InsecureSkipVerify: insecureSkipVerify}, nil
+	}
+
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA file '%s': %s", caFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA file '%s'", caFile)
+	}
+
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// NewTunedTransport creates the transport used by NewTunedClient for the provided options, which can
+// be set as the RoundTripper of the prometheus.Config passed to NewRateLimitedClient:
+//
+//	config := prometheus.Config{Address: address, RoundTripper: NewTunedTransport()}
+//	client, err := NewRateLimitedClient(PrometheusClientID, config, ...)
+func NewTunedTransport(opts ...TunedClientOption) http.RoundTripper {
+	return newTunedTransport(opts...)
+}
+
+// newTunedTransport creates the http.Transport for the provided options
+func newTunedTransport(opts ...TunedClientOption) *http.Transport {
+	options := &tunedClientOptions{
+		dialTimeout:         DefaultTunedDialTimeout,
+		keepAlive:           DefaultTunedKeepAlive,
+		maxIdleConns:        DefaultTunedMaxIdleConns,
+		maxIdleConnsPerHost: DefaultTunedMaxIdleConnsPerHost,
+		idleConnTimeout:     DefaultTunedIdleConnTimeout,
+		disableKeepAlives:   false,
+		tlsConfig:           &tls.Config{InsecureSkipVerify: env.GetInsecureSkipVerify()},
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   options.dialTimeout,
+			KeepAlive: options.keepAlive,
+		}).DialContext,
+		MaxIdleConns:        options.maxIdleConns,
+		MaxIdleConnsPerHost: options.maxIdleConnsPerHost,
+		IdleConnTimeout:     options.idleConnTimeout,
+		DisableKeepAlives:   options.disableKeepAlives,
+		TLSHandshakeTimeout: DefaultTunedTLSHandshakeTimeout,
+		TLSClientConfig:     options.tlsConfig,
+	}
+}
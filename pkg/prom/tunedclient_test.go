@@ -0,0 +1,160 @@
+package prom
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+func TestNewTunedTransport(t *testing.T) {
+	transport := newTunedTransport()
+	if transport.MaxIdleConnsPerHost != DefaultTunedMaxIdleConnsPerHost {
+		t.Fatalf("MaxIdleConnsPerHost: exp (%d); act (%d)", DefaultTunedMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != DefaultTunedIdleConnTimeout {
+		t.Fatalf("IdleConnTimeout: exp (%s); act (%s)", DefaultTunedIdleConnTimeout, transport.IdleConnTimeout)
+	}
+
+	transport = newTunedTransport(
+		WithMaxIdleConnsPerHost(50),
+		WithIdleConnTimeout(time.Minute),
+		WithDisableKeepAlives(true),
+	)
+	if transport.MaxIdleConnsPerHost != 50 {
+		t.Fatalf("MaxIdleConnsPerHost: exp (%d); act (%d)", 50, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != time.Minute {
+		t.Fatalf("IdleConnTimeout: exp (%s); act (%s)", time.Minute, transport.IdleConnTimeout)
+	}
+	if !transport.DisableKeepAlives {
+		t.Fatalf("DisableKeepAlives: exp (true); act (false)")
+	}
+}
+
+func TestNewTunedClient(t *testing.T) {
+	client, err := NewTunedClient("http://prometheus:9090", WithMaxIdleConnsPerHost(10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	u := client.URL(epQuery, nil)
+	if u.String() != "http://prometheus:9090/api/v1/query" {
+		t.Fatalf("URL: exp (%s); act (%s)", "http://prometheus:9090/api/v1/query", u)
+	}
+}
+
+func TestNewTunedTransportRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config := prometheus.Config{
+		Address:      server.URL,
+		RoundTripper: NewTunedTransport(WithMaxIdleConnsPerHost(10)),
+	}
+	client, err := NewRateLimitedClient(PrometheusClientID, config, 2, nil, nil, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, client.URL(epReady, nil).String(), nil)
+	if _, _, _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+func TestNewClientWithTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	testCases := map[string]struct {
+		caFile             string
+		insecureSkipVerify bool
+		expectErr          bool
+	}{
+		"system roots": {
+			caFile:    "",
+			expectErr: true,
+		},
+		"ca file": {
+			caFile:    caFile,
+			expectErr: false,
+		},
+		"insecure skip verify": {
+			caFile:             "",
+			insecureSkipVerify: true,
+			expectErr:          false,
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClientWithTLS(server.URL, test.caFile, test.insecureSkipVerify)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, client.URL(epReady, nil).String(), nil)
+			_, _, _, err = client.Do(context.Background(), req)
+			if test.expectErr && err == nil {
+				t.Fatalf("Expected certificate verification error")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		})
+	}
+
+	if _, err := NewClientWithTLS(server.URL, filepath.Join(t.TempDir(), "missing.crt"), false); err == nil {
+		t.Fatalf("Expected error for missing CA file")
+	}
+}
+
+func TestNewTLSTransportRateLimited(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	transport, err := NewTLSTransport(caFile, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	config := prometheus.Config{Address: server.URL, RoundTripper: transport}
+	client, err := NewRateLimitedClient(PrometheusClientID, config, 2, nil, nil, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, client.URL(epReady, nil).String(), nil)
+	if _, _, _, err := client.Do(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if _, err := NewTLSTransport(filepath.Join(t.TempDir(), "missing.crt"), false); err == nil {
+		t.Fatalf("Expected error for missing CA file")
+	}
+}
@@ -1,5 +1,7 @@
 package prom
 
+import "strings"
+
 // warning represents an unexpected result that occurs but doesn't halt processing
 type warning interface {
 	Message() string
@@ -24,3 +26,73 @@ func (dw *defaultWarning) String() string {
 func newWarning(msg string) warning {
 	return &defaultWarning{msg}
 }
+
+// WarningType classifies a warning returned alongside the results of a query
+type WarningType string
+
+const (
+	// WarningTypeNoStoreAPI is the Thanos warning returned when no store matched the query, see
+	// NoStoreAPIWarning
+	WarningTypeNoStoreAPI WarningType = "no_store_api"
+
+	// WarningTypePartialResponse is a Thanos or Cortex warning returned when a store or querier
+	// failed to respond, so the results are missing its series
+	WarningTypePartialResponse WarningType = "partial_response"
+
+	// WarningTypePromQL is an annotation emitted by the PromQL engine about the evaluation of the
+	// query, ie: a possible non-counter passed to rate
+	WarningTypePromQL WarningType = "promql"
+
+	// WarningTypeUnknown is any warning which is not otherwise classified
+	WarningTypeUnknown WarningType = "unknown"
+)
+
+// partialResponseWarnings are the substrings identifying the warnings returned by Thanos and
+// Cortex when the results are missing the series of an unavailable store or querier
+var partialResponseWarnings = []string{
+	"partial response",
+	"fetch series for",
+	"receive series from",
+	"results may be incomplete",
+}
+
+// ResponseWarning is a warning returned alongside the results of a query
+type ResponseWarning struct {
+	Type    WarningType
+	Message string
+}
+
+// ClassifyWarning returns the type of the warning, or WarningTypeUnknown if the warning is not
+// recognized
+func ClassifyWarning(warning string) WarningType {
+	if IsNoStoreAPIWarning(warning) {
+		return WarningTypeNoStoreAPI
+	}
+
+	lower := strings.ToLower(warning)
+	for _, s := range partialResponseWarnings {
+		if strings.Contains(lower, s) {
+			return WarningTypePartialResponse
+		}
+	}
+
+	if strings.HasPrefix(lower, "promql info:") || strings.HasPrefix(lower, "promql warning:") {
+		return WarningTypePromQL
+	}
+
+	return WarningTypeUnknown
+}
+
+// ParseWarnings classifies each of the warnings, returning nil if there are none
+func ParseWarnings(warnings []string) []ResponseWarning {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	parsed := make([]ResponseWarning, len(warnings))
+	for i, w := range warnings {
+		parsed[i] = ResponseWarning{Type: ClassifyWarning(w), Message: w}
+	}
+
+	return parsed
+}
@@ -16,29 +16,126 @@ func IsRetryCancelledError(err error) bool {
 }
 
 // Retry will run the f func until we receive a non error result up to the provided attempts or a cancellation.
+// A zero delay retries immediately, without jitter, and a negative delay is clamped to zero. If the context has
+// a deadline which would pass before the next attempt, the last error is returned immediately rather than
+// waiting out the delay.
 func Retry(ctx context.Context, f func() (interface{}, error), attempts uint, delay time.Duration) (interface{}, error) {
+	result, _, err := RetryResult(ctx, f, attempts, delay)
+	return result, err
+}
+
+// RetryResult runs f in the same way as Retry, additionally returning the number of attempts that
+// were made before succeeding, exhausting the attempts, or being cancelled.
+func RetryResult(ctx context.Context, f func() (interface{}, error), attempts uint, delay time.Duration) (interface{}, uint, error) {
+	return retry(ctx, f, attempts, delay, true)
+}
+
+// RetryNoJitter runs f in the same way as Retry, but waits exactly delay between attempts rather than
+// a randomly growing delay, which makes the timing of attempts deterministic, ie: for tests.
+func RetryNoJitter(ctx context.Context, f func() (interface{}, error), attempts uint, delay time.Duration) (interface{}, error) {
+	result, _, err := retry(ctx, f, attempts, delay, false)
+	return result, err
+}
+
+// retry implements Retry, RetryResult and RetryNoJitter. If jitter is enabled, the delay grows by a
+// random amount of up to half of its value after each attempt.
+func retry(ctx context.Context, f func() (interface{}, error), attempts uint, delay time.Duration, jitter bool) (interface{}, uint, error) {
 	var result interface{}
 	var err error
+	var used uint
 
 	d := delay
+	if d < 0 {
+		d = 0
+	}
+
 	for r := attempts; r > 0; r-- {
 		select {
 		case <-ctx.Done():
-			return nil, RetryCancellationErr
+			return nil, used, RetryCancellationErr
 		default:
 		}
 
 		result, err = f()
+		used++
 
 		if err == nil {
 			break
 		}
 
-		time.Sleep(d)
+		// no further attempts remain, so there is nothing to wait for
+		if r == 1 || d == 0 {
+			continue
+		}
+
+		// the next attempt cannot start before the deadline of the context, so waiting for it
+		// would only delay the error
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+			break
+		}
+
+		if err := sleep(ctx, d); err != nil {
+			return nil, used, err
+		}
 
-		jitter := time.Duration(rand.Int63n(int64(d))) // #nosec No need for a cryptographic strength random here
-		d = d + jitter/2
+		if jitter {
+			j := time.Duration(rand.Int63n(int64(d))) // #nosec No need for a cryptographic strength random here
+			d = d + j/2
+		}
 	}
 
-	return result, err
+	return result, used, err
+}
+
+// RetryForever will run the f func until we receive a non error result or a cancellation. The delay
+// between attempts starts at baseDelay, and is multiplied by factor after each failure until it
+// reaches maxDelay. A factor less than 1 keeps the delay constant, and a non-positive baseDelay is
+// treated as 1ms to prevent busy looping. The only error returned is RetryCancellationErr.
+func RetryForever(ctx context.Context, f func() (interface{}, error), baseDelay, maxDelay time.Duration, factor float64) (interface{}, error) {
+	d := baseDelay
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	if maxDelay < d {
+		maxDelay = d
+	}
+	if factor < 1 {
+		factor = 1
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, RetryCancellationErr
+		default:
+		}
+
+		result, err := f()
+		if err == nil {
+			return result, nil
+		}
+
+		if err := sleep(ctx, d); err != nil {
+			return nil, err
+		}
+
+		d = time.Duration(float64(d) * factor)
+		if d > maxDelay || d <= 0 {
+			d = maxDelay
+		}
+	}
+}
+
+// sleep pauses for the provided duration, returning RetryCancellationErr early if the context is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return RetryCancellationErr
+	case <-timer.C:
+		return nil
+	}
 }
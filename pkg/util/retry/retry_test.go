@@ -124,3 +124,196 @@ func TestCancelRetry(t *testing.T) {
 		t.Fatalf("Expected CancellationError, got: %s", e)
 	}
 }
+
+func TestDeadlineRetry(t *testing.T) {
+	t.Parallel()
+
+	var count uint64 = 0
+
+	f := func() (interface{}, error) {
+		c := atomic.AddUint64(&count, 1)
+		return nil, fmt.Errorf("Failed: %d", c)
+	}
+
+	// the deadline allows a single delay, but not the total backoff of all attempts
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := Retry(ctx, f, 5, time.Second)
+	elapsed := time.Since(start)
+
+	if IsRetryCancelledError(err) {
+		t.Fatalf("Expected the last error, got: %s", err)
+	}
+	if err == nil || err.Error() != "Failed: 2" {
+		t.Fatalf("Expected error: Failed: 2, Actual error: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("Expected Count: %d, Actual: %d", 2, count)
+	}
+	if elapsed >= 1500*time.Millisecond {
+		t.Fatalf("Expected to return before the deadline, took %s", elapsed)
+	}
+}
+
+func TestZeroDelayRetry(t *testing.T) {
+	t.Parallel()
+	const Expected uint64 = 3
+
+	var count uint64 = 0
+
+	f := func() (interface{}, error) {
+		c := atomic.AddUint64(&count, 1)
+		if c == Expected {
+			return c, nil
+		}
+
+		return nil, fmt.Errorf("Failed: %d", c)
+	}
+
+	for _, delay := range []time.Duration{0, -time.Second} {
+		atomic.StoreUint64(&count, 0)
+
+		result, err := Retry(context.Background(), f, 5, delay)
+		if err != nil {
+			t.Fatalf("Unexpected error with delay %s: %s", delay, err)
+		}
+		if result.(uint64) != Expected {
+			t.Fatalf("Expected result: %d, Actual: %d", Expected, result)
+		}
+	}
+}
+
+func TestRetryResultAttempts(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		succeedOn uint64
+		attempts  uint
+		expUsed   uint
+		expErr    bool
+	}{
+		"first attempt": {
+			succeedOn: 1,
+			attempts:  5,
+			expUsed:   1,
+		},
+		"third attempt": {
+			succeedOn: 3,
+			attempts:  5,
+			expUsed:   3,
+		},
+		"exhausted": {
+			succeedOn: 10,
+			attempts:  4,
+			expUsed:   4,
+			expErr:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var count uint64 = 0
+
+			f := func() (interface{}, error) {
+				c := atomic.AddUint64(&count, 1)
+				if c == tc.succeedOn {
+					return c, nil
+				}
+
+				return nil, fmt.Errorf("Failed: %d", c)
+			}
+
+			_, used, err := RetryResult(context.Background(), f, tc.attempts, 0)
+			if tc.expErr != (err != nil) {
+				t.Fatalf("Expected error: %t, Actual: %v", tc.expErr, err)
+			}
+			if used != tc.expUsed {
+				t.Fatalf("Expected attempts: %d, Actual: %d", tc.expUsed, used)
+			}
+		})
+	}
+}
+
+func TestRetryForeverSuccess(t *testing.T) {
+	t.Parallel()
+	const Expected uint64 = 6
+
+	var count uint64 = 0
+
+	f := func() (interface{}, error) {
+		c := atomic.AddUint64(&count, 1)
+		if c == Expected {
+			return c, nil
+		}
+
+		return nil, fmt.Errorf("Failed: %d", c)
+	}
+
+	// delays are 10, 20, 40, 50, 50 ms
+	start := time.Now()
+	result, err := RetryForever(context.Background(), f, 10*time.Millisecond, 50*time.Millisecond, 2)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if result.(uint64) != Expected {
+		t.Fatalf("Expected result: %d, Actual: %d", Expected, result)
+	}
+	if elapsed < 170*time.Millisecond {
+		t.Fatalf("Expected capped backoff of at least 170ms, took: %s", elapsed)
+	}
+}
+
+func TestRetryForeverCancel(t *testing.T) {
+	t.Parallel()
+
+	f := func() (interface{}, error) {
+		return nil, fmt.Errorf("Failed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	// the cancellation should interrupt the 1 minute delay
+	start := time.Now()
+	_, err := RetryForever(ctx, f, time.Minute, time.Hour, 2)
+	if !IsRetryCancelledError(err) {
+		t.Fatalf("Expected CancellationError, got: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("Expected cancellation to interrupt the delay, took: %s", elapsed)
+	}
+}
+
+func TestNoJitterRetry(t *testing.T) {
+	t.Parallel()
+	const delay = 100 * time.Millisecond
+
+	var attempts []time.Time
+	f := func() (interface{}, error) {
+		attempts = append(attempts, time.Now())
+		return nil, fmt.Errorf("Failed: %d", len(attempts))
+	}
+
+	_, err := RetryNoJitter(context.Background(), f, 4, delay)
+	if err == nil || err.Error() != "Failed: 4" {
+		t.Fatalf("Expected error: Failed: 4, Actual error: %v", err)
+	}
+	if len(attempts) != 4 {
+		t.Fatalf("Expected Count: %d, Actual: %d", 4, len(attempts))
+	}
+
+	for i := 1; i < len(attempts); i++ {
+		gap := attempts[i].Sub(attempts[i-1])
+		if gap < delay || gap >= 2*delay {
+			t.Fatalf("Expected delay of %s before attempt %d, Actual: %s", delay, i+1, gap)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Splay returns a random duration in the range [0, max), which can be used to offset the start of
+// periodic work so that multiple loops with the same interval do not align. Returns 0 if max <= 0.
+func Splay(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(max))) // #nosec No need for a cryptographic strength random here
+}
+
+// SplayTicker delivers ticks on C every interval, with the first tick offset by a random splay.
+// Like time.Ticker, ticks are dropped if the receiver falls behind.
+type SplayTicker struct {
+	C    <-chan time.Time
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Ticker creates a SplayTicker which first ticks after interval plus a random duration in the range
+// [0, splay), then every interval after. This desynchronizes periodic query loops started at the
+// same time, while preserving the interval between ticks. The ticker must be stopped to release
+// its resources.
+func Ticker(interval, splay time.Duration) *SplayTicker {
+	if interval <= 0 {
+		panic("non-positive interval for retry.Ticker")
+	}
+
+	c := make(chan time.Time, 1)
+	t := &SplayTicker{
+		C:    c,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go t.run(c, interval, Splay(splay))
+
+	return t
+}
+
+// Stop turns off the ticker. No more ticks will be sent after Stop returns, but C is not closed.
+func (t *SplayTicker) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+
+	<-t.done
+}
+
+func (t *SplayTicker) run(c chan<- time.Time, interval, offset time.Duration) {
+	defer close(t.done)
+
+	timer := time.NewTimer(interval + offset)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-timer.C:
+			select {
+			case c <- now:
+			default:
+			}
+
+			timer.Reset(interval)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplayBounds(t *testing.T) {
+	t.Parallel()
+
+	const max = 100 * time.Millisecond
+	const samples = 10000
+
+	var lower, upper int
+	for i := 0; i < samples; i++ {
+		s := Splay(max)
+		if s < 0 || s >= max {
+			t.Fatalf("Splay out of bounds: %s not in [0, %s)", s, max)
+		}
+
+		if s < max/2 {
+			lower++
+		} else {
+			upper++
+		}
+	}
+
+	// a uniform distribution should place roughly half of the samples in each half of the range
+	if lower < samples/3 || upper < samples/3 {
+		t.Fatalf("Splay distribution is skewed: %d in lower half, %d in upper half", lower, upper)
+	}
+
+	if s := Splay(0); s != 0 {
+		t.Fatalf("Splay(0): exp (0); act (%s)", s)
+	}
+	if s := Splay(-time.Second); s != 0 {
+		t.Fatalf("Splay(-1s): exp (0); act (%s)", s)
+	}
+}
+
+func TestTicker(t *testing.T) {
+	t.Parallel()
+
+	const interval = 20 * time.Millisecond
+	const splay = 20 * time.Millisecond
+
+	start := time.Now()
+	ticker := Ticker(interval, splay)
+	defer ticker.Stop()
+
+	first := <-ticker.C
+	if elapsed := first.Sub(start); elapsed < interval {
+		t.Fatalf("First tick arrived too early: %s < %s", elapsed, interval)
+	}
+
+	second := <-ticker.C
+	if elapsed := second.Sub(first); elapsed < interval {
+		t.Fatalf("Second tick arrived too early: %s < %s", elapsed, interval)
+	}
+
+	ticker.Stop()
+	ticker.Stop()
+
+	// drain any tick sent before Stop, then ensure no further ticks arrive
+	select {
+	case <-ticker.C:
+	default:
+	}
+
+	select {
+	case <-ticker.C:
+		t.Fatalf("Received tick after Stop")
+	case <-time.After(3 * interval):
+	}
+}